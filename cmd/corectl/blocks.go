@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"chain/core/blockarchive"
+	"chain/core/config"
+	"chain/core/txdb"
+	"chain/database/sql"
+	"chain/protocol"
+	"chain/protocol/bc"
+)
+
+func exportBlocks(db *sql.DB, args []string) {
+	const usage = "usage: corectl export-blocks [-from height] [-to height] [-z] [file]"
+	var flags flag.FlagSet
+	flagFrom := flags.Uint64("from", 1, "the `height` of the first block to export")
+	flagTo := flags.Uint64("to", 0, "the `height` of the last block to export (0 for the latest)")
+	flagZ := flags.Bool("z", false, "compress the blocks")
+	flags.Usage = func() {
+		fmt.Println(usage)
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 1 {
+		fatalln(usage)
+	}
+
+	ctx := context.Background()
+	conf := loadConfig(ctx, db)
+	store := txdb.NewStore(db)
+	height, err := store.Height(ctx)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	from, to := *flagFrom, *flagTo
+	if to == 0 {
+		to = height
+	}
+	if from < 1 || from > to || to > height {
+		fatalln(fmt.Sprintf("error: bad block range %d-%d; blockchain height is %d", from, to, height))
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		fatalln("error:", err)
+	}
+	err = writeArchive(ctx, f, store, conf.BlockchainID, from, to, *flagZ)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(args[0])
+		fatalln("error:", err)
+	}
+	fmt.Printf("exported blocks %d-%d\n", from, to)
+}
+
+func writeArchive(ctx context.Context, w io.Writer, store *txdb.Store, blockchainID bc.Hash, from, to uint64, compress bool) error {
+	aw, err := blockarchive.NewWriter(w, blockchainID, from, compress)
+	if err != nil {
+		return err
+	}
+	for h := from; h <= to; h++ {
+		b, err := store.GetBlock(ctx, h)
+		if err != nil {
+			return err
+		}
+		err = aw.WriteBlock(b)
+		if err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+func importBlocks(db *sql.DB, args []string) {
+	const usage = "usage: corectl import-blocks [file]"
+	if len(args) != 1 {
+		fatalln(usage)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conf := loadConfig(ctx, db)
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		fatalln("error:", err)
+	}
+	defer f.Close()
+	ar, err := blockarchive.NewReader(f)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	if ar.BlockchainID() != conf.BlockchainID {
+		fatalln("error: archive is from blockchain", ar.BlockchainID(), "but this core is configured for", conf.BlockchainID)
+	}
+
+	store := txdb.NewStore(db)
+	c, err := protocol.NewChain(ctx, conf.BlockchainID, store, nil)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	prev, snapshot, err := c.Recover(ctx)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	height := c.Height()
+	if ar.First() > height+1 {
+		fatalln(fmt.Sprintf("error: archive starts at block %d; blockchain height is %d", ar.First(), height))
+	}
+
+	var imported uint64
+	for {
+		b, err := ar.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			fatalln("error:", err)
+		}
+
+		if b.Height <= height {
+			// The core already has this block.
+			// Make sure it's the same one.
+			have, err := store.GetBlock(ctx, b.Height)
+			if err != nil {
+				fatalln("error:", err)
+			}
+			if have.Hash() != b.Hash() {
+				fatalln(fmt.Sprintf("error: archive block %d is %s; core has %s", b.Height, b.Hash(), have.Hash()))
+			}
+			continue
+		}
+
+		snapshot, err = c.ValidateBlock(ctx, snapshot, prev, b)
+		if err != nil {
+			fatalln(fmt.Sprintf("error: validating block %d: %s", b.Height, err))
+		}
+		err = c.CommitBlock(ctx, b, snapshot)
+		if err != nil {
+			fatalln(fmt.Sprintf("error: committing block %d: %s", b.Height, err))
+		}
+		prev = b
+		imported++
+	}
+
+	// CommitBlock saves snapshots in the background, so
+	// save the latest one here before exiting.
+	if imported > 0 {
+		err = store.SaveSnapshot(ctx, prev.Height, snapshot)
+		if err != nil {
+			fatalln("error:", err)
+		}
+	}
+	fmt.Printf("imported %d blocks; blockchain height is %d\n", imported, c.Height())
+}
+
+func loadConfig(ctx context.Context, db *sql.DB) *config.Config {
+	conf, err := config.Load(ctx, db)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	if conf == nil {
+		fatalln("error: core is not configured")
+	}
+	return conf
+}
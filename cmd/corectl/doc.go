@@ -13,13 +13,24 @@ Subcommand 'config-generator' configures a new core as a generator.
 It matches the dashboard's behavior when writing the config,
 but with additional functionality.
 
-	corectl config-generator [-s] [-w duration] [quorum] [pubkey url]...
+	corectl config-generator [-s] [-w duration] [-block-period duration] [-max-block-txs n] [-heartbeat duration] [quorum] [pubkey url]...
 
 Flag -s sets this core as a signer.
 
 Flag -w, followed by a duration string (e.g. "24h"), sets the maximum issuance window.
 The default is 24 hours.
 
+Flag -block-period sets how often the generator makes a block.
+The default is 1 second.
+
+Flag -max-block-txs sets the maximum number of transactions in each block.
+The default is 10000.
+
+Flag -heartbeat, followed by a duration string, makes the generator
+produce an empty block whenever no block has been made for that long,
+so participants can tell an idle network from an unavailable generator.
+The default, 0, disables heartbeat blocks.
+
 Config Participant
 
 Subcommand 'config' configures the Core as a non-generator. It requires a
@@ -50,6 +61,31 @@ otherwise it will create a client token.
 
     corectl create-token [-net] [name]
 
+Export Blocks
+
+Subcommand 'export-blocks' writes blocks from the Core's database
+to a block archive file, which 'import-blocks' can load into another
+Core on the same blockchain. Each block in the archive is checksummed,
+and the archive ends with a digest of all its blocks, so corruption
+and truncation are detected on import.
+
+    corectl export-blocks [-from height] [-to height] [-z] [file]
+
+Flags -from and -to give the range of blocks to export.
+The default is every block, from 1 to the latest.
+
+Flag -z compresses the blocks with gzip.
+
+Import Blocks
+
+Subcommand 'import-blocks' validates the blocks in a block archive
+and commits them to the Core's blockchain. The Core must already be
+configured for the blockchain the archive is from, and should not be
+running. Blocks the Core already has are checked against the archive
+and skipped; the rest must follow on from the Core's latest block.
+
+    corectl import-blocks [file]
+
 Reset
 
 Subcommand 'reset' resets the database so the Chain Core can be configured again.
@@ -19,6 +19,7 @@ import (
 	chainjson "chain/encoding/json"
 	"chain/env"
 	"chain/log"
+	"chain/protocol"
 	_ "chain/protocol/tx" // for BlockHeaderHashFunc
 )
 
@@ -42,6 +43,8 @@ var commands = map[string]*command{
 	"create-block-keypair": {createBlockKeyPair},
 	"create-token":         {createToken},
 	"config":               {configNongenerator},
+	"export-blocks":        {exportBlocks},
+	"import-blocks":        {importBlocks},
 	"reset":                {reset},
 }
 
@@ -87,6 +90,9 @@ func configGenerator(db *sql.DB, args []string) {
 
 	var flags flag.FlagSet
 	maxIssuanceWindow := flags.Duration("w", 24*time.Hour, "the maximum issuance window `duration` for this generator")
+	blockPeriod := flags.Duration("block-period", time.Second, "the `duration` between blocks")
+	maxBlockTxs := flags.Int("max-block-txs", protocol.DefaultMaxBlockTxs, "the maximum `number` of transactions per block")
+	heartbeat := flags.Duration("heartbeat", 0, "make an empty block if none has been made for this `duration` (0 to disable)")
 	flagK := flags.String("k", "", "local `pubkey` for signing blocks")
 	flagHSMURL := flags.String("hsm-url", "", "hsm `url` for signing blocks (mockhsm if empty)")
 	flagHSMToken := flags.String("hsm-token", "", "hsm `access-token` for connecting to hsm")
@@ -144,6 +150,9 @@ func configGenerator(db *sql.DB, args []string) {
 		MaxIssuanceWindow: chainjson.Duration{
 			Duration: *maxIssuanceWindow,
 		},
+		BlockPeriod:         chainjson.Duration{Duration: *blockPeriod},
+		MaxBlockTxs:         *maxBlockTxs,
+		HeartbeatPeriod:     chainjson.Duration{Duration: *heartbeat},
 		IsSigner:            *flagK != "",
 		BlockPub:            *flagK,
 		BlockHSMURL:         *flagHSMURL,
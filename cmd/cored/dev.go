@@ -10,9 +10,12 @@ import (
 	"os"
 
 	"chain/core"
+	"chain/core/account"
 	"chain/core/blocksigner"
 	"chain/core/coreunsafe"
 	"chain/core/mockhsm"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
 	"chain/database/pg"
 	"chain/env"
 	"chain/log"
@@ -58,3 +61,17 @@ func hsmRegister(db pg.DB) func(*http.ServeMux, *core.API) {
 func devHSM(db pg.DB) (blocksigner.Signer, error) {
 	return mockhsm.New(db), nil
 }
+
+func devTxSigner(db pg.DB) (account.TemplateSigner, error) {
+	hsm := mockhsm.New(db)
+	signFn := func(ctx context.Context, xpub chainkd.XPub, path [][]byte, data [32]byte) ([]byte, error) {
+		sig, err := hsm.XSign(ctx, xpub, path, data[:])
+		if err == mockhsm.ErrNoKey {
+			return nil, nil
+		}
+		return sig, err
+	}
+	return func(ctx context.Context, tpl *txbuilder.Template, xpubs []chainkd.XPub) error {
+		return txbuilder.Sign(ctx, tpl, xpubs, signFn)
+	}, nil
+}
@@ -2,9 +2,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/hex"
+	stdjson "encoding/json"
 	"expvar"
 	"flag"
 	"fmt"
@@ -14,6 +16,7 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,6 +39,7 @@ import (
 	"chain/core/txdb"
 	"chain/core/txfeed"
 	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
 	"chain/database/pg"
 	"chain/database/sql"
 	"chain/encoding/json"
@@ -72,6 +76,41 @@ var (
 	rpsRemoteAddr = env.Int("RATELIMIT_REMOTE_ADDR", 0) // reqs/sec
 	indexTxs      = env.Bool("INDEX_TRANSACTIONS", true)
 
+	// Other Cores to compare blocks with, as URLs with
+	// network access tokens in the userinfo.
+	crossCheckPeers = env.StringSlice("CROSSCHECK_PEERS")
+
+	// Other Cores to download a snapshot from if the generator
+	// fails, in the same form as CROSSCHECK_PEERS.
+	snapshotPeers = env.StringSlice("SNAPSHOT_PEERS")
+
+	// A block trusted to be in the blockchain, as height:hash.
+	// Bootstrapping checks the block signatures from there to
+	// the snapshot. By default, it checks them from the initial
+	// block.
+	bootstrapCheckpoint = env.String("BOOTSTRAP_CHECKPOINT", "")
+
+	// Witness strategies the generator tries, in order, for
+	// consensus programs that aren't simple block multisig
+	// programs; see generator.StrategiesByName.
+	witnessStrategies = env.StringSlice("WITNESS_STRATEGIES")
+
+	// How many of the most recent state snapshots to keep, and
+	// for how many days to keep the latest snapshot of each day.
+	snapshotKeepLast  = env.Int("SNAPSHOT_KEEP_LAST", txdb.DefaultRetentionPolicy.KeepLast)
+	snapshotKeepDaily = env.Int("SNAPSHOT_KEEP_DAILY_DAYS", txdb.DefaultRetentionPolicy.KeepDailyDays)
+
+	// How often to merge accounts' small UTXOs (0 to disable),
+	// and which to merge; see account.ConsolidationPolicy.
+	// Consolidation transactions are signed by the HSM at
+	// ACCOUNT_HSM_URL, or by the mockhsm in development.
+	consolidatePeriod    = env.Duration("CONSOLIDATE_UTXOS_PERIOD", 0)
+	consolidateMinUTXOs  = env.Int("CONSOLIDATE_MIN_UTXOS", account.DefaultConsolidationPolicy.MinUTXOs)
+	consolidateMaxAmount = env.Int("CONSOLIDATE_MAX_AMOUNT", int(account.DefaultConsolidationPolicy.MaxAmount))
+	consolidateMaxInputs = env.Int("CONSOLIDATE_MAX_INPUTS", account.DefaultConsolidationPolicy.MaxInputs)
+	accountHSMURL        = env.String("ACCOUNT_HSM_URL", "")
+	accountHSMToken      = env.String("ACCOUNT_HSM_ACCESS_TOKEN", "")
+
 	// build vars; initialized by the linker
 	buildTag    = "?"
 	buildCommit = "?"
@@ -80,8 +119,9 @@ var (
 	race          []interface{} // initialized in race.go
 	httpsRedirect = true        // initialized in insecure.go
 
-	blockPeriod              = time.Second
 	expireReservationsPeriod = time.Second
+	crossCheckPeriod         = time.Minute
+	pruneSnapshotsPeriod     = time.Hour
 )
 
 func init() {
@@ -239,7 +279,7 @@ func launchConfiguredCore(ctx context.Context, db *sql.DB, conf *config.Config,
 		chainlog.Fatal(ctx, chainlog.KeyError, err)
 	}
 
-	var generatorSigners []generator.BlockSigner
+	var localSigner *blocksigner.BlockSigner
 	var signBlockHandler func(context.Context, *bc.Block) ([]byte, error)
 	if conf.IsSigner {
 		blockPub, err := hex.DecodeString(conf.BlockPub)
@@ -267,7 +307,7 @@ func launchConfiguredCore(ctx context.Context, db *sql.DB, conf *config.Config,
 		}
 		s := blocksigner.New(blockPub, hsm, db, c)
 
-		generatorSigners = append(generatorSigners, s) // "local" signer
+		localSigner = s
 		signBlockHandler = func(ctx context.Context, b *bc.Block) ([]byte, error) {
 			sig, err := s.ValidateAndSignBlock(ctx, b)
 			if errors.Root(err) == blocksigner.ErrInvalidKey {
@@ -276,11 +316,21 @@ func launchConfiguredCore(ctx context.Context, db *sql.DB, conf *config.Config,
 			return sig, err
 		}
 	}
-	if conf.IsGenerator {
+	// generatorSigners returns the signers named in conf,
+	// including the local signer, if any.
+	generatorSigners := func(conf *config.Config) []generator.BlockSigner {
+		var a []generator.BlockSigner
+		if localSigner != nil {
+			a = append(a, localSigner)
+		}
 		for _, signer := range remoteSignerInfo(ctx, processID, buildTag, conf.BlockchainID.String(), conf) {
-			generatorSigners = append(generatorSigners, signer)
+			a = append(a, signer)
 		}
+		return a
+	}
+	if conf.IsGenerator {
 		c.MaxIssuanceWindow = conf.MaxIssuanceWindow.Duration
+		c.MaxBlockTxs = conf.MaxBlockTxs
 	}
 
 	var submitter txbuilder.Submitter
@@ -297,7 +347,12 @@ func launchConfiguredCore(ctx context.Context, db *sql.DB, conf *config.Config,
 		}
 		submitter = &txbuilder.RemoteGenerator{Peer: remoteGenerator}
 	} else {
-		gen = generator.New(c, generatorSigners, db)
+		gen = generator.New(c, generatorSigners(conf), db)
+		gen.HeartbeatPeriod = conf.HeartbeatPeriod.Duration
+		gen.WitnessStrategies, err = generator.StrategiesByName(*witnessStrategies)
+		if err != nil {
+			chainlog.Fatal(ctx, chainlog.KeyError, err)
+		}
 		submitter = gen
 	}
 
@@ -362,10 +417,51 @@ func launchConfiguredCore(ctx context.Context, db *sql.DB, conf *config.Config,
 		})
 	}
 
+	crossChecker := &fetch.CrossChecker{
+		Chain: c,
+		DB:    db,
+		Peers: peerClients(ctx, *crossCheckPeers, processID, conf),
+	}
+
 	var (
 		genhealth   = h.HealthSetter("generator")
 		fetchhealth = h.HealthSetter("fetch")
 	)
+	bootstrapFrom := checkpoint(ctx)
+	retention := txdb.RetentionPolicy{
+		KeepLast:      *snapshotKeepLast,
+		KeepDailyDays: *snapshotKeepDaily,
+	}
+	var consolidateSigner account.TemplateSigner
+	if *consolidatePeriod > 0 {
+		if *accountHSMURL != "" {
+			hsm := &remoteTxSigner{Client: &rpc.Client{
+				BaseURL:      *accountHSMURL,
+				AccessToken:  *accountHSMToken,
+				Username:     processID,
+				CoreID:       conf.ID,
+				BuildTag:     buildTag,
+				BlockchainID: conf.BlockchainID.String(),
+			}}
+			consolidateSigner = hsm.Sign
+		} else {
+			consolidateSigner, err = devTxSigner(db)
+			if err != nil {
+				chainlog.Fatal(ctx, chainlog.KeyError, err)
+			}
+		}
+	}
+	consolidation := account.ConsolidationPolicy{
+		MinUTXOs:  *consolidateMinUTXOs,
+		MaxAmount: uint64(*consolidateMaxAmount),
+		MaxInputs: *consolidateMaxInputs,
+	}
+	if consolidateSigner != nil {
+		err = consolidation.Validate()
+		if err != nil {
+			chainlog.Fatal(ctx, chainlog.KeyError, err)
+		}
+	}
 
 	go leader.Run(db, *listenAddr, func(ctx context.Context) {
 		if !conf.IsGenerator {
@@ -373,7 +469,8 @@ func launchConfiguredCore(ctx context.Context, db *sql.DB, conf *config.Config,
 			// If don't have any blocks, bootstrap from the generator's
 			// latest snapshot.
 			if c.Height() == 0 {
-				fetch.BootstrapSnapshot(ctx, c, remoteGenerator, fetchhealth)
+				peers := append([]*rpc.Client{remoteGenerator}, peerClients(ctx, *snapshotPeers, processID, conf)...)
+				fetch.BootstrapSnapshot(ctx, c, peers, bootstrapFrom, fetchhealth)
 			}
 		}
 
@@ -407,9 +504,42 @@ func launchConfiguredCore(ctx context.Context, db *sql.DB, conf *config.Config,
 		}
 
 		if conf.IsGenerator {
-			go gen.Generate(ctx, blockPeriod, genhealth, recoveredBlock, recoveredSnapshot)
+			// The block signers and next consensus program may have
+			// been changed by another process since this one started.
+			conf, err := config.Load(ctx, db)
+			if err != nil {
+				chainlog.Fatal(ctx, chainlog.KeyError, err)
+			}
+			// Once the generator has switched to the next
+			// consensus program, only its signers are needed.
+			pruneSigners := func(ctx context.Context, prog []byte) {
+				err := config.PruneSigners(ctx, db, conf, prog)
+				if err != nil {
+					chainlog.Error(ctx, err)
+					return
+				}
+				gen.SetSigners(generatorSigners(conf))
+			}
+			if recoveredBlock != nil && len(conf.NextConsensusProgram) > 0 &&
+				bytes.Equal(recoveredBlock.ConsensusProgram, conf.NextConsensusProgram) {
+				err = config.PruneSigners(ctx, db, conf, conf.NextConsensusProgram)
+				if err != nil {
+					chainlog.Fatal(ctx, chainlog.KeyError, err)
+				}
+			}
+			gen.SetSigners(generatorSigners(conf))
+			gen.NextConsensusProgram = conf.NextConsensusProgram
+			gen.Rotated = pruneSigners
+			go gen.Generate(ctx, conf.BlockPeriod.Duration, genhealth, recoveredBlock, recoveredSnapshot)
 		} else {
-			go fetch.Fetch(ctx, c, remoteGenerator, fetchhealth, recoveredBlock, recoveredSnapshot)
+			go fetch.Fetch(ctx, c, remoteGenerator, fetchhealth, crossChecker, recoveredBlock, recoveredSnapshot)
+		}
+		if len(crossChecker.Peers) > 0 {
+			go crossChecker.Run(ctx, crossCheckPeriod)
+		}
+		go store.PruneSnapshotsPeriodically(ctx, retention, pruneSnapshotsPeriod)
+		if consolidateSigner != nil {
+			go accounts.ConsolidateUTXOsPeriodically(ctx, consolidation, *consolidatePeriod, consolidateSigner, submitter)
 		}
 		go h.Accounts.ProcessBlocks(ctx)
 		go h.Assets.ProcessBlocks(ctx)
@@ -456,6 +586,42 @@ func (h *remoteHSM) Sign(ctx context.Context, pk ed25519.PublicKey, bh *bc.Block
 	return
 }
 
+// remoteTxSigner is a client wrapper for an hsm
+// that signs transactions for accounts.
+type remoteTxSigner struct {
+	Client *rpc.Client
+}
+
+func (h *remoteTxSigner) Sign(ctx context.Context, tpl *txbuilder.Template, xpubs []chainkd.XPub) error {
+	body := struct {
+		Txs   []*txbuilder.Template `json:"transactions"`
+		XPubs []chainkd.XPub        `json:"xpubs"`
+	}{[]*txbuilder.Template{tpl}, xpubs}
+
+	// Each response item is either a signed
+	// template or an error.
+	var resp []stdjson.RawMessage
+	err := h.Client.Call(ctx, "/sign-transaction", body, &resp)
+	if err != nil {
+		return err
+	}
+	if len(resp) != 1 {
+		return fmt.Errorf("hsm returned %d transactions, want 1", len(resp))
+	}
+	var errResp struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	err = stdjson.Unmarshal(resp[0], &errResp)
+	if err != nil {
+		return errors.Wrap(err, "decoding hsm response")
+	}
+	if errResp.Code != "" {
+		return fmt.Errorf("hsm error %s: %s", errResp.Code, errResp.Message)
+	}
+	return errors.Wrap(stdjson.Unmarshal(resp[0], tpl), "decoding signed transaction")
+}
+
 func remoteSignerInfo(ctx context.Context, processID, buildTag, blockchainID string, conf *config.Config) (a []*remoteSigner) {
 	for _, signer := range conf.Signers {
 		u, err := url.Parse(signer.URL)
@@ -478,6 +644,53 @@ func remoteSignerInfo(ctx context.Context, processID, buildTag, blockchainID str
 	return a
 }
 
+// peerClients returns clients for peers, given as URLs
+// with network access tokens in the userinfo.
+func peerClients(ctx context.Context, peers []string, processID string, conf *config.Config) (a []*rpc.Client) {
+	for _, peer := range peers {
+		u, err := url.Parse(peer)
+		if err != nil {
+			chainlog.Fatal(ctx, chainlog.KeyError, err)
+		}
+		var accessToken string
+		if u.User != nil {
+			accessToken = u.User.String()
+			u.User = nil
+		}
+		a = append(a, &rpc.Client{
+			BaseURL:      u.String(),
+			AccessToken:  accessToken,
+			Username:     processID,
+			CoreID:       conf.ID,
+			BuildTag:     buildTag,
+			BlockchainID: conf.BlockchainID.String(),
+		})
+	}
+	return a
+}
+
+// checkpoint parses bootstrapCheckpoint.
+// It returns nil if it's not set.
+func checkpoint(ctx context.Context) *fetch.Checkpoint {
+	if *bootstrapCheckpoint == "" {
+		return nil
+	}
+	parts := strings.SplitN(*bootstrapCheckpoint, ":", 2)
+	if len(parts) != 2 {
+		chainlog.Fatal(ctx, chainlog.KeyError, "BOOTSTRAP_CHECKPOINT must be height:hash")
+	}
+	height, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		chainlog.Fatal(ctx, chainlog.KeyError, err)
+	}
+	cp := &fetch.Checkpoint{Height: height}
+	err = cp.Hash.UnmarshalText([]byte(parts[1]))
+	if err != nil {
+		chainlog.Fatal(ctx, chainlog.KeyError, err)
+	}
+	return cp
+}
+
 func (s *remoteSigner) SignBlock(ctx context.Context, b *bc.Block) (signature []byte, err error) {
 	// TODO(kr): We might end up serializing b multiple
 	// times in multiple calls to different remoteSigners.
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"chain/core"
+	"chain/core/account"
 	"chain/core/blocksigner"
 	"chain/database/pg"
 )
@@ -26,3 +27,7 @@ func hsmRegister(_ pg.DB) func(*http.ServeMux, *core.API) {
 func devHSM(_ pg.DB) (blocksigner.Signer, error) {
 	return nil, errors.New("cannot use mockhsm in production, must configure block hsm url")
 }
+
+func devTxSigner(_ pg.DB) (account.TemplateSigner, error) {
+	return nil, errors.New("cannot use mockhsm in production, must configure account hsm url")
+}
@@ -29,13 +29,14 @@ var ErrDuplicateAlias = errors.New("duplicate account alias")
 
 func NewManager(db *sql.DB, chain *protocol.Chain, pinStore *pin.Store) *Manager {
 	return &Manager{
-		db:          db,
-		chain:       chain,
-		utxoDB:      newReserver(db, chain, pinStore),
-		pinStore:    pinStore,
-		cache:       lru.New(maxAccountCache),
-		aliasCache:  lru.New(maxAccountCache),
-		delayedACPs: make(map[*txbuilder.TemplateBuilder][]*controlProgram),
+		db:           db,
+		chain:        chain,
+		utxoDB:       newReserver(db, chain),
+		pinStore:     pinStore,
+		cache:        lru.New(maxAccountCache),
+		aliasCache:   lru.New(maxAccountCache),
+		versionCache: lru.New(maxAccountCache),
+		delayedACPs:  make(map[*txbuilder.TemplateBuilder][]*controlProgram),
 	}
 }
 
@@ -47,9 +48,10 @@ type Manager struct {
 	indexer  Saver
 	pinStore *pin.Store
 
-	cacheMu    sync.Mutex
-	cache      *lru.Cache
-	aliasCache *lru.Cache
+	cacheMu      sync.Mutex
+	cache        *lru.Cache
+	aliasCache   *lru.Cache
+	versionCache *lru.Cache // signerVersion -> *signers.Signer
 
 	delayedACPsMu sync.Mutex
 	delayedACPs   map[*txbuilder.TemplateBuilder][]*controlProgram
@@ -85,10 +87,20 @@ type Account struct {
 	*signers.Signer
 	Alias string
 	Tags  map[string]interface{}
+
+	// CoinSelection is the strategy for spends from the
+	// account that don't give one. If it's empty,
+	// DefaultSelectionStrategy is used.
+	CoinSelection SelectionStrategy
 }
 
 // Create creates a new Account.
-func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int, alias string, tags map[string]interface{}, clientToken string) (*Account, error) {
+func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int, alias string, tags map[string]interface{}, coinSelection SelectionStrategy, clientToken string) (*Account, error) {
+	err := coinSelection.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	signer, err := signers.Create(ctx, m.db, "account", xpubs, quorum, clientToken)
 	if err != nil {
 		return nil, errors.Wrap(err)
@@ -105,10 +117,10 @@ func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int,
 	}
 
 	const q = `
-		INSERT INTO accounts (account_id, alias, tags) VALUES ($1, $2, $3)
-		ON CONFLICT (account_id) DO UPDATE SET alias = $2, tags = $3
+		INSERT INTO accounts (account_id, alias, tags, coin_selection) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (account_id) DO UPDATE SET alias = $2, tags = $3, coin_selection = $4
 	`
-	_, err = m.db.Exec(ctx, q, signer.ID, aliasSQL, tagsParam)
+	_, err = m.db.Exec(ctx, q, signer.ID, aliasSQL, tagsParam, string(coinSelection))
 	if pg.IsUniqueViolation(err) {
 		return nil, errors.WithDetail(ErrDuplicateAlias, "an account with the provided alias already exists")
 	} else if err != nil {
@@ -116,9 +128,10 @@ func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int,
 	}
 
 	account := &Account{
-		Signer: signer,
-		Alias:  alias,
-		Tags:   tags,
+		Signer:        signer,
+		Alias:         alias,
+		Tags:          tags,
+		CoinSelection: coinSelection,
 	}
 
 	err = m.indexAnnotatedAccount(ctx, account)
@@ -129,6 +142,63 @@ func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int,
 	return account, nil
 }
 
+// UpdateCoinSelection changes an account's coin selection
+// strategy for spends that don't give one. If coinSelection
+// is empty, DefaultSelectionStrategy is used.
+func (m *Manager) UpdateCoinSelection(ctx context.Context, accountID string, coinSelection SelectionStrategy) (*Account, error) {
+	err := coinSelection.validate()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := m.findByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `UPDATE accounts SET coin_selection = $2 WHERE account_id = $1`
+	_, err = m.db.Exec(ctx, q, accountID, string(coinSelection))
+	if err != nil {
+		return nil, errors.Wrap(err, "update coin selection")
+	}
+
+	account, err := m.loadAccount(ctx, signer)
+	if err != nil {
+		return nil, err
+	}
+	err = m.indexAnnotatedAccount(ctx, account)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexing annotated account")
+	}
+	return account, nil
+}
+
+// loadAccount returns the Account with signer
+// and the alias, tags and coin selection stored for it.
+func (m *Manager) loadAccount(ctx context.Context, signer *signers.Signer) (*Account, error) {
+	const q = `SELECT alias, tags, coin_selection FROM accounts WHERE account_id = $1`
+	var (
+		alias         stdsql.NullString
+		tags          []byte
+		coinSelection string
+	)
+	err := m.db.QueryRow(ctx, q, signer.ID).Scan(&alias, &tags, &coinSelection)
+	if err != nil {
+		return nil, errors.Wrap(err, "get account info")
+	}
+	account := &Account{
+		Signer:        signer,
+		Alias:         alias.String,
+		CoinSelection: SelectionStrategy(coinSelection),
+	}
+	if len(tags) > 0 {
+		err = json.Unmarshal(tags, &account.Tags)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding account tags")
+		}
+	}
+	return account, nil
+}
+
 // FindByAlias retrieves an account's Signer record by its alias
 func (m *Manager) FindByAlias(ctx context.Context, alias string) (*signers.Signer, error) {
 	var accountID string
@@ -172,19 +242,71 @@ func (m *Manager) findByID(ctx context.Context, id string) (*signers.Signer, err
 	return account, nil
 }
 
+// signerVersion identifies a version of an account's keys.
+type signerVersion struct {
+	accountID string
+	version   int
+}
+
+// findVersion returns the given version of an account's Signer
+// record. UTXOs are controlled by the version of the account's
+// keys that was current when their control programs were made.
+func (m *Manager) findVersion(ctx context.Context, id string, version int) (*signers.Signer, error) {
+	key := signerVersion{id, version}
+	m.cacheMu.Lock()
+	cached, ok := m.versionCache.Get(key)
+	m.cacheMu.Unlock()
+	if ok {
+		return cached.(*signers.Signer), nil
+	}
+	account, err := signers.FindVersion(ctx, m.db, "account", id, version)
+	if err != nil {
+		return nil, err
+	}
+	m.cacheMu.Lock()
+	m.versionCache.Add(key, account) // versions never change
+	m.cacheMu.Unlock()
+	return account, nil
+}
+
+// selectionStrategy returns the coin selection strategy
+// for spends from the account that don't give one.
+// It's read from the database each time, since another
+// process may have changed it.
+func (m *Manager) selectionStrategy(ctx context.Context, accountID string) (SelectionStrategy, error) {
+	const q = `SELECT coin_selection FROM accounts WHERE account_id=$1`
+	var s string
+	err := m.db.QueryRow(ctx, q, accountID).Scan(&s)
+	if err == stdsql.ErrNoRows {
+		return "", errors.WithDetailf(pg.ErrUserInputNotFound, "account id: %s", accountID)
+	} else if err != nil {
+		return "", errors.Wrap(err)
+	}
+	if s == "" {
+		return DefaultSelectionStrategy, nil
+	}
+	return SelectionStrategy(s), nil
+}
+
 type controlProgram struct {
 	accountID      string
 	keyIndex       uint64
+	keyVersion     int
 	controlProgram []byte
 	change         bool
 	expiresAt      time.Time
 }
 
 func (m *Manager) createControlProgram(ctx context.Context, accountID string, change bool, expiresAt time.Time) (*controlProgram, error) {
-	account, err := m.findByID(ctx, accountID)
+	// Read the account's keys from the database rather than the
+	// cache, which may be stale if another process updated them.
+	account, err := signers.Find(ctx, m.db, "account", accountID)
 	if err != nil {
 		return nil, err
 	}
+	m.cacheMu.Lock()
+	m.cache.Add(accountID, account)
+	m.cacheMu.Unlock()
 
 	idx, err := m.nextIndex(ctx)
 	if err != nil {
@@ -201,6 +323,7 @@ func (m *Manager) createControlProgram(ctx context.Context, accountID string, ch
 	return &controlProgram{
 		accountID:      account.ID,
 		keyIndex:       idx,
+		keyVersion:     account.Version,
 		controlProgram: control,
 		change:         change,
 		expiresAt:      expiresAt,
@@ -223,13 +346,14 @@ func (m *Manager) CreateControlProgram(ctx context.Context, accountID string, ch
 
 func (m *Manager) insertAccountControlProgram(ctx context.Context, progs ...*controlProgram) error {
 	const q = `
-		INSERT INTO account_control_programs (signer_id, key_index, control_program, change, expires_at)
-		SELECT unnest($1::text[]), unnest($2::bigint[]), unnest($3::bytea[]), unnest($4::boolean[]),
-			unnest($5::timestamp with time zone[])
+		INSERT INTO account_control_programs (signer_id, key_index, key_version, control_program, change, expires_at)
+		SELECT unnest($1::text[]), unnest($2::bigint[]), unnest($3::integer[]), unnest($4::bytea[]),
+			unnest($5::boolean[]), unnest($6::timestamp with time zone[])
 	`
 	var (
 		accountIDs   pq.StringArray
 		keyIndexes   pq.Int64Array
+		keyVersions  pq.Int64Array
 		controlProgs pq.ByteaArray
 		change       pq.BoolArray
 		expirations  []stdsql.NullString
@@ -237,6 +361,7 @@ func (m *Manager) insertAccountControlProgram(ctx context.Context, progs ...*con
 	for _, p := range progs {
 		accountIDs = append(accountIDs, p.accountID)
 		keyIndexes = append(keyIndexes, int64(p.keyIndex))
+		keyVersions = append(keyVersions, int64(p.keyVersion))
 		controlProgs = append(controlProgs, p.controlProgram)
 		change = append(change, p.change)
 		expirations = append(expirations, stdsql.NullString{
@@ -245,7 +370,7 @@ func (m *Manager) insertAccountControlProgram(ctx context.Context, progs ...*con
 		})
 	}
 
-	_, err := m.db.Exec(ctx, q, accountIDs, keyIndexes, controlProgs, change, pq.Array(expirations))
+	_, err := m.db.Exec(ctx, q, accountIDs, keyIndexes, keyVersions, controlProgs, change, pq.Array(expirations))
 	return errors.Wrap(err)
 }
 
@@ -19,7 +19,7 @@ func TestCreateAccount(t *testing.T) {
 	m := NewManager(db, prottest.NewChain(t), nil)
 	ctx := context.Background()
 
-	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "")
+	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "", "")
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -42,11 +42,11 @@ func TestCreateAccountIdempotency(t *testing.T) {
 	ctx := context.Background()
 	var clientToken = "a-unique-client-token"
 
-	account1, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "satoshi", nil, clientToken)
+	account1, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "satoshi", nil, "", clientToken)
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
-	account2, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "satoshi", nil, clientToken)
+	account2, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "satoshi", nil, "", clientToken)
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -61,7 +61,7 @@ func TestCreateAccountReusedAlias(t *testing.T) {
 	ctx := context.Background()
 	m.createTestAccount(ctx, t, "some-account", nil)
 
-	_, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "some-account", nil, "")
+	_, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "some-account", nil, "", "")
 	if errors.Root(err) != ErrDuplicateAlias {
 		t.Errorf("Expected %s when reusing an alias, got %v", ErrDuplicateAlias, err)
 	}
@@ -73,7 +73,7 @@ func TestCreateControlProgram(t *testing.T) {
 	m := NewManager(db, prottest.NewChain(t), nil)
 	ctx := context.Background()
 
-	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "")
+	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "", "")
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -94,7 +94,7 @@ func TestCreateControlProgram(t *testing.T) {
 }
 
 func (m *Manager) createTestAccount(ctx context.Context, t testing.TB, alias string, tags map[string]interface{}) *Account {
-	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, alias, tags, "")
+	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, alias, tags, "", "")
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -146,3 +146,40 @@ func TestFindByAlias(t *testing.T) {
 		t.Errorf("expected found account to be %v, instead found %v", account, found)
 	}
 }
+
+func TestUpdateCoinSelection(t *testing.T) {
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+	account := m.createTestAccount(ctx, t, "some-alias", nil)
+
+	s, err := m.selectionStrategy(ctx, account.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if s != DefaultSelectionStrategy {
+		t.Fatalf("selection strategy = %q want %q", s, DefaultSelectionStrategy)
+	}
+
+	// Update it in another process's Manager.
+	other := NewManager(db, m.chain, nil)
+	updated, err := other.UpdateCoinSelection(ctx, account.ID, OldestFirst)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if updated.CoinSelection != OldestFirst || updated.Alias != "some-alias" {
+		t.Errorf("UpdateCoinSelection got %q alias %q, want %q and some-alias", updated.CoinSelection, updated.Alias, OldestFirst)
+	}
+	s, err = m.selectionStrategy(ctx, account.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if s != OldestFirst {
+		t.Errorf("after update, selection strategy = %q want %q", s, OldestFirst)
+	}
+
+	_, err = m.UpdateCoinSelection(ctx, account.ID, "bogus")
+	if errors.Root(err) != ErrBadSelectionStrategy {
+		t.Errorf("UpdateCoinSelection(bogus) = %v want %v", err, ErrBadSelectionStrategy)
+	}
+}
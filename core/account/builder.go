@@ -31,9 +31,10 @@ func (m *Manager) DecodeSpendAction(data []byte) (txbuilder.Action, error) {
 type spendAction struct {
 	accounts *Manager
 	bc.AssetAmount
-	AccountID     string        `json:"account_id"`
-	ReferenceData chainjson.Map `json:"reference_data"`
-	ClientToken   *string       `json:"client_token"`
+	AccountID     string            `json:"account_id"`
+	CoinSelection SelectionStrategy `json:"coin_selection"`
+	ReferenceData chainjson.Map     `json:"reference_data"`
+	ClientToken   *string           `json:"client_token"`
 }
 
 func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
@@ -47,17 +48,29 @@ func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) e
 	if len(missing) > 0 {
 		return txbuilder.MissingFieldsError(missing...)
 	}
+	err := a.CoinSelection.validate()
+	if err != nil {
+		return err
+	}
 
-	acct, err := a.accounts.findByID(ctx, a.AccountID)
+	_, err = a.accounts.findByID(ctx, a.AccountID)
 	if err != nil {
 		return errors.Wrap(err, "get account info")
 	}
 
+	strategy := a.CoinSelection
+	if strategy == "" {
+		strategy, err = a.accounts.selectionStrategy(ctx, a.AccountID)
+		if err != nil {
+			return errors.Wrap(err, "get account coin selection")
+		}
+	}
+
 	src := source{
 		AssetID:   a.AssetID,
 		AccountID: a.AccountID,
 	}
-	res, err := a.accounts.utxoDB.Reserve(ctx, src, a.Amount, a.ClientToken, b.MaxTime())
+	res, err := a.accounts.utxoDB.Reserve(ctx, src, a.Amount, strategy, a.ClientToken, b.MaxTime())
 	if err != nil {
 		return errors.Wrap(err, "reserving utxos")
 	}
@@ -66,6 +79,10 @@ func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) e
 	b.OnRollback(canceler(ctx, a.accounts, res.ID))
 
 	for _, r := range res.UTXOs {
+		acct, err := a.accounts.findVersion(ctx, a.AccountID, r.KeyVersion)
+		if err != nil {
+			return errors.Wrap(err, "get account keys")
+		}
 		txInput, sigInst, err := utxoToInputs(ctx, acct, r, a.ReferenceData)
 		if err != nil {
 			return errors.Wrap(err, "creating inputs")
@@ -125,11 +142,12 @@ func (a *spendUTXOAction) Build(ctx context.Context, b *txbuilder.TemplateBuilde
 	}
 	b.OnRollback(canceler(ctx, a.accounts, res.ID))
 
-	acct, err := a.accounts.findByID(ctx, res.Source.AccountID)
+	u := res.UTXOs[0]
+	acct, err := a.accounts.findVersion(ctx, u.AccountID, u.KeyVersion)
 	if err != nil {
 		return err
 	}
-	txInput, sigInst, err := utxoToInputs(ctx, acct, res.UTXOs[0], a.ReferenceData)
+	txInput, sigInst, err := utxoToInputs(ctx, acct, u, a.ReferenceData)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,247 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+)
+
+// consolidationTTL is how long a consolidation
+// transaction has to be confirmed.
+const consolidationTTL = 5 * time.Minute
+
+// A ConsolidationPolicy says when to merge an account's
+// small UTXOs into one.
+type ConsolidationPolicy struct {
+	// MinUTXOs is how many small UTXOs of one asset an
+	// account must have before they're consolidated.
+	// It must be at least 2.
+	MinUTXOs int
+
+	// MaxAmount is the largest amount a UTXO can have
+	// and still count as small, or 0 for no limit.
+	MaxAmount uint64
+
+	// MaxInputs is the most UTXOs to spend
+	// in one consolidation transaction.
+	MaxInputs int
+}
+
+// DefaultConsolidationPolicy consolidates any account with
+// at least 50 UTXOs of one asset, up to 100 at a time.
+var DefaultConsolidationPolicy = ConsolidationPolicy{
+	MinUTXOs:  50,
+	MaxInputs: 100,
+}
+
+// Validate returns an error if p's limits are
+// inconsistent, so it can't consolidate anything.
+func (p ConsolidationPolicy) Validate() error {
+	if p.MinUTXOs < 2 || p.MaxInputs < p.MinUTXOs {
+		return errors.New("consolidation policy must have 2 <= MinUTXOs <= MaxInputs")
+	}
+	return nil
+}
+
+// A TemplateSigner adds signatures to tpl
+// for the keys it holds among xpubs.
+type TemplateSigner func(ctx context.Context, tpl *txbuilder.Template, xpubs []chainkd.XPub) error
+
+// A Consolidation records a transaction that
+// merged some of an account's UTXOs.
+type Consolidation struct {
+	TxID      bc.Hash    `json:"transaction_id"`
+	AccountID string     `json:"account_id"`
+	AssetID   bc.AssetID `json:"asset_id"`
+	Inputs    int        `json:"inputs"`
+	Amount    uint64     `json:"amount"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ConsolidateUTXOs builds, signs, and submits a transaction for each
+// batch of small UTXOs that p says to consolidate, spending them to a
+// new control program in the same account. It returns the number of
+// transactions submitted. Failures for individual accounts are
+// logged, and don't stop the others from being consolidated.
+func (m *Manager) ConsolidateUTXOs(ctx context.Context, p ConsolidationPolicy, sign TemplateSigner, submitter txbuilder.Submitter) (int, error) {
+	err := p.Validate()
+	if err != nil {
+		return 0, err
+	}
+
+	const q = `
+		SELECT account_id, asset_id FROM account_utxos
+		WHERE $1 = 0 OR amount <= $1
+		GROUP BY account_id, asset_id HAVING COUNT(*) >= $2
+	`
+	var srcs []source
+	err = pg.ForQueryRows(ctx, m.db, q, p.MaxAmount, p.MinUTXOs, func(accountID string, assetID bc.AssetID) {
+		srcs = append(srcs, source{AccountID: accountID, AssetID: assetID})
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "finding accounts to consolidate")
+	}
+
+	var n int
+	for _, src := range srcs {
+		for {
+			c, err := m.consolidate(ctx, src, p, sign, submitter)
+			if err != nil {
+				log.Error(ctx, err, "account_id", src.AccountID, "asset_id", src.AssetID)
+				break
+			}
+			if c == nil {
+				break // too few small UTXOs left
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ConsolidateUTXOsPeriodically calls ConsolidateUTXOs with p every
+// period. It blocks until the context is canceled.
+func (m *Manager) ConsolidateUTXOsPeriodically(ctx context.Context, p ConsolidationPolicy, period time.Duration, sign TemplateSigner, submitter txbuilder.Submitter) {
+	ticks := time.Tick(period)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Messagef(ctx, "Deposed, ConsolidateUTXOsPeriodically exiting")
+			return
+		case <-ticks:
+			n, err := m.ConsolidateUTXOs(ctx, p, sign, submitter)
+			if err != nil {
+				log.Error(ctx, err)
+			} else if n > 0 {
+				log.Messagef(ctx, "submitted %d utxo consolidation transactions", n)
+			}
+		}
+	}
+}
+
+// consolidate reserves one batch of src's small UTXOs and spends
+// them. It returns nil if there are too few to consolidate.
+func (m *Manager) consolidate(ctx context.Context, src source, p ConsolidationPolicy, sign TemplateSigner, submitter txbuilder.Submitter) (*Consolidation, error) {
+	maxTime := time.Now().Add(consolidationTTL)
+	res, err := m.utxoDB.ReserveSmall(ctx, src, p.MaxAmount, p.MinUTXOs, p.MaxInputs, maxTime)
+	if err != nil || res == nil {
+		return nil, errors.Wrap(err, "reserving utxos")
+	}
+
+	// The action cancels the reservation if the build fails.
+	action := &consolidateAction{accounts: m, res: res}
+	tpl, err := txbuilder.Build(ctx, nil, []txbuilder.Action{action}, maxTime)
+	if err != nil {
+		return nil, errors.Wrap(err, "building consolidation tx")
+	}
+	cancel := canceler(ctx, m, res.ID)
+
+	xpubs, err := m.utxoXPubs(ctx, res.UTXOs)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "get account keys")
+	}
+	err = sign(ctx, tpl, xpubs)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "signing consolidation tx")
+	}
+	err = txbuilder.FinalizeTx(ctx, m.chain, submitter, tpl.Transaction)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "submitting consolidation tx")
+	}
+
+	// The reservation is left to expire. By then, the
+	// transaction has either spent the UTXOs or been
+	// rejected, leaving them free to try again.
+	c := &Consolidation{
+		TxID:      tpl.Transaction.ID,
+		AccountID: src.AccountID,
+		AssetID:   src.AssetID,
+		Inputs:    len(res.UTXOs),
+	}
+	for _, u := range res.UTXOs {
+		c.Amount += u.Amount
+	}
+	const q = `
+		INSERT INTO account_consolidations (tx_hash, account_id, asset_id, inputs, amount)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	err = m.db.QueryRow(ctx, q, c.TxID, c.AccountID, c.AssetID, c.Inputs, c.Amount).Scan(&c.CreatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "recording consolidation")
+	}
+	return c, nil
+}
+
+// ListConsolidations returns the most recent consolidation
+// transactions, newest first, for the account with the given
+// ID, or for all accounts if accountID is empty.
+func (m *Manager) ListConsolidations(ctx context.Context, accountID string, limit int) ([]*Consolidation, error) {
+	const q = `
+		SELECT tx_hash, account_id, asset_id, inputs, amount, created_at
+		FROM account_consolidations
+		WHERE $1 = '' OR account_id = $1
+		ORDER BY created_at DESC LIMIT $2
+	`
+	cs := []*Consolidation{} // not null
+	err := pg.ForQueryRows(ctx, m.db, q, accountID, limit, func(txID bc.Hash, accountID string, assetID bc.AssetID, inputs int, amount uint64, createdAt time.Time) {
+		cs = append(cs, &Consolidation{
+			TxID:      txID,
+			AccountID: accountID,
+			AssetID:   assetID,
+			Inputs:    inputs,
+			Amount:    amount,
+			CreatedAt: createdAt,
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing consolidations")
+	}
+	return cs, nil
+}
+
+// consolidateAction spends the UTXOs in a reservation
+// to a single new control program in the same account.
+type consolidateAction struct {
+	accounts *Manager
+	res      *reservation
+}
+
+func (a *consolidateAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	// Cancel the reservation if the build gets rolled back.
+	b.OnRollback(canceler(ctx, a.accounts, a.res.ID))
+
+	src := a.res.Source
+	var total uint64
+	for _, u := range a.res.UTXOs {
+		acct, err := a.accounts.findVersion(ctx, src.AccountID, u.KeyVersion)
+		if err != nil {
+			return errors.Wrap(err, "get account keys")
+		}
+		txInput, sigInst, err := utxoToInputs(ctx, acct, u, nil)
+		if err != nil {
+			return errors.Wrap(err, "creating inputs")
+		}
+		err = b.AddInput(txInput, sigInst)
+		if err != nil {
+			return errors.Wrap(err, "adding inputs")
+		}
+		total += u.Amount // selectSmall ensures this doesn't overflow
+	}
+
+	acp, err := a.accounts.createControlProgram(ctx, src.AccountID, true, b.MaxTime())
+	if err != nil {
+		return errors.Wrap(err, "creating control program")
+	}
+	a.accounts.insertControlProgramDelayed(ctx, b, acp)
+	return b.AddOutput(bc.NewTxOutput(src.AssetID, total, acp.controlProgram, nil))
+}
@@ -0,0 +1,80 @@
+package account
+
+import (
+	"context"
+	"testing"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/protocol/bc"
+	"chain/protocol/memstore"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestConsolidateUTXOs(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+
+	outputIDs := []bc.Hash{{1}, {2}, {3}}
+	c := prottest.NewChainWithStorage(t, memstore.New(), outputIDs...)
+	m := NewManager(db, c, nil)
+	acc := m.createTestAccount(ctx, t, "", nil)
+	prog := m.createTestControlProgram(ctx, t, acc.ID)
+	for _, id := range outputIDs {
+		pgtest.Exec(ctx, db, t, `
+			INSERT INTO account_utxos (output_id, asset_id, amount, account_id,
+				control_program_index, control_program, confirmed_in)
+			VALUES ($1, $2, 1, $3, 1, $4, 1)
+		`, id, bc.AssetID{1}, acc.ID, prog)
+	}
+
+	sign := func(ctx context.Context, tpl *txbuilder.Template, xpubs []chainkd.XPub) error {
+		return txbuilder.Sign(ctx, tpl, xpubs, func(_ context.Context, _ chainkd.XPub, path [][]byte, data [32]byte) ([]byte, error) {
+			return testutil.TestXPrv.Derive(path).Sign(data[:]), nil
+		})
+	}
+	var submitted []*bc.Tx
+	submitter := submitterFunc(func(ctx context.Context, tx *bc.Tx) error {
+		submitted = append(submitted, tx)
+		return nil
+	})
+
+	_, err := m.ConsolidateUTXOs(ctx, ConsolidationPolicy{MinUTXOs: 3, MaxInputs: 2}, sign, submitter)
+	if err == nil {
+		t.Error("ConsolidateUTXOs accepted MinUTXOs > MaxInputs")
+	}
+	err = DefaultConsolidationPolicy.Validate()
+	if err != nil {
+		t.Errorf("DefaultConsolidationPolicy.Validate() = %v", err)
+	}
+
+	// Two of the three UTXOs are consolidated,
+	// leaving too few for another transaction.
+	n, err := m.ConsolidateUTXOs(ctx, ConsolidationPolicy{MinUTXOs: 2, MaxInputs: 2}, sign, submitter)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if n != 1 || len(submitted) != 1 {
+		t.Fatalf("ConsolidateUTXOs submitted %d (%d) txs, want 1", n, len(submitted))
+	}
+	tx := submitted[0]
+	if len(tx.Inputs) != 2 || len(tx.Outputs) != 1 || tx.Outputs[0].Amount != 2 {
+		t.Errorf("consolidation tx has %d inputs and %d outputs, want 2 inputs and one output of 2", len(tx.Inputs), len(tx.Outputs))
+	}
+
+	cs, err := m.ListConsolidations(ctx, acc.ID, 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(cs) != 1 || cs[0].TxID != tx.ID || cs[0].Inputs != 2 || cs[0].Amount != 2 {
+		t.Errorf("ListConsolidations = %+v, want one of 2 inputs and amount 2 in tx %x", cs, tx.ID.Bytes())
+	}
+}
+
+type submitterFunc func(context.Context, *bc.Tx) error
+
+func (f submitterFunc) Submit(ctx context.Context, tx *bc.Tx) error {
+	return f(ctx, tx)
+}
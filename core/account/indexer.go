@@ -35,10 +35,11 @@ type Saver interface {
 
 func Annotated(a *Account) (*query.AnnotatedAccount, error) {
 	aa := &query.AnnotatedAccount{
-		ID:     a.ID,
-		Alias:  a.Alias,
-		Quorum: a.Quorum,
-		Tags:   &emptyJSONObject,
+		ID:            a.ID,
+		Alias:         a.Alias,
+		Quorum:        a.Quorum,
+		Tags:          &emptyJSONObject,
+		CoinSelection: string(a.CoinSelection),
 	}
 
 	tags, err := json.Marshal(a.Tags)
@@ -86,8 +87,9 @@ type rawOutput struct {
 
 type accountOutput struct {
 	rawOutput
-	AccountID string
-	keyIndex  uint64
+	AccountID  string
+	keyIndex   uint64
+	keyVersion int
 }
 
 func (m *Manager) ProcessBlocks(ctx context.Context) {
@@ -176,16 +178,17 @@ func (m *Manager) loadAccountInfo(ctx context.Context, outs []*rawOutput) ([]*ac
 	result := make([]*accountOutput, 0, len(outs))
 
 	const q = `
-		SELECT signer_id, key_index, control_program
+		SELECT signer_id, key_index, key_version, control_program
 		FROM account_control_programs
 		WHERE control_program IN (SELECT unnest($1::bytea[]))
 	`
-	err := pg.ForQueryRows(ctx, m.db, q, scripts, func(accountID string, keyIndex uint64, program []byte) {
+	err := pg.ForQueryRows(ctx, m.db, q, scripts, func(accountID string, keyIndex uint64, keyVersion int, program []byte) {
 		for _, out := range outsByScript[string(program)] {
 			newOut := &accountOutput{
-				rawOutput: *out,
-				AccountID: accountID,
-				keyIndex:  keyIndex,
+				rawOutput:  *out,
+				AccountID:  accountID,
+				keyIndex:   keyIndex,
+				keyVersion: keyVersion,
 			}
 			result = append(result, newOut)
 		}
@@ -208,6 +211,7 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		accountID pq.StringArray
 		cpIndex   pq.Int64Array
 		program   pq.ByteaArray
+		version   pq.Int64Array
 	)
 	for _, out := range outs {
 		outputID = append(outputID, out.OutputID.Bytes())
@@ -216,13 +220,14 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		accountID = append(accountID, out.AccountID)
 		cpIndex = append(cpIndex, int64(out.keyIndex))
 		program = append(program, out.ControlProgram)
+		version = append(version, int64(out.keyVersion))
 	}
 
 	const q = `
 		INSERT INTO account_utxos (output_id, asset_id, amount, account_id, control_program_index,
-			control_program, confirmed_in)
+			control_program, key_version, confirmed_in)
 		SELECT unnest($1::bytea[]), unnest($2::bytea[]),  unnest($3::bigint[]),
-			   unnest($4::text[]), unnest($5::bigint[]), unnest($6::bytea[]), $7
+			   unnest($4::text[]), unnest($5::bigint[]), unnest($6::bytea[]), unnest($7::integer[]), $8
 		ON CONFLICT (output_id) DO NOTHING
 	`
 	_, err := m.db.Exec(ctx, q,
@@ -232,6 +237,7 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		accountID,
 		cpIndex,
 		program,
+		version,
 		block.Height,
 	)
 	return errors.Wrap(err)
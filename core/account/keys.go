@@ -0,0 +1,121 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"chain/core/signers"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// maxSweepInputs is the most UTXOs spent
+// by one transaction built by SweepOldKeys.
+const maxSweepInputs = 100
+
+// UpdateKeys replaces an account's keys and quorum, making a new
+// version of its Signer record. Control programs created afterward
+// use the new keys. UTXOs controlled by the account's older keys
+// are still spent with those keys; see SweepOldKeys.
+func (m *Manager) UpdateKeys(ctx context.Context, accountID string, xpubs []chainkd.XPub, quorum int) (*Account, error) {
+	signer, err := signers.Update(ctx, m.db, "account", accountID, xpubs, quorum)
+	if err != nil {
+		return nil, err
+	}
+	m.cacheMu.Lock()
+	m.cache.Add(accountID, signer)
+	m.cacheMu.Unlock()
+
+	account, err := m.loadAccount(ctx, signer)
+	if err != nil {
+		return nil, err
+	}
+	err = m.indexAnnotatedAccount(ctx, account)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexing annotated account")
+	}
+	return account, nil
+}
+
+// SweepOldKeys builds transactions that spend the account's available
+// UTXOs controlled by older versions of its keys to new control
+// programs with its current keys, one asset and at most
+// maxSweepInputs UTXOs per transaction. The UTXOs are reserved until
+// maxTime; the templates must be signed with the old keys and
+// submitted before then.
+func (m *Manager) SweepOldKeys(ctx context.Context, accountID string, maxTime time.Time) ([]*txbuilder.Template, error) {
+	acct, err := signers.Find(ctx, m.db, "account", accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		SELECT DISTINCT asset_id FROM account_utxos
+		WHERE account_id = $1 AND key_version < $2
+	`
+	var assetIDs []bc.AssetID
+	err = pg.ForQueryRows(ctx, m.db, q, accountID, acct.Version, func(assetID bc.AssetID) {
+		assetIDs = append(assetIDs, assetID)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "finding old-key utxos")
+	}
+
+	tpls := []*txbuilder.Template{} // not null
+	var rollbacks []func()
+	rollback := func() {
+		for _, f := range rollbacks {
+			f()
+		}
+	}
+	for _, assetID := range assetIDs {
+		src := source{AccountID: accountID, AssetID: assetID}
+		for {
+			res, err := m.utxoDB.ReserveOldKeys(ctx, src, acct.Version, maxSweepInputs, maxTime)
+			if err != nil {
+				rollback()
+				return nil, errors.Wrap(err, "reserving utxos")
+			}
+			if res == nil {
+				break // none left
+			}
+
+			// The action cancels the reservation if the build fails.
+			action := &consolidateAction{accounts: m, res: res}
+			tpl, err := txbuilder.Build(ctx, nil, []txbuilder.Action{action}, maxTime)
+			if err != nil {
+				rollback()
+				return nil, errors.Wrap(err, "building sweep tx")
+			}
+			rollbacks = append(rollbacks, canceler(ctx, m, res.ID))
+			tpls = append(tpls, tpl)
+		}
+	}
+	return tpls, nil
+}
+
+// utxoXPubs returns the root xpubs of every
+// version of the keys controlling utxos.
+func (m *Manager) utxoXPubs(ctx context.Context, utxos []*utxo) ([]chainkd.XPub, error) {
+	var (
+		xpubs []chainkd.XPub
+		seen  = make(map[signerVersion]bool)
+	)
+	for _, u := range utxos {
+		key := signerVersion{u.AccountID, u.KeyVersion}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		signer, err := m.findVersion(ctx, u.AccountID, u.KeyVersion)
+		if err != nil {
+			return nil, err
+		}
+		xpubs = append(xpubs, signer.XPubs...)
+	}
+	return xpubs, nil
+}
@@ -0,0 +1,89 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/protocol/bc"
+	"chain/protocol/memstore"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestUpdateKeys(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+
+	// Two old-key UTXOs, already in the state tree.
+	outputIDs := []bc.Hash{{1}, {2}}
+	c := prottest.NewChainWithStorage(t, memstore.New(), outputIDs...)
+	m := NewManager(db, c, nil)
+	acc := m.createTestAccount(ctx, t, "", nil)
+	oldProg := m.createTestControlProgram(ctx, t, acc.ID)
+	for i, id := range outputIDs {
+		pgtest.Exec(ctx, db, t, `
+			INSERT INTO account_utxos (output_id, asset_id, amount, account_id,
+				control_program_index, control_program, confirmed_in)
+			VALUES ($1, $2, $3, $4, 1, $5, 1)
+		`, id, bc.AssetID{1}, i+1, acc.ID, oldProg)
+	}
+
+	newXPrv, err := chainkd.NewXPrv(nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	newXPub := newXPrv.XPub()
+	updated, err := m.UpdateKeys(ctx, acc.ID, []chainkd.XPub{newXPub}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if updated.Version != 2 || updated.Alias != acc.Alias {
+		t.Errorf("UpdateKeys got version %d alias %q, want 2 and %q", updated.Version, updated.Alias, acc.Alias)
+	}
+
+	// New control programs use the new keys.
+	cp, err := m.createControlProgram(ctx, acc.ID, false, time.Time{})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if cp.keyVersion != 2 {
+		t.Errorf("control program key version = %d want 2", cp.keyVersion)
+	}
+
+	tpls, err := m.SweepOldKeys(ctx, acc.ID, time.Now().Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(tpls) != 1 {
+		t.Fatalf("SweepOldKeys built %d templates want 1", len(tpls))
+	}
+	tx := tpls[0].Transaction
+	if len(tx.Inputs) != 2 || len(tx.Outputs) != 1 || tx.Outputs[0].Amount != 3 {
+		t.Fatalf("sweep tx has %d inputs and %d outputs, want 2 inputs and one output of 3", len(tx.Inputs), len(tx.Outputs))
+	}
+	if bytes.Equal(tx.Outputs[0].ControlProgram, oldProg) {
+		t.Error("sweep tx pays to an old-key control program")
+	}
+
+	// The old UTXOs are still signed for with the old keys.
+	for _, sigInst := range tpls[0].SigningInstructions {
+		sw := sigInst.WitnessComponents[0].(*txbuilder.SignatureWitness)
+		if len(sw.Keys) != 1 || sw.Keys[0].XPub != testutil.TestXPub {
+			t.Errorf("input %d signing keys = %+v want old key %x", sigInst.Position, sw.Keys, testutil.TestXPub[:])
+		}
+	}
+
+	// They're reserved, so there's nothing left to sweep.
+	tpls, err = m.SweepOldKeys(ctx, acc.ID, time.Now().Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(tpls) != 0 {
+		t.Errorf("second SweepOldKeys built %d templates want 0", len(tpls))
+	}
+}
@@ -17,7 +17,7 @@ func TestCreateReceiver(t *testing.T) {
 	m := NewManager(db, prottest.NewChain(t), nil)
 	ctx := context.Background()
 
-	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "alias", nil, "")
+	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "alias", nil, "", "")
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -0,0 +1,105 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// A Reservation describes a reservation of an
+// account's UTXOs that hasn't expired or been canceled.
+type Reservation struct {
+	ID        uint64     `json:"id"`
+	AccountID string     `json:"account_id"`
+	AssetID   bc.AssetID `json:"asset_id"`
+
+	// Amount is the total of the reserved UTXOs less
+	// Change, which the transaction returns to the account.
+	Amount uint64 `json:"amount"`
+	Change uint64 `json:"change"`
+
+	OutputIDs   []bc.Hash `json:"output_ids"`
+	ClientToken *string   `json:"client_token,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// A ReservationFilter selects reservations. Its
+// zero-valued fields match every reservation.
+type ReservationFilter struct {
+	AccountID   string
+	AssetID     bc.AssetID
+	ClientToken string
+
+	// ExpiresBefore, if not zero, matches reservations
+	// expiring before it.
+	ExpiresBefore time.Time
+}
+
+// ListReservations returns the unexpired reservations that
+// match f, in the order they were made.
+func (m *Manager) ListReservations(ctx context.Context, f ReservationFilter) ([]*Reservation, error) {
+	const q = `
+		SELECT r.reservation_id, r.account_id, r.asset_id, r.change, r.client_token, r.expiry,
+			COALESCE(array_agg(u.output_id) FILTER (WHERE u.output_id IS NOT NULL), '{}'),
+			COALESCE(SUM(u.amount), 0)
+		FROM reservations r
+		LEFT JOIN account_utxos u ON u.reservation_id = r.reservation_id
+		WHERE r.expiry > now()
+			AND ($1 = '' OR r.account_id = $1)
+			AND ($2::bytea IS NULL OR r.asset_id = $2)
+			AND ($3 = '' OR r.client_token = $3)
+			AND ($4::timestamp with time zone IS NULL OR r.expiry < $4)
+		GROUP BY r.reservation_id
+		ORDER BY r.reservation_id
+	`
+	var (
+		assetID       []byte
+		expiresBefore *time.Time
+	)
+	if f.AssetID != (bc.AssetID{}) {
+		assetID = f.AssetID[:]
+	}
+	if !f.ExpiresBefore.IsZero() {
+		expiresBefore = &f.ExpiresBefore
+	}
+
+	list := []*Reservation{} // not null
+	err := pg.ForQueryRows(ctx, m.db, q, f.AccountID, assetID, f.ClientToken, expiresBefore,
+		func(id uint64, accountID string, assetID bc.AssetID, change uint64, clientToken *string, expiry time.Time, outputIDs pq.ByteaArray, total uint64) {
+			r := &Reservation{
+				ID:          id,
+				AccountID:   accountID,
+				AssetID:     assetID,
+				Change:      change,
+				OutputIDs:   make([]bc.Hash, len(outputIDs)),
+				ClientToken: clientToken,
+				ExpiresAt:   expiry,
+			}
+			for i, b := range outputIDs {
+				copy(r.OutputIDs[i][:], b)
+			}
+			// Reserved UTXOs are removed once spent,
+			// which can leave less than the change.
+			if total > change {
+				r.Amount = total - change
+			}
+			list = append(list, r)
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing reservations")
+	}
+	return list, nil
+}
+
+// CancelReservation cancels the reservation with the given ID,
+// making its UTXOs available again, as if the transaction it
+// was made for had been rolled back. It returns an error wrapping
+// pg.ErrUserInputNotFound if there's no such reservation.
+func (m *Manager) CancelReservation(ctx context.Context, id uint64) error {
+	return m.utxoDB.Cancel(ctx, id)
+}
@@ -0,0 +1,128 @@
+package account
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/memstore"
+	"chain/protocol/prottest"
+)
+
+func TestListCancelReservations(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+
+	utxos := []struct {
+		outputID  bc.Hash
+		accountID string
+		assetID   bc.AssetID
+		amount    uint64
+	}{
+		{bc.Hash{1}, "acc1", bc.AssetID{1}, 5},
+		{bc.Hash{2}, "acc1", bc.AssetID{2}, 7},
+		{bc.Hash{3}, "acc2", bc.AssetID{1}, 10},
+	}
+	var outputIDs []bc.Hash
+	for _, u := range utxos {
+		pgtest.Exec(ctx, db, t, `
+			INSERT INTO account_utxos (output_id, asset_id, amount, account_id,
+				control_program_index, control_program, confirmed_in)
+			VALUES ($1, $2, $3, $4, 1, '\x6a'::bytea, 1)
+		`, u.outputID, u.assetID, u.amount, u.accountID)
+		outputIDs = append(outputIDs, u.outputID)
+	}
+	c := prottest.NewChainWithStorage(t, memstore.New(), outputIDs...)
+	m := NewManager(db, c, nil)
+
+	now := time.Now()
+	token := "tok"
+	var ids []uint64
+	for _, r := range []struct {
+		outputID bc.Hash
+		token    *string
+		exp      time.Time
+	}{
+		{bc.Hash{1}, nil, now.Add(time.Minute)},
+		{bc.Hash{2}, &token, now.Add(time.Hour)},
+	} {
+		res, err := m.utxoDB.ReserveUTXO(ctx, r.outputID, r.token, r.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, res.ID)
+	}
+	src := source{AccountID: "acc2", AssetID: bc.AssetID{1}}
+	res, err := m.utxoDB.Reserve(ctx, src, 8, LargestFirst, nil, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids = append(ids, res.ID)
+
+	cases := []struct {
+		filter ReservationFilter
+		want   []uint64
+	}{
+		{ReservationFilter{}, ids},
+		{ReservationFilter{AccountID: "acc1"}, ids[:2]},
+		{ReservationFilter{AssetID: bc.AssetID{1}}, []uint64{ids[0], ids[2]}},
+		{ReservationFilter{AccountID: "acc1", AssetID: bc.AssetID{1}}, ids[:1]},
+		{ReservationFilter{ClientToken: "tok"}, ids[1:2]},
+		{ReservationFilter{ExpiresBefore: now.Add(2 * time.Minute)}, ids[:1]},
+		{ReservationFilter{AccountID: "acc3"}, []uint64{}},
+	}
+	for _, c := range cases {
+		list, err := m.ListReservations(ctx, c.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []uint64{}
+		for _, r := range list {
+			got = append(got, r.ID)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ListReservations(%+v) = %v want %v", c.filter, got, c.want)
+		}
+	}
+
+	got, err := m.ListReservations(ctx, ReservationFilter{AccountID: "acc2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Reservation{
+		ID:        ids[2],
+		AccountID: "acc2",
+		AssetID:   bc.AssetID{1},
+		Amount:    8,
+		Change:    2,
+		OutputIDs: []bc.Hash{{3}},
+	}
+	if len(got) != 1 {
+		t.Fatalf("ListReservations(acc2) = %+v want %+v", got, want)
+	}
+	got[0].ExpiresAt = time.Time{} // rounded by postgres
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("ListReservations(acc2) = %+v want %+v", got[0], want)
+	}
+
+	err = m.CancelReservation(ctx, ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = m.ListReservations(ctx, ReservationFilter{AccountID: "acc2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("after cancel, ListReservations(acc2) = %+v want none", got)
+	}
+	err = m.CancelReservation(ctx, ids[2])
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("CancelReservation(canceled) = %v want %v", err, pg.ErrUserInputNotFound)
+	}
+}
@@ -2,18 +2,21 @@ package account
 
 import (
 	"context"
-	"database/sql"
+	stdsql "database/sql"
 	"fmt"
-	"sync"
-	"sync/atomic"
+	"hash/fnv"
+	"math"
+	"sort"
 	"time"
 
-	"chain/core/pin"
+	"github.com/lib/pq"
+
+	"chain/core/leader"
 	"chain/database/pg"
+	"chain/database/sql"
 	"chain/errors"
 	"chain/protocol"
 	"chain/protocol/bc"
-	"chain/sync/idempotency"
 )
 
 var (
@@ -40,6 +43,8 @@ type utxo struct {
 
 	AccountID           string
 	ControlProgramIndex uint64
+	KeyVersion          int
+	ConfirmedIn         uint64
 }
 
 func (u *utxo) source() source {
@@ -52,6 +57,16 @@ type source struct {
 	AccountID string
 }
 
+// lockKey returns the key of the Postgres advisory lock
+// held while reserving the source's UTXOs. Distinct sources
+// may share a key; that only serializes their reservations.
+func (src source) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(src.AccountID))
+	h.Write(src.AssetID[:])
+	return int64(h.Sum64())
+}
+
 // reservation describes a reservation of a set of UTXOs belonging
 // to a particular account. Reservations are immutable.
 type reservation struct {
@@ -63,137 +78,150 @@ type reservation struct {
 	ClientToken *string
 }
 
-func newReserver(db pg.DB, c *protocol.Chain, pinStore *pin.Store) *reserver {
-	return &reserver{
-		c:            c,
-		db:           db,
-		pinStore:     pinStore,
-		reservations: make(map[uint64]*reservation),
-		sources:      make(map[source]*sourceReserver),
-	}
+// errTooFewUTXOs is returned by a picker when
+// there are too few matching UTXOs to reserve.
+var errTooFewUTXOs = errors.New("too few matching utxos")
+
+// maxCandidateUTXOs is the most available UTXOs
+// reserve considers choosing from.
+const maxCandidateUTXOs = 1000
+
+// A picker chooses which of a source's available UTXOs to
+// reserve, given the totals of all the source's UTXOs, and
+// returns the change due from them.
+type picker func(available []*utxo, t totals) (utxos []*utxo, change uint64, err error)
+
+// totals sums a source's UTXOs in the account_utxos table,
+// and those of them held by unexpired reservations.
+type totals struct {
+	amount, reserved uint64
+	n, nReserved     int
+}
+
+// Orders for the UTXOs reserve considers, as ORDER BY
+// clauses on account_utxos u. See also SelectionStrategy.sqlOrder.
+const (
+	orderSmallest = "u.amount, u.output_id"
+	orderLargest  = "u.amount DESC, u.output_id"
+	orderOldest   = "u.confirmed_in, u.output_id"
+)
+
+func newReserver(db *sql.DB, c *protocol.Chain) *reserver {
+	return &reserver{c: c, db: db}
 }
 
 // reserver implements a utxo reserver that stores reservations
-// in-memory. It relies on the account_utxos table for the source of
-// truth of valid UTXOs but tracks which of those UTXOs are reserved
-// in-memory.
+// in Postgres, so every cored process sees the same reservations
+// and they survive a change of leader. It relies on the
+// account_utxos table for the source of truth of valid UTXOs,
+// checked against the blockchain; see unspentChecker. A UTXO is
+// reserved while its reservation_id refers to a row in the
+// reservations table that hasn't expired.
 //
-// To reduce latency and prevent deadlock, no two mutexes (either on
-// reserver or sourceReserver) should be held at the same time
+// Each reservation considers at most maxCandidateUTXOs of the
+// source's available UTXOs, those its strategy prefers.
 //
-// reserver ensures idempotency of reservations until the reservation
-// expiration.
+// Reservations of UTXOs from the same source are serialized by
+// a transaction-scoped advisory lock on the source.
+//
+// reserver ensures idempotency of reservations made with a client
+// token until the reservation expires or is canceled.
 type reserver struct {
-	c                 *protocol.Chain
-	db                pg.DB
-	pinStore          *pin.Store
-	nextReservationID uint64
-	idempotency       idempotency.Group
-
-	reservationsMu sync.Mutex
-	reservations   map[uint64]*reservation
-
-	sourcesMu sync.Mutex
-	sources   map[source]*sourceReserver
+	c  *protocol.Chain
+	db *sql.DB
 }
 
 // Reserve selects and reserves UTXOs according to the criteria provided
-// in source. The resulting reservation expires at exp.
-func (re *reserver) Reserve(ctx context.Context, src source, amount uint64, clientToken *string, exp time.Time) (*reservation, error) {
-	if clientToken == nil {
-		return re.reserve(ctx, src, amount, clientToken, exp)
-	}
-
-	untypedRes, err := re.idempotency.Once(*clientToken, func() (interface{}, error) {
-		return re.reserve(ctx, src, amount, clientToken, exp)
+// in source, choosing among them with strategy. The resulting
+// reservation expires at exp.
+func (re *reserver) Reserve(ctx context.Context, src source, amount uint64, strategy SelectionStrategy, clientToken *string, exp time.Time) (*reservation, error) {
+	return re.idempotent(ctx, clientToken, func() (*reservation, error) {
+		return re.reserve(ctx, src, nil, strategy.sqlOrder(amount), clientToken, exp, func(available []*utxo, t totals) ([]*utxo, uint64, error) {
+			return selectAmount(available, t, amount, strategy)
+		})
 	})
-	return untypedRes.(*reservation), err
-}
-
-func (re *reserver) reserve(ctx context.Context, src source, amount uint64, clientToken *string, exp time.Time) (res *reservation, err error) {
-	sourceReserver := re.source(src)
-
-	// Try to reserve the right amount.
-	rid := atomic.AddUint64(&re.nextReservationID, 1)
-	reserved, total, err := sourceReserver.reserve(ctx, rid, amount)
-	if err != nil {
-		return nil, err
-	}
-
-	res = &reservation{
-		ID:          rid,
-		Source:      src,
-		UTXOs:       reserved,
-		Expiry:      exp,
-		ClientToken: clientToken,
-	}
-
-	// Save the successful reservation.
-	re.reservationsMu.Lock()
-	defer re.reservationsMu.Unlock()
-	re.reservations[rid] = res
-
-	// Make change if necessary
-	if total > amount {
-		res.Change = total - amount
-	}
-	return res, nil
 }
 
 // ReserveUTXO reserves a specific utxo for spending. The resulting
 // reservation expires at exp.
 func (re *reserver) ReserveUTXO(ctx context.Context, out bc.Hash, clientToken *string, exp time.Time) (*reservation, error) {
-	if clientToken == nil {
-		return re.reserveUTXO(ctx, out, exp, nil)
-	}
-
-	untypedRes, err := re.idempotency.Once(*clientToken, func() (interface{}, error) {
-		return re.reserveUTXO(ctx, out, exp, clientToken)
+	return re.idempotent(ctx, clientToken, func() (*reservation, error) {
+		u, err := findSpecificUTXO(ctx, re.db, out)
+		if err != nil {
+			return nil, err
+		}
+		return re.reserve(ctx, u.source(), &out, orderSmallest, clientToken, exp, func(available []*utxo, t totals) ([]*utxo, uint64, error) {
+			if t.nReserved > 0 {
+				return nil, 0, ErrReserved
+			}
+			if len(available) == 0 {
+				return nil, 0, pg.ErrUserInputNotFound // spent since it was found
+			}
+			return available, 0, nil
+		})
 	})
-	return untypedRes.(*reservation), err
 }
 
-func (re *reserver) reserveUTXO(ctx context.Context, out bc.Hash, exp time.Time, clientToken *string) (*reservation, error) {
-	u, err := findSpecificUTXO(ctx, re.db, out)
-	if err != nil {
-		return nil, err
-	}
-	if !re.checkUTXO(u) {
-		return nil, pg.ErrUserInputNotFound
-	}
-
-	rid := atomic.AddUint64(&re.nextReservationID, 1)
-	err = re.source(u.source()).reserveUTXO(rid, u)
-	if err != nil {
-		return nil, err
+// ReserveSmall reserves up to max of the smallest available UTXOs
+// in src whose amounts are at most maxAmount (or any amount, if
+// maxAmount is 0), for consolidating them into one. It returns a
+// nil reservation if fewer than min such UTXOs are available.
+// The resulting reservation expires at exp.
+func (re *reserver) ReserveSmall(ctx context.Context, src source, maxAmount uint64, min, max int, exp time.Time) (*reservation, error) {
+	res, err := re.reserve(ctx, src, nil, orderSmallest, nil, exp, func(available []*utxo, _ totals) ([]*utxo, uint64, error) {
+		small := selectSmall(available, maxAmount, min, max)
+		if small == nil {
+			return nil, 0, errTooFewUTXOs
+		}
+		return small, 0, nil
+	})
+	if err == errTooFewUTXOs {
+		return nil, nil
 	}
+	return res, err
+}
 
-	res := &reservation{
-		ID:          rid,
-		Source:      u.source(),
-		UTXOs:       []*utxo{u},
-		Expiry:      exp,
-		ClientToken: clientToken,
+// ReserveOldKeys reserves up to max of the smallest available UTXOs
+// in src controlled by versions of the account's keys older than
+// version. It returns a nil reservation if there are none. The
+// resulting reservation expires at exp.
+func (re *reserver) ReserveOldKeys(ctx context.Context, src source, version, max int, exp time.Time) (*reservation, error) {
+	order := fmt.Sprintf("u.key_version < %d DESC, %s", version, orderSmallest)
+	res, err := re.reserve(ctx, src, nil, order, nil, exp, func(available []*utxo, _ totals) ([]*utxo, uint64, error) {
+		var old []*utxo
+		for _, u := range available {
+			if u.KeyVersion < version {
+				old = append(old, u)
+			}
+		}
+		old = selectSmall(old, 0, 1, max)
+		if old == nil {
+			return nil, 0, errTooFewUTXOs
+		}
+		return old, 0, nil
+	})
+	if err == errTooFewUTXOs {
+		return nil, nil
 	}
-	re.reservationsMu.Lock()
-	re.reservations[rid] = res
-	re.reservationsMu.Unlock()
-	return res, nil
+	return res, err
 }
 
 // Cancel makes a best-effort attempt at canceling the reservation with
 // the provided ID.
 func (re *reserver) Cancel(ctx context.Context, rid uint64) error {
-	re.reservationsMu.Lock()
-	res, ok := re.reservations[rid]
-	delete(re.reservations, rid)
-	re.reservationsMu.Unlock()
-	if !ok {
-		return fmt.Errorf("couldn't find reservation %d", rid)
+	// Deleting the reservation clears account_utxos.reservation_id
+	// for its UTXOs, through the foreign key.
+	const q = `DELETE FROM reservations WHERE reservation_id = $1`
+	res, err := re.db.Exec(ctx, q, rid)
+	if err != nil {
+		return errors.Wrap(err, "deleting reservation")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "deleting reservation")
 	}
-	re.source(res.Source).cancel(res)
-	if res.ClientToken != nil {
-		re.idempotency.Forget(*res.ClientToken)
+	if n == 0 {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "reservation %d", rid)
 	}
 	return nil
 }
@@ -201,219 +229,336 @@ func (re *reserver) Cancel(ctx context.Context, rid uint64) error {
 // ExpireReservations cleans up all reservations that have expired,
 // making their UTXOs available for reservation again.
 func (re *reserver) ExpireReservations(ctx context.Context) error {
-	// Remove records of any reservations that have expired.
-	now := time.Now()
-	var canceled []*reservation
-	re.reservationsMu.Lock()
-	for rid, res := range re.reservations {
-		if res.Expiry.Before(now) {
-			canceled = append(canceled, res)
-			delete(re.reservations, rid)
-		}
+	// Expired reservations already don't hold their UTXOs;
+	// deleting them frees their client tokens and keeps
+	// the table small.
+	const q = `DELETE FROM reservations WHERE expiry < now()`
+	_, err := re.db.Exec(ctx, q)
+	return errors.Wrap(err, "deleting expired reservations")
+}
+
+// idempotent calls reserve, unless clientToken is set and there's
+// already an unexpired reservation made with it, in which case it
+// returns that reservation instead.
+func (re *reserver) idempotent(ctx context.Context, clientToken *string, reserve func() (*reservation, error)) (*reservation, error) {
+	if clientToken == nil {
+		return reserve()
 	}
-	re.reservationsMu.Unlock()
 
-	// If we removed any expired reservations, update the corresponding
-	// source reservers.
-	for _, res := range canceled {
-		re.source(res.Source).cancel(res)
-		if res.ClientToken != nil {
-			re.idempotency.Forget(*res.ClientToken)
+	res, err := findByClientToken(ctx, re.db, *clientToken)
+	if err != nil || res != nil {
+		return res, err
+	}
+	res, err = reserve()
+	if pg.IsUniqueViolation(errors.Root(err)) {
+		// Another request with the same client token, maybe in
+		// another process, made its reservation first.
+		res, err = findByClientToken(ctx, re.db, *clientToken)
+		if err == nil && res == nil {
+			// It's already been canceled or expired.
+			return reserve()
 		}
 	}
-
-	// TODO(jackson): Cleanup any source reservers that don't have
-	// anything reserved. It'll be a little tricky because of our
-	// locking scheme.
-	return nil
+	return res, err
 }
 
-func (re *reserver) checkUTXO(u *utxo) bool {
-	_, s := re.c.State()
-	return s.Tree.Contains(u.OutputID.Bytes())
-}
+// reserve reserves the UTXOs that pick chooses from those in src,
+// or from only the UTXO with output ID only, if it's not nil.
+// Pick chooses from the first maxCandidateUTXOs available UTXOs
+// listed in order, an ORDER BY clause on account_utxos u.
+func (re *reserver) reserve(ctx context.Context, src source, only *bc.Hash, order string, clientToken *string, exp time.Time, pick picker) (*reservation, error) {
+	dbtx, err := re.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "begin transaction")
+	}
+	defer dbtx.Rollback(ctx) // no-op after a successful commit
 
-func (re *reserver) source(src source) *sourceReserver {
-	re.sourcesMu.Lock()
-	defer re.sourcesMu.Unlock()
+	_, err = dbtx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, src.lockKey())
+	if err != nil {
+		return nil, errors.Wrap(err, "locking source")
+	}
 
-	sr, ok := re.sources[src]
-	if ok {
-		return sr
+	t, err := sourceTotals(ctx, dbtx, src, only)
+	if err != nil {
+		return nil, err
+	}
+	unspent, err := re.unspentChecker(ctx, dbtx)
+	if err != nil {
+		return nil, err
+	}
+	available, err := findAvailableUTXOs(ctx, dbtx, src, only, order, unspent)
+	if err != nil {
+		return nil, err
+	}
+	utxos, change, err := pick(available, t)
+	if err != nil {
+		return nil, err
 	}
 
-	sr = &sourceReserver{
-		db:      re.db,
-		src:     src,
-		validFn: re.checkUTXO,
-		heightFn: func() uint64 {
-			return re.pinStore.Height(PinName)
-		},
-		cached:   make(map[bc.Hash]*utxo),
-		reserved: make(map[bc.Hash]uint64),
+	if clientToken != nil {
+		// An expired reservation that hasn't been cleaned
+		// up yet may still hold the client token.
+		const delQ = `DELETE FROM reservations WHERE client_token = $1 AND expiry <= now()`
+		_, err = dbtx.Exec(ctx, delQ, *clientToken)
+		if err != nil {
+			return nil, errors.Wrap(err, "deleting expired reservation")
+		}
 	}
-	re.sources[src] = sr
-	return sr
-}
 
-type sourceReserver struct {
-	db       pg.DB
-	src      source
-	validFn  func(u *utxo) bool
-	heightFn func() uint64
+	res := &reservation{
+		Source:      src,
+		UTXOs:       utxos,
+		Change:      change,
+		Expiry:      exp,
+		ClientToken: clientToken,
+	}
+	const insertQ = `
+		INSERT INTO reservations (account_id, asset_id, change, client_token, expiry)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING reservation_id
+	`
+	err = dbtx.QueryRow(ctx, insertQ, src.AccountID, src.AssetID, change, clientToken, exp).Scan(&res.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting reservation")
+	}
 
-	mu         sync.Mutex
-	cached     map[bc.Hash]*utxo
-	reserved   map[bc.Hash]uint64
-	lastHeight uint64
+	outputIDs := make(pq.ByteaArray, 0, len(utxos))
+	for _, u := range utxos {
+		outputIDs = append(outputIDs, u.OutputID.Bytes())
+	}
+	const updateQ = `
+		UPDATE account_utxos SET reservation_id = $1
+		WHERE output_id IN (SELECT unnest($2::bytea[]))
+	`
+	_, err = dbtx.Exec(ctx, updateQ, res.ID, outputIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "reserving utxos")
+	}
+
+	err = dbtx.Commit(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "commit transaction")
+	}
+	return res, nil
 }
 
-func (sr *sourceReserver) reserve(ctx context.Context, rid uint64, amount uint64) ([]*utxo, uint64, error) {
-	reservedUTXOs, reservedAmount, err := sr.reserveFromCache(rid, amount)
-	if err == nil {
-		return reservedUTXOs, reservedAmount, nil
+// sourceTotals returns the totals of the UTXOs in src, or of
+// only the one with output ID only, if it's not nil.
+func sourceTotals(ctx context.Context, db pg.DB, src source, only *bc.Hash) (t totals, err error) {
+	const q = `
+		SELECT LEAST(COALESCE(SUM(u.amount), 0), 9223372036854775807)::bigint,
+			LEAST(COALESCE(SUM(u.amount) FILTER (WHERE r.reservation_id IS NOT NULL), 0), 9223372036854775807)::bigint,
+			COUNT(*), COUNT(r.reservation_id)
+		FROM account_utxos u
+		LEFT JOIN reservations r ON r.reservation_id = u.reservation_id AND r.expiry > now()
+		WHERE u.account_id = $1 AND u.asset_id = $2 AND ($3::bytea IS NULL OR u.output_id = $3)
+	`
+	var onlyID []byte
+	if only != nil {
+		onlyID = only.Bytes()
 	}
+	err = db.QueryRow(ctx, q, src.AccountID, src.AssetID, onlyID).Scan(&t.amount, &t.reserved, &t.n, &t.nReserved)
+	return t, errors.Wrap(err, "summing utxos")
+}
 
-	// Find the set of UTXOs that match this source.
-	err = sr.refillCache(ctx)
+// findAvailableUTXOs returns the first maxCandidateUTXOs UTXOs in
+// src (or only the one with output ID only, if it's not nil),
+// listed in order, that aren't held by unexpired reservations
+// and that unspent reports are still unspent.
+func findAvailableUTXOs(ctx context.Context, db pg.DB, src source, only *bc.Hash, order string, unspent func(*utxo) bool) ([]*utxo, error) {
+	q := `
+		SELECT u.output_id, u.amount, u.control_program_index, u.control_program,
+			u.key_version, u.confirmed_in
+		FROM account_utxos u
+		LEFT JOIN reservations r ON r.reservation_id = u.reservation_id AND r.expiry > now()
+		WHERE u.account_id = $1 AND u.asset_id = $2 AND ($3::bytea IS NULL OR u.output_id = $3)
+			AND r.reservation_id IS NULL
+		ORDER BY ` + order + `
+		LIMIT $4
+	`
+	var onlyID []byte
+	if only != nil {
+		onlyID = only.Bytes()
+	}
+	var available []*utxo
+	err := pg.ForQueryRows(ctx, db, q, src.AccountID, src.AssetID, onlyID, maxCandidateUTXOs,
+		func(oid bc.Hash, amount uint64, cpIndex uint64, controlProg []byte, keyVersion int, confirmedIn uint64) {
+			u := &utxo{
+				OutputID: oid,
+				AssetAmount: bc.AssetAmount{
+					Amount:  amount,
+					AssetID: src.AssetID,
+				},
+				ControlProgram:      controlProg,
+				AccountID:           src.AccountID,
+				ControlProgramIndex: cpIndex,
+				KeyVersion:          keyVersion,
+				ConfirmedIn:         confirmedIn,
+			}
+			if unspent(u) {
+				available = append(available, u)
+			}
+		})
 	if err != nil {
-		return nil, 0, err
+		return nil, errors.Wrap(err)
 	}
-
-	return sr.reserveFromCache(rid, amount)
+	return available, nil
 }
 
-func (sr *sourceReserver) reserveFromCache(rid uint64, amount uint64) ([]*utxo, uint64, error) {
-	var (
-		reserved, unavailable uint64
-		reservedUTXOs         []*utxo
-	)
-	sr.mu.Lock()
-	defer sr.mu.Unlock()
-
-	for o, u := range sr.cached {
-		// If the UTXO is already reserved, skip it.
-		if _, ok := sr.reserved[u.OutputID]; ok {
-			unavailable += u.Amount
-			continue
-		}
-		// Cached utxos aren't guaranteed to still be valid; they may
-		// have been spent. Verify that that the outputs are still in
-		// the state tree.
-		if !sr.validFn(u) {
-			delete(sr.cached, o)
-			continue
-		}
+// unspentChecker returns a function that reports whether a UTXO
+// from the account_utxos table is still unspent. The table can lag
+// behind the blockchain, so it may still hold outputs that have
+// been spent. The leader checks UTXOs against its state tree.
+// Other processes, including a former leader whose state has gone
+// stale, check them against the spends in the blocks the account
+// indexer hasn't processed yet.
+// (Spends in blocks the leader hasn't yet stored are missed,
+// so a template built anywhere may still be rejected.)
+func (re *reserver) unspentChecker(ctx context.Context, db pg.DB) (func(*utxo) bool, error) {
+	_, s := re.c.State()
+	if leader.IsLeading() && s != nil {
+		return func(u *utxo) bool {
+			return s.Tree.Contains(u.OutputID.Bytes())
+		}, nil
+	}
 
-		reserved += u.Amount
-		reservedUTXOs = append(reservedUTXOs, u)
-		if reserved >= amount {
-			break
+	const q = `
+		SELECT data FROM blocks
+		WHERE height > (SELECT COALESCE(MAX(height), 0) FROM block_processors WHERE name = $1)
+	`
+	spent := make(map[bc.Hash]bool)
+	err := pg.ForQueryRows(ctx, db, q, PinName, func(b bc.Block) {
+		for _, tx := range b.Transactions {
+			for _, in := range tx.Inputs {
+				if !in.IsIssuance() {
+					spent[in.SpentOutputID()] = true
+				}
+			}
 		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "finding unindexed spends")
 	}
-	if reserved+unavailable < amount {
+	return func(u *utxo) bool {
+		return !spent[u.OutputID]
+	}, nil
+}
+
+// selectAmount chooses UTXOs totaling at least amount from
+// available with strategy, and returns them with the change due.
+// The totals t are for all the source's UTXOs.
+func selectAmount(available []*utxo, t totals, amount uint64, strategy SelectionStrategy) ([]*utxo, uint64, error) {
+	if t.amount < amount {
 		// Even if everything was available, this account wouldn't have
 		// enough to satisfy the request.
 		return nil, 0, ErrInsufficient
 	}
-	if reserved < amount {
+	if t.amount-t.reserved < amount {
 		// The account has enough for the request, but some is tied up in
 		// other reservations.
 		return nil, 0, ErrReserved
 	}
-
-	// We've found enough to satisfy the request.
-	for _, u := range reservedUTXOs {
-		sr.reserved[u.OutputID] = rid
+	var availableAmount uint64
+	for _, u := range available {
+		availableAmount += u.Amount
 	}
-
-	return reservedUTXOs, reserved, nil
-}
-
-func (sr *sourceReserver) reserveUTXO(rid uint64, utxo *utxo) error {
-	sr.mu.Lock()
-	defer sr.mu.Unlock()
-
-	_, isReserved := sr.reserved[utxo.OutputID]
-	if isReserved {
-		return ErrReserved
+	if availableAmount < amount {
+		// Some of the UTXOs have been spent, or there
+		// are too many to consider.
+		return nil, 0, errors.WithDetailf(ErrInsufficient, "at most %d outputs are considered", maxCandidateUTXOs)
 	}
 
-	sr.reserved[utxo.OutputID] = rid
-	return nil
-}
-
-func (sr *sourceReserver) cancel(res *reservation) {
-	sr.mu.Lock()
-	defer sr.mu.Unlock()
-	for _, utxo := range res.UTXOs {
-		delete(sr.reserved, utxo.OutputID)
+	// There's enough to satisfy the request.
+	// Let the strategy choose which UTXOs to use.
+	var total uint64
+	utxos := strategy.selectUTXOs(available, amount)
+	for _, u := range utxos {
+		total += u.Amount
 	}
+	return utxos, total - amount, nil
 }
 
-func (sr *sourceReserver) refillCache(ctx context.Context) error {
-	sr.mu.Lock()
-	lastHeight := sr.lastHeight
-	sr.mu.Unlock()
-
-	curHeight := sr.heightFn()
-	if lastHeight >= curHeight {
-		return nil
+// selectSmall chooses up to max of the smallest UTXOs in available
+// whose amounts are at most maxAmount (or any amount, if maxAmount
+// is 0). It returns nil if there are fewer than min of them.
+func selectSmall(available []*utxo, maxAmount uint64, min, max int) []*utxo {
+	var small []*utxo
+	for _, u := range available {
+		if maxAmount > 0 && u.Amount > maxAmount {
+			continue
+		}
+		small = append(small, u)
 	}
-
-	utxos, err := findMatchingUTXOs(ctx, sr.db, sr.src, lastHeight)
-	if err != nil {
-		return errors.Wrap(err)
+	sort.Sort(byAmount(small))
+	if len(small) > max {
+		small = small[:max]
 	}
 
-	sr.mu.Lock()
-	if curHeight > sr.lastHeight {
-		sr.lastHeight = curHeight
+	// The UTXOs are spent to a single output,
+	// so their total must fit in its amount.
+	var total uint64
+	for i, u := range small {
+		if total+u.Amount > math.MaxInt64 {
+			small = small[:i]
+			break
+		}
+		total += u.Amount
 	}
-	for _, u := range utxos {
-		sr.cached[u.OutputID] = u
+	if len(small) < min {
+		return nil
 	}
-	sr.mu.Unlock()
-
-	return nil
+	return small
 }
 
-func findMatchingUTXOs(ctx context.Context, db pg.DB, src source, height uint64) ([]*utxo, error) {
+// findByClientToken returns the unexpired reservation made with
+// clientToken, or nil if there is none.
+func findByClientToken(ctx context.Context, db pg.DB, clientToken string) (*reservation, error) {
 	const q = `
-		SELECT output_id, amount, control_program_index, control_program
-		FROM account_utxos
-		WHERE account_id = $1 AND asset_id = $2 AND confirmed_in > $3
+		SELECT reservation_id, account_id, asset_id, change, expiry
+		FROM reservations WHERE client_token = $1 AND expiry > now()
 	`
-	var utxos []*utxo
-	err := pg.ForQueryRows(ctx, db, q, src.AccountID, src.AssetID, height,
-		func(oid bc.Hash, amount uint64, cpIndex uint64, controlProg []byte) {
-			utxos = append(utxos, &utxo{
-				OutputID: oid,
-				AssetAmount: bc.AssetAmount{
-					Amount:  amount,
-					AssetID: src.AssetID,
-				},
-				ControlProgram:      controlProg,
-				AccountID:           src.AccountID,
-				ControlProgramIndex: cpIndex,
-			})
+	res := &reservation{ClientToken: &clientToken}
+	err := db.QueryRow(ctx, q, clientToken).Scan(&res.ID, &res.Source.AccountID, &res.Source.AssetID, &res.Change, &res.Expiry)
+	if err == stdsql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "finding reservation")
+	}
+
+	const utxosQ = `
+		SELECT output_id, amount, control_program_index, control_program, key_version, confirmed_in
+		FROM account_utxos WHERE reservation_id = $1
+	`
+	err = pg.ForQueryRows(ctx, db, utxosQ, res.ID, func(oid bc.Hash, amount uint64, cpIndex uint64, controlProg []byte, keyVersion int, confirmedIn uint64) {
+		res.UTXOs = append(res.UTXOs, &utxo{
+			OutputID: oid,
+			AssetAmount: bc.AssetAmount{
+				Amount:  amount,
+				AssetID: res.Source.AssetID,
+			},
+			ControlProgram:      controlProg,
+			AccountID:           res.Source.AccountID,
+			ControlProgramIndex: cpIndex,
+			KeyVersion:          keyVersion,
+			ConfirmedIn:         confirmedIn,
 		})
+	})
 	if err != nil {
-		return nil, errors.Wrap(err)
+		return nil, errors.Wrap(err, "finding reserved utxos")
 	}
-	return utxos, nil
+	return res, nil
 }
 
 func findSpecificUTXO(ctx context.Context, db pg.DB, out bc.Hash) (*utxo, error) {
 	const q = `
-		SELECT account_id, asset_id, amount, control_program_index, control_program
+		SELECT account_id, asset_id, amount, control_program_index, control_program, key_version, confirmed_in
 		FROM account_utxos
 		WHERE output_id = $1
 	`
 	u := new(utxo)
 	// TODO(oleg): maybe we need to scan txid:index too from here...
-	err := db.QueryRow(ctx, q, out).Scan(&u.AccountID, &u.AssetID, &u.Amount, &u.ControlProgramIndex, &u.ControlProgram)
-	if err == sql.ErrNoRows {
+	err := db.QueryRow(ctx, q, out).Scan(&u.AccountID, &u.AssetID, &u.Amount, &u.ControlProgramIndex, &u.ControlProgram, &u.KeyVersion, &u.ConfirmedIn)
+	if err == stdsql.ErrNoRows {
 		return nil, pg.ErrUserInputNotFound
 	} else if err != nil {
 		return nil, errors.Wrap(err)
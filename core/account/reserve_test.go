@@ -2,6 +2,7 @@ package account
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
@@ -36,14 +37,14 @@ func TestCancelReservation(t *testing.T) {
 	}
 	c := prottest.NewChainWithStorage(t, memstore.New(), outid)
 
-	utxoDB := newReserver(db, c, nil)
-	res, err := utxoDB.ReserveUTXO(ctx, outid, nil, time.Now())
+	utxoDB := newReserver(db, c)
+	res, err := utxoDB.ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Minute))
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Verify that the UTXO is reserved.
-	_, err = utxoDB.ReserveUTXO(ctx, outid, nil, time.Now())
+	_, err = utxoDB.ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Minute))
 	if err != ErrReserved {
 		t.Fatalf("got=%s want=%s", err, ErrReserved)
 	}
@@ -55,8 +56,71 @@ func TestCancelReservation(t *testing.T) {
 	}
 
 	// Reserving again should succeed.
-	_, err = utxoDB.ReserveUTXO(ctx, outid, nil, time.Now())
+	_, err = utxoDB.ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Minute))
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestReserveClientToken(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	_, err := db.Exec(ctx, sampleAccountUTXOs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outid bc.Hash
+	err = outid.UnmarshalText([]byte("9886ae2dc24b6d868c68768038c43801e905a62f1a9b826ca0dc357f00c30117"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := prottest.NewChainWithStorage(t, memstore.New(), outid)
+
+	// Reserving with the same client token gives the same
+	// reservation, even from another reserver.
+	token := "a-client-token"
+	res1, err := newReserver(db, c).ReserveUTXO(ctx, outid, &token, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res2, err := newReserver(db, c).ReserveUTXO(ctx, outid, &token, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res1.ID != res2.ID || len(res2.UTXOs) != 1 || res2.UTXOs[0].OutputID != outid {
+		t.Errorf("got reservation %d with %d utxos, want %d with %x", res2.ID, len(res2.UTXOs), res1.ID, outid.Bytes())
+	}
+
+	// Once the reservation expires, the token can be used again.
+	_, err = db.Exec(ctx, `UPDATE reservations SET expiry = now() - interval '1 minute'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res3, err := newReserver(db, c).ReserveUTXO(ctx, outid, &token, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res3.ID == res1.ID {
+		t.Errorf("reservation %d was reused after expiring", res3.ID)
+	}
+}
+
+func TestSelectSmall(t *testing.T) {
+	var available []*utxo
+	for i, amt := range []uint64{7, 1, 100, 3, 2} {
+		available = append(available, &utxo{OutputID: bc.Hash{byte(i)}, AssetAmount: bc.AssetAmount{Amount: amt}})
+	}
+
+	var amounts []uint64
+	for _, u := range selectSmall(available, 10, 2, 3) {
+		amounts = append(amounts, u.Amount)
+	}
+	if want := []uint64{1, 2, 3}; !reflect.DeepEqual(amounts, want) {
+		t.Errorf("selected amounts %v want %v", amounts, want)
+	}
+
+	// Only four of the utxos are small enough.
+	if got := selectSmall(available, 10, 5, 10); got != nil {
+		t.Errorf("selected %d utxos, want none", len(got))
+	}
+}
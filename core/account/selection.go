@@ -0,0 +1,142 @@
+package account
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"chain/errors"
+)
+
+// ErrBadSelectionStrategy is returned for a coin selection
+// strategy that isn't one of the known ones.
+var ErrBadSelectionStrategy = errors.New("invalid coin selection strategy")
+
+// A SelectionStrategy says which of an account's UTXOs to spend
+// when reserving funds for a spend_account action.
+type SelectionStrategy string
+
+const (
+	// LargestFirst spends the largest UTXOs first.
+	LargestFirst SelectionStrategy = "largest_first"
+
+	// SmallestFirst spends the smallest UTXOs first,
+	// consolidating dust at the cost of more inputs.
+	SmallestFirst SelectionStrategy = "smallest_first"
+
+	// FewestInputs spends the smallest single UTXO that
+	// covers the amount, if there is one, and otherwise
+	// the largest UTXOs first.
+	FewestInputs SelectionStrategy = "fewest_inputs"
+
+	// ExactMatch spends a single UTXO of exactly the amount,
+	// so there's no change, if there is one. Otherwise it
+	// behaves like FewestInputs.
+	ExactMatch SelectionStrategy = "exact_match"
+
+	// OldestFirst spends the UTXOs confirmed
+	// earliest in the blockchain first.
+	OldestFirst SelectionStrategy = "oldest_first"
+)
+
+// DefaultSelectionStrategy is used for spends that don't
+// give a strategy, from accounts that don't have a default.
+const DefaultSelectionStrategy = FewestInputs
+
+// validate returns ErrBadSelectionStrategy if s is not empty
+// and not one of the known strategies.
+func (s SelectionStrategy) validate() error {
+	switch s {
+	case "", LargestFirst, SmallestFirst, FewestInputs, ExactMatch, OldestFirst:
+		return nil
+	}
+	return errors.WithDetailf(ErrBadSelectionStrategy, "unknown strategy %q", s)
+}
+
+// sqlOrder returns an ORDER BY clause on account_utxos u that
+// lists first the UTXOs s prefers when choosing for amount, so
+// reserve can consider only the first few.
+func (s SelectionStrategy) sqlOrder(amount uint64) string {
+	switch s {
+	case SmallestFirst:
+		return orderSmallest
+	case OldestFirst:
+		return orderOldest
+	case ExactMatch:
+		return fmt.Sprintf("u.amount = %d DESC, %s", amount, FewestInputs.sqlOrder(amount))
+	case FewestInputs:
+		// The smallest UTXO covering amount, then the largest.
+		return fmt.Sprintf("u.amount >= %[1]d DESC, CASE WHEN u.amount >= %[1]d THEN u.amount END, %s", amount, orderLargest)
+	default: // LargestFirst
+		return orderLargest
+	}
+}
+
+// selectUTXOs chooses UTXOs from available, according to s,
+// totaling at least amount. The caller must make sure the
+// available UTXOs total at least amount.
+func (s SelectionStrategy) selectUTXOs(available []*utxo, amount uint64) []*utxo {
+	switch s {
+	case SmallestFirst:
+		sort.Sort(byAmount(available))
+	case OldestFirst:
+		sort.Sort(byConfirmedIn(available))
+	case ExactMatch:
+		for _, u := range available {
+			if u.Amount == amount {
+				return []*utxo{u}
+			}
+		}
+		return FewestInputs.selectUTXOs(available, amount)
+	case FewestInputs:
+		var best *utxo
+		for _, u := range available {
+			if u.Amount >= amount && (best == nil || u.Amount < best.Amount) {
+				best = u
+			}
+		}
+		if best != nil {
+			return []*utxo{best}
+		}
+		sort.Sort(sort.Reverse(byAmount(available)))
+	default: // LargestFirst
+		sort.Sort(sort.Reverse(byAmount(available)))
+	}
+
+	var (
+		selected []*utxo
+		total    uint64
+	)
+	for _, u := range available {
+		if total >= amount {
+			break
+		}
+		selected = append(selected, u)
+		total += u.Amount
+	}
+	return selected
+}
+
+// byAmount sorts UTXOs by amount, then by output ID,
+// so selection doesn't depend on map iteration order.
+type byAmount []*utxo
+
+func (a byAmount) Len() int      { return len(a) }
+func (a byAmount) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byAmount) Less(i, j int) bool {
+	if a[i].Amount != a[j].Amount {
+		return a[i].Amount < a[j].Amount
+	}
+	return bytes.Compare(a[i].OutputID[:], a[j].OutputID[:]) < 0
+}
+
+type byConfirmedIn []*utxo
+
+func (a byConfirmedIn) Len() int      { return len(a) }
+func (a byConfirmedIn) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byConfirmedIn) Less(i, j int) bool {
+	if a[i].ConfirmedIn != a[j].ConfirmedIn {
+		return a[i].ConfirmedIn < a[j].ConfirmedIn
+	}
+	return bytes.Compare(a[i].OutputID[:], a[j].OutputID[:]) < 0
+}
@@ -0,0 +1,61 @@
+package account
+
+import (
+	"reflect"
+	"testing"
+
+	"chain/protocol/bc"
+)
+
+func TestSelectUTXOs(t *testing.T) {
+	// Each utxo's output ID is its index in utxos,
+	// and it was confirmed in reverse order.
+	amounts := []uint64{5, 20, 1, 10, 2}
+	var utxos []*utxo
+	for i, amt := range amounts {
+		utxos = append(utxos, &utxo{
+			OutputID:    bc.Hash{byte(i)},
+			AssetAmount: bc.AssetAmount{Amount: amt},
+			ConfirmedIn: uint64(len(amounts) - i),
+		})
+	}
+
+	cases := []struct {
+		strategy SelectionStrategy
+		amount   uint64
+		want     []uint64 // amounts of the selected utxos
+	}{
+		{LargestFirst, 25, []uint64{20, 10}},
+		{LargestFirst, 3, []uint64{20}},
+		{SmallestFirst, 3, []uint64{1, 2}},
+		{SmallestFirst, 8, []uint64{1, 2, 5}},
+		{FewestInputs, 3, []uint64{5}},
+		{FewestInputs, 11, []uint64{20}},
+		{FewestInputs, 25, []uint64{20, 10}},
+		{ExactMatch, 10, []uint64{10}},
+		{ExactMatch, 9, []uint64{10}},
+		{ExactMatch, 38, []uint64{20, 10, 5, 2, 1}},
+		{OldestFirst, 3, []uint64{2, 10}},
+	}
+	for _, c := range cases {
+		avail := append([]*utxo(nil), utxos...)
+		var got []uint64
+		for _, u := range c.strategy.selectUTXOs(avail, c.amount) {
+			got = append(got, u.Amount)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s(%d) selected %v want %v", c.strategy, c.amount, got, c.want)
+		}
+	}
+}
+
+func TestSelectionStrategyValidate(t *testing.T) {
+	for _, s := range []SelectionStrategy{"", LargestFirst, SmallestFirst, FewestInputs, ExactMatch, OldestFirst} {
+		if err := s.validate(); err != nil {
+			t.Errorf("validate(%q) = %v want nil", s, err)
+		}
+	}
+	if err := SelectionStrategy("random").validate(); err == nil {
+		t.Error("validate(random) = nil want error")
+	}
+}
@@ -3,9 +3,13 @@ package core
 import (
 	"context"
 	"sync"
+	"time"
 
 	"chain/core/account"
+	"chain/core/query"
+	"chain/core/txbuilder"
 	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
 	"chain/net/http/reqid"
 )
 
@@ -16,6 +20,10 @@ func (a *API) createAccount(ctx context.Context, ins []struct {
 	Alias     string
 	Tags      map[string]interface{}
 
+	// CoinSelection is the account's default strategy for
+	// choosing which outputs to spend. See account.SelectionStrategy.
+	CoinSelection account.SelectionStrategy `json:"coin_selection"`
+
 	// ClientToken is the application's unique token for the account. Every account
 	// should have a unique client token. The client token is used to ensure
 	// idempotency of create account requests. Duplicate create account requests
@@ -32,7 +40,7 @@ func (a *API) createAccount(ctx context.Context, ins []struct {
 			defer wg.Done()
 			defer batchRecover(subctx, &responses[i])
 
-			acc, err := a.Accounts.Create(subctx, ins[i].RootXPubs, ins[i].Quorum, ins[i].Alias, ins[i].Tags, ins[i].ClientToken)
+			acc, err := a.Accounts.Create(subctx, ins[i].RootXPubs, ins[i].Quorum, ins[i].Alias, ins[i].Tags, ins[i].CoinSelection, ins[i].ClientToken)
 			if err != nil {
 				responses[i] = err
 				return
@@ -49,3 +57,81 @@ func (a *API) createAccount(ctx context.Context, ins []struct {
 	wg.Wait()
 	return responses
 }
+
+// POST /update-account-keys
+//
+// updateAccountKeys replaces an account's root xpubs and quorum.
+// New control programs for the account use the new keys; outputs
+// controlled by the old keys are still spent with them. If sweep
+// is true, it also builds transactions moving those outputs to the
+// new keys, for the application to sign with the old keys and
+// submit within ttl.
+func (a *API) updateAccountKeys(ctx context.Context, in struct {
+	AccountID    string         `json:"account_id"`
+	AccountAlias string         `json:"account_alias"`
+	RootXPubs    []chainkd.XPub `json:"root_xpubs"`
+	Quorum       int
+	Sweep        bool
+	TTL          chainjson.Duration `json:"ttl"`
+}) (interface{}, error) {
+	if in.AccountAlias != "" {
+		acc, err := a.Accounts.FindByAlias(ctx, in.AccountAlias)
+		if err != nil {
+			return nil, err
+		}
+		in.AccountID = acc.ID
+	}
+
+	acc, err := a.Accounts.UpdateKeys(ctx, in.AccountID, in.RootXPubs, in.Quorum)
+	if err != nil {
+		return nil, err
+	}
+	aa, err := account.Annotated(acc)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Account           *query.AnnotatedAccount `json:"account"`
+		SweepTransactions []*txbuilder.Template   `json:"sweep_transactions"`
+	}{
+		Account:           aa,
+		SweepTransactions: []*txbuilder.Template{}, // not null
+	}
+	if !in.Sweep {
+		return resp, nil
+	}
+
+	ttl := in.TTL.Duration
+	if ttl == 0 {
+		ttl = defaultTxTTL
+	}
+	resp.SweepTransactions, err = a.Accounts.SweepOldKeys(ctx, in.AccountID, time.Now().Add(ttl))
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// POST /update-account-coin-selection
+//
+// updateAccountCoinSelection changes an account's default
+// strategy for choosing which outputs to spend.
+func (a *API) updateAccountCoinSelection(ctx context.Context, in struct {
+	AccountID     string                    `json:"account_id"`
+	AccountAlias  string                    `json:"account_alias"`
+	CoinSelection account.SelectionStrategy `json:"coin_selection"`
+}) (*query.AnnotatedAccount, error) {
+	if in.AccountAlias != "" {
+		acc, err := a.Accounts.FindByAlias(ctx, in.AccountAlias)
+		if err != nil {
+			return nil, err
+		}
+		in.AccountID = acc.ID
+	}
+
+	acc, err := a.Accounts.UpdateCoinSelection(ctx, in.AccountID, in.CoinSelection)
+	if err != nil {
+		return nil, err
+	}
+	return account.Annotated(acc)
+}
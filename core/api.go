@@ -14,6 +14,7 @@ import (
 	"chain/core/account"
 	"chain/core/asset"
 	"chain/core/config"
+	"chain/core/fetch"
 	"chain/core/leader"
 	"chain/core/pin"
 	"chain/core/query"
@@ -68,6 +69,9 @@ type API struct {
 
 	healthMu     sync.Mutex
 	healthErrors map[string]interface{}
+
+	manifestMu sync.Mutex
+	manifests  map[uint64]*fetch.SnapshotManifest // by height
 }
 
 type RequestLimit struct {
@@ -110,9 +114,14 @@ func Handler(a *API, register func(*http.ServeMux, *API)) http.Handler {
 	m.Handle("/", alwaysError(errNotFound))
 
 	m.Handle("/create-account", needConfig(a.createAccount))
+	m.Handle("/update-account-keys", needConfig(a.updateAccountKeys))
+	m.Handle("/update-account-coin-selection", needConfig(a.updateAccountCoinSelection))
 	m.Handle("/create-asset", needConfig(a.createAsset))
 	m.Handle("/build-transaction", needConfig(a.build))
 	m.Handle("/submit-transaction", needConfig(a.submit))
+	m.Handle("/validate-transaction", needConfig(a.validateTx))
+	m.Handle("/get-transaction-status", needConfig(a.getTxStatus))
+	m.Handle("/get-transaction-proof", needConfig(a.getTxProof))
 	m.Handle("/create-control-program", needConfig(a.createControlProgram)) // DEPRECATED
 	m.Handle("/create-account-receiver", needConfig(a.createAccountReceiver))
 	m.Handle("/create-transaction-feed", needConfig(a.createTxFeed))
@@ -126,15 +135,26 @@ func Handler(a *API, register func(*http.ServeMux, *API)) http.Handler {
 	m.Handle("/list-transactions", needConfig(a.listTransactions))
 	m.Handle("/list-balances", needConfig(a.listBalances))
 	m.Handle("/list-unspent-outputs", needConfig(a.listUnspentOutputs))
+	m.Handle("/list-block-evidence", needConfig(a.listBlockEvidence))
+	m.Handle("/list-sign-request-conflicts", needConfig(a.listSignRequestConflicts))
+	m.Handle("/list-snapshots", needConfig(a.listSnapshots))
+	m.Handle("/list-utxo-consolidations", needConfig(a.listUTXOConsolidations))
+	m.Handle("/list-reservations", needConfig(a.listReservations))
+	m.Handle("/cancel-reservation", needConfig(a.cancelReservation))
 	m.Handle("/reset", devOnly(needConfig(a.reset)))
+	m.Handle("/set-next-consensus-program", needConfig(a.setNextConsensusProgram))
 
-	m.Handle(networkRPCPrefix+"submit", needConfig(func(ctx context.Context, tx *bc.Tx) error {
-		return a.Submitter.Submit(ctx, tx)
-	}))
+	m.Handle(networkRPCPrefix+"submit", needConfig(a.submitRPC))
+	m.Handle(networkRPCPrefix+"get-tx-status", needConfig(a.getTxStatusRPC))
+	m.Handle(networkRPCPrefix+"get-transaction-proof", needConfig(a.getTxProof))
+	m.Handle(networkRPCPrefix+"get-output-proof", needConfig(a.getOutputProofRPC))
 	m.Handle(networkRPCPrefix+"get-blocks", needConfig(a.getBlocksRPC)) // DEPRECATED: use get-block instead
 	m.Handle(networkRPCPrefix+"get-block", needConfig(a.getBlockRPC))
+	m.Handle(networkRPCPrefix+"get-block-header", needConfig(a.getBlockHeaderRPC))
 	m.Handle(networkRPCPrefix+"get-snapshot-info", needConfig(a.getSnapshotInfoRPC))
 	m.Handle(networkRPCPrefix+"get-snapshot", http.HandlerFunc(a.getSnapshotRPC))
+	m.Handle(networkRPCPrefix+"get-snapshot-manifest", needConfig(a.getSnapshotManifestRPC))
+	m.Handle(networkRPCPrefix+"get-snapshot-chunk", http.HandlerFunc(a.getSnapshotChunkRPC))
 	m.Handle(networkRPCPrefix+"signer/sign-block", needConfig(a.leaderSignHandler(a.Signer)))
 	m.Handle(networkRPCPrefix+"block-height", needConfig(func(ctx context.Context) map[string]uint64 {
 		h := a.Chain.Height()
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"chain/core/accesstoken"
+	"chain/core/generator"
 	"chain/errors"
 )
 
@@ -33,26 +34,35 @@ type tokenResult struct {
 
 func (a *apiAuthn) handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-		err := a.auth(req)
+		caller, err := a.auth(req)
 		if err != nil {
 			WriteHTTPError(req.Context(), rw, err)
 			return
 		}
-		next.ServeHTTP(rw, req)
+		// Txs submitted in this request count toward
+		// the pending pool limit of the caller.
+		ctx := generator.NewSubmitterContext(req.Context(), caller)
+		next.ServeHTTP(rw, req.WithContext(ctx))
 	})
 }
 
-func (a *apiAuthn) auth(req *http.Request) error {
+// auth authenticates req and returns an identifier
+// for the caller: the type and ID of its access token.
+func (a *apiAuthn) auth(req *http.Request) (string, error) {
 	user, pw, ok := req.BasicAuth()
 	if !ok && a.alt(req) {
-		return nil
+		return "loopback", nil
 	}
 
 	typ := "client"
 	if strings.HasPrefix(req.URL.Path, networkRPCPrefix) {
 		typ = "network"
 	}
-	return a.cachedAuthCheck(req.Context(), typ, user, pw)
+	err := a.cachedAuthCheck(req.Context(), typ, user, pw)
+	if err != nil {
+		return "", err
+	}
+	return typ + ":" + user, nil
 }
 
 func (a *apiAuthn) authCheck(ctx context.Context, typ, user, pw string) (bool, error) {
@@ -0,0 +1,279 @@
+// Package blockarchive reads and writes block archives: files
+// holding a consecutive range of blocks from one blockchain, for
+// seeding Cores offline and keeping copies of the blockchain
+// independent of the database.
+//
+// An archive starts with a header: an 8-byte magic string, a
+// version, flags, the blockchain ID, and the height of the first
+// block. The blocks follow, gzipped if the compression flag is
+// set. Each block is preceded by its height, its length, and a
+// CRC-32C checksum. A trailer, which has height 0, ends the
+// archive with the number of blocks and a SHA3-256 digest of all
+// the block records, so a truncated archive is detected.
+package blockarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+
+	"chain/crypto/sha3pool"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+const (
+	magic   = "CHAINBLK"
+	version = 1
+
+	flagGzip = 1 << 0
+
+	// headerLen is the size of the magic string, version,
+	// flags, blockchain ID and first height.
+	headerLen = 8 + 4 + 4 + 32 + 8
+
+	// recordHeaderLen is the size of the height, length,
+	// and checksum preceding each block.
+	recordHeaderLen = 16
+
+	// maxBlockLen bounds the length a record can claim,
+	// so a corrupt archive can't make Next allocate
+	// arbitrarily much.
+	maxBlockLen = 1 << 30
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrBadArchive is returned when reading an archive that is
+// malformed, truncated, or fails a checksum.
+var ErrBadArchive = errors.New("invalid block archive")
+
+// A Writer writes blocks to an archive.
+type Writer struct {
+	w      io.Writer
+	gz     *gzip.Writer // nil if not compressing
+	digest sha3.ShakeHash
+	next   uint64 // height of the next block
+	n      uint64 // blocks written
+}
+
+// NewWriter writes the header of an archive of blocks from the
+// blockchain with the given ID, starting at height first, and
+// returns a Writer for the blocks. If compress is true, the
+// blocks are gzipped.
+func NewWriter(w io.Writer, blockchainID bc.Hash, first uint64, compress bool) (*Writer, error) {
+	var flags uint32
+	if compress {
+		flags |= flagGzip
+	}
+	header := make([]byte, headerLen)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[8:], version)
+	binary.BigEndian.PutUint32(header[12:], flags)
+	copy(header[16:], blockchainID[:])
+	binary.BigEndian.PutUint64(header[48:], first)
+	_, err := w.Write(header)
+	if err != nil {
+		return nil, errors.Wrap(err, "writing archive header")
+	}
+
+	aw := &Writer{w: w, digest: sha3pool.Get256(), next: first}
+	if compress {
+		aw.gz = gzip.NewWriter(w)
+		aw.w = aw.gz
+	}
+	return aw, nil
+}
+
+// WriteBlock appends b to the archive. Blocks must be
+// written in order, starting at the archive's first height.
+func (w *Writer) WriteBlock(b *bc.Block) error {
+	if b.Height != w.next {
+		return errors.Wrapf(ErrBadArchive, "writing block %d, want %d", b.Height, w.next)
+	}
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	if err != nil {
+		return errors.Wrapf(err, "encoding block %d", b.Height)
+	}
+	rec := record(b.Height, buf.Bytes())
+	w.digest.Write(rec)
+	_, err = w.w.Write(rec)
+	if err != nil {
+		return errors.Wrapf(err, "writing block %d", b.Height)
+	}
+	w.next++
+	w.n++
+	return nil
+}
+
+// record returns data preceded by its record header.
+func record(height uint64, data []byte) []byte {
+	rec := make([]byte, recordHeaderLen, recordHeaderLen+len(data))
+	binary.BigEndian.PutUint64(rec, height)
+	binary.BigEndian.PutUint32(rec[8:], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[12:], crc32.Checksum(data, crcTable))
+	return append(rec, data...)
+}
+
+// Close writes the archive's trailer and flushes any
+// compressed data. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	trailer := make([]byte, 8+32)
+	binary.BigEndian.PutUint64(trailer, w.n)
+	w.digest.Read(trailer[8:])
+	sha3pool.Put256(w.digest)
+
+	_, err := w.w.Write(record(0, trailer))
+	if err != nil {
+		return errors.Wrap(err, "writing archive trailer")
+	}
+	if w.gz != nil {
+		err = w.gz.Close()
+		if err != nil {
+			return errors.Wrap(err, "flushing compressed blocks")
+		}
+	}
+	return nil
+}
+
+// A Reader reads blocks from an archive.
+type Reader struct {
+	r            *bufio.Reader
+	blockchainID bc.Hash
+	first        uint64
+	compressed   bool
+	digest       sha3.ShakeHash
+	next         uint64 // height of the next block
+	n            uint64 // blocks read
+	done         bool
+}
+
+// NewReader reads the header of an archive from r
+// and returns a Reader for its blocks.
+func NewReader(r io.Reader) (*Reader, error) {
+	header := make([]byte, headerLen)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, errors.Sub(ErrBadArchive, errors.Wrap(err, "reading archive header"))
+	}
+	if string(header[:8]) != magic {
+		return nil, errors.WithDetail(ErrBadArchive, "not a block archive")
+	}
+	if v := binary.BigEndian.Uint32(header[8:]); v != version {
+		return nil, errors.WithDetailf(ErrBadArchive, "unknown archive version %d", v)
+	}
+	flags := binary.BigEndian.Uint32(header[12:])
+	if flags&^flagGzip != 0 {
+		return nil, errors.WithDetailf(ErrBadArchive, "unknown archive flags %#x", flags)
+	}
+
+	ar := &Reader{
+		first:      binary.BigEndian.Uint64(header[48:]),
+		compressed: flags&flagGzip != 0,
+		digest:     sha3pool.Get256(),
+	}
+	copy(ar.blockchainID[:], header[16:48])
+	ar.next = ar.first
+	if ar.compressed {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Sub(ErrBadArchive, errors.Wrap(err, "reading compressed blocks"))
+		}
+		r = gz
+	}
+	ar.r = bufio.NewReader(r)
+	return ar, nil
+}
+
+// BlockchainID returns the ID of the blockchain
+// the archive's blocks are from.
+func (r *Reader) BlockchainID() bc.Hash { return r.blockchainID }
+
+// First returns the height of the archive's first block.
+func (r *Reader) First() uint64 { return r.first }
+
+// Compressed reports whether the archive's blocks are gzipped.
+func (r *Reader) Compressed() bool { return r.compressed }
+
+// Next returns the next block in the archive. After the last
+// block, it checks the archive's trailer and returns io.EOF.
+func (r *Reader) Next() (*bc.Block, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	height, data, err := r.readRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	if height == 0 {
+		return nil, r.checkTrailer(data)
+	}
+	if height != r.next {
+		return nil, errors.WithDetailf(ErrBadArchive, "found block %d, want %d", height, r.next)
+	}
+	var b bc.Block
+	err = b.Scan(data)
+	if err != nil {
+		return nil, errors.Sub(ErrBadArchive, errors.Wrapf(err, "decoding block %d", height))
+	}
+	if b.Height != height {
+		return nil, errors.WithDetailf(ErrBadArchive, "record %d holds block %d", height, b.Height)
+	}
+	r.next++
+	r.n++
+	return &b, nil
+}
+
+func (r *Reader) readRecord() (height uint64, data []byte, err error) {
+	rec := make([]byte, recordHeaderLen)
+	_, err = io.ReadFull(r.r, rec)
+	if err == io.EOF {
+		return 0, nil, errors.WithDetail(ErrBadArchive, "archive is truncated: missing trailer")
+	} else if err != nil {
+		return 0, nil, errors.Sub(ErrBadArchive, errors.Wrap(err, "reading record header"))
+	}
+	height = binary.BigEndian.Uint64(rec)
+	n := binary.BigEndian.Uint32(rec[8:])
+	sum := binary.BigEndian.Uint32(rec[12:])
+	if n > maxBlockLen {
+		return 0, nil, errors.WithDetailf(ErrBadArchive, "record %d is %d bytes", height, n)
+	}
+
+	data = make([]byte, n)
+	_, err = io.ReadFull(r.r, data)
+	if err != nil {
+		return 0, nil, errors.Sub(ErrBadArchive, errors.Wrapf(err, "reading record %d", height))
+	}
+	if crc32.Checksum(data, crcTable) != sum {
+		return 0, nil, errors.WithDetailf(ErrBadArchive, "record %d fails its checksum", height)
+	}
+	if height != 0 {
+		r.digest.Write(rec)
+		r.digest.Write(data)
+	}
+	return height, data, nil
+}
+
+func (r *Reader) checkTrailer(data []byte) error {
+	r.done = true
+	if len(data) != 8+32 {
+		return errors.WithDetail(ErrBadArchive, "malformed trailer")
+	}
+	if n := binary.BigEndian.Uint64(data); n != r.n {
+		return errors.WithDetailf(ErrBadArchive, "trailer counts %d blocks, read %d", n, r.n)
+	}
+	digest := make([]byte, 32)
+	r.digest.Read(digest)
+	sha3pool.Put256(r.digest)
+	if !bytes.Equal(data[8:], digest) {
+		return errors.WithDetail(ErrBadArchive, "trailer digest doesn't match blocks")
+	}
+	return io.EOF
+}
@@ -0,0 +1,145 @@
+package blockarchive
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+func testBlocks(first, n uint64) []*bc.Block {
+	var blocks []*bc.Block
+	for h := first; h < first+n; h++ {
+		blocks = append(blocks, &bc.Block{
+			BlockHeader: bc.BlockHeader{
+				Version:           1,
+				Height:            h,
+				TimestampMS:       1000 * h,
+				ConsensusProgram:  []byte{byte(h)},
+				PreviousBlockHash: bc.Hash{byte(h - 1)},
+			},
+		})
+	}
+	return blocks
+}
+
+func writeArchive(t *testing.T, blocks []*bc.Block, compress bool) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, bc.Hash{1}, blocks[0].Height, compress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks {
+		err = w.WriteBlock(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readArchive(data []byte) ([]*bc.Block, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var blocks []*bc.Block
+	for {
+		b, err := r.Next()
+		if err == io.EOF {
+			return blocks, nil
+		} else if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, b)
+	}
+}
+
+func encode(t *testing.T, blocks []*bc.Block) []string {
+	var enc []string
+	for _, b := range blocks {
+		text, err := b.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc = append(enc, string(text))
+	}
+	return enc
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		blocks := testBlocks(5, 10)
+		data := writeArchive(t, blocks, compress)
+
+		r, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.BlockchainID() != (bc.Hash{1}) {
+			t.Errorf("compress=%v: BlockchainID = %x want %x", compress, r.BlockchainID(), bc.Hash{1})
+		}
+		if r.First() != 5 {
+			t.Errorf("compress=%v: First = %d want 5", compress, r.First())
+		}
+		if r.Compressed() != compress {
+			t.Errorf("compress=%v: Compressed = %v", compress, r.Compressed())
+		}
+
+		got, err := readArchive(data)
+		if err != nil {
+			t.Fatalf("compress=%v: %v", compress, err)
+		}
+		if !reflect.DeepEqual(encode(t, got), encode(t, blocks)) {
+			t.Errorf("compress=%v: got blocks %s want %s", compress, encode(t, got), encode(t, blocks))
+		}
+	}
+}
+
+func TestWriteOutOfOrder(t *testing.T) {
+	w, err := NewWriter(new(bytes.Buffer), bc.Hash{1}, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteBlock(testBlocks(2, 1)[0])
+	if errors.Root(err) != ErrBadArchive {
+		t.Errorf("WriteBlock(block 2) = %v want %v", err, ErrBadArchive)
+	}
+}
+
+func TestBadArchive(t *testing.T) {
+	data := writeArchive(t, testBlocks(1, 3), false)
+
+	flip := func(i int) []byte {
+		d := append([]byte(nil), data...)
+		d[i] ^= 1
+		return d
+	}
+
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", flip(0)},
+		{"bad version", flip(11)},
+		{"bad flags", flip(14)},
+		{"corrupt block", flip(headerLen + recordHeaderLen)},
+		{"missing trailer", data[:len(data)-recordHeaderLen-8-32]},
+		{"truncated trailer", data[:len(data)-1]},
+		{"corrupt trailer", flip(len(data) - 1)},
+	}
+	for _, c := range cases {
+		_, err := readArchive(c.data)
+		if errors.Root(err) != ErrBadArchive {
+			t.Errorf("%s: got error %v want %v", c.name, err, ErrBadArchive)
+		}
+	}
+}
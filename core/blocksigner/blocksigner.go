@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"time"
 
+	"chain/core/evidence"
 	"chain/crypto/ed25519"
 	"chain/database/pg"
 	"chain/errors"
@@ -14,9 +16,15 @@ import (
 )
 
 // ErrConsensusChange is returned from ValidateAndSignBlock
-// when a new consensus program is detected.
+// when a block changes the consensus program to one other
+// than the announced next consensus program.
 var ErrConsensusChange = errors.New("consensus program has changed")
 
+// ErrConflictingBlock is returned from ValidateAndSignBlock
+// when this signer has already signed a different block at
+// the same height.
+var ErrConflictingBlock = errors.New("already signed a different block at this height")
+
 // ErrInvalidKey is returned from SignBlock when the
 // key specified on the Signer is invalid. It may be
 // not found by the mock HSM or not paired to a valid
@@ -66,8 +74,14 @@ func (s *BlockSigner) String() string {
 // and, if valid, computes and returns a signature for the block.  It
 // is used as the httpjson handler for /rpc/signer/sign-block.
 //
-// This function fails if this node has ever signed a different block at the
-// same height as b.
+// This function fails with ErrConflictingBlock if this node has ever
+// signed a different block at the same height as b. It records the
+// request in the database (see evidence.SaveSignRequestConflict).
+//
+// A block may change the consensus program only to the next consensus
+// program set in this core's config (see config.SetNextConsensusProgram).
+// Once a block has switched to it, it becomes the current program,
+// and it's the only one signable until a new next program is set.
 func (s *BlockSigner) ValidateAndSignBlock(ctx context.Context, b *bc.Block) ([]byte, error) {
 	err := <-s.c.BlockSoonWaiter(ctx, b.Height-1)
 	if err != nil {
@@ -75,38 +89,71 @@ func (s *BlockSigner) ValidateAndSignBlock(ctx context.Context, b *bc.Block) ([]
 	}
 	prev, err := s.c.GetBlock(ctx, b.Height-1)
 	if err != nil {
-		return nil, errors.Wrap(err, "getting block at height %d", b.Height-1)
+		return nil, errors.Wrapf(err, "getting block at height %d", b.Height-1)
 	}
-	// TODO: Add the ability to change the consensus program
-	// by having a current consensus program, and a potential
-	// next consensus program. Once the next consensus program
-	// has been used, it will become the current consensus program
-	// and the only signable consensus program until a new
-	// next is set.
 	if !bytes.Equal(b.ConsensusProgram, prev.ConsensusProgram) {
-		return nil, errors.Wrap(ErrConsensusChange)
+		next, err := nextConsensusProgram(ctx, s.db)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting next consensus program")
+		}
+		if len(next) == 0 || !bytes.Equal(b.ConsensusProgram, next) {
+			return nil, errors.Wrap(ErrConsensusChange)
+		}
 	}
 	err = s.c.ValidateBlockForSig(ctx, b)
 	if err != nil {
 		return nil, errors.Wrap(err, "validating block for signature")
 	}
 	err = lockBlockHeight(ctx, s.db, b)
-	if err != nil {
+	if errors.Root(err) == ErrConflictingBlock {
+		return nil, err
+	} else if err != nil {
 		return nil, errors.Wrap(err, "lock block height")
 	}
 	return s.SignBlock(ctx, b)
 }
 
+// nextConsensusProgram returns the next consensus program
+// from the config, or nil if there is none.
+func nextConsensusProgram(ctx context.Context, db pg.DB) ([]byte, error) {
+	const q = `SELECT next_consensus_program FROM config`
+	var prog []byte
+	err := db.QueryRow(ctx, q).Scan(&prog)
+	return prog, err
+}
+
 // lockBlockHeight records a signer's intention to sign a given block
-// at a given height.  It's an error if a different block at the same
-// height has previously been signed.
+// at a given height. It's an error if a different block at the same
+// height has previously been signed; that block's hash is recorded
+// along with b as evidence against the generator.
 func lockBlockHeight(ctx context.Context, db pg.DB, b *bc.Block) error {
-	const q = `
-		INSERT INTO signed_blocks (block_height, block_hash)
-		SELECT $1, $2
-		    WHERE NOT EXISTS (SELECT 1 FROM signed_blocks
-		                      WHERE block_height = $1 AND block_hash = $2)
+	const insertQ = `
+		INSERT INTO signed_blocks (block_height, block_hash) VALUES ($1, $2)
+		ON CONFLICT (block_height) DO NOTHING
 	`
-	_, err := db.Exec(ctx, q, b.Height, b.Hash())
-	return err
+	_, err := db.Exec(ctx, insertQ, b.Height, b.Hash())
+	if err != nil {
+		return err
+	}
+
+	const selectQ = `SELECT block_hash FROM signed_blocks WHERE block_height = $1`
+	var signed bc.Hash
+	err = db.QueryRow(ctx, selectQ, b.Height).Scan(&signed)
+	if err != nil {
+		return err
+	}
+	if signed == b.Hash() {
+		return nil
+	}
+
+	err = evidence.SaveSignRequestConflict(ctx, db, &evidence.SignRequestConflict{
+		Height:     b.Height,
+		SignedHash: signed,
+		Header:     &b.BlockHeader,
+		DetectedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return errors.WithDetailf(ErrConflictingBlock, "signed block %s at height %d", signed, b.Height)
 }
@@ -0,0 +1,46 @@
+package blocksigner
+
+import (
+	"context"
+	"testing"
+
+	"chain/core/evidence"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestLockBlockHeight(t *testing.T) {
+	ctx := context.Background()
+	dbtx := pgtest.NewTx(t)
+
+	b1 := &bc.Block{BlockHeader: bc.BlockHeader{Height: 2, TimestampMS: 1}}
+	b2 := &bc.Block{BlockHeader: bc.BlockHeader{Height: 2, TimestampMS: 2}}
+
+	// Signing the same block again is fine.
+	for i := 0; i < 2; i++ {
+		err := lockBlockHeight(ctx, dbtx, b1)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	err := lockBlockHeight(ctx, dbtx, b2)
+	if errors.Root(err) != ErrConflictingBlock {
+		t.Fatalf("lockBlockHeight(conflicting block) = %v, want %v", err, ErrConflictingBlock)
+	}
+
+	conflicts, err := evidence.ListSignRequestConflicts(ctx, dbtx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("got %d sign request conflicts, want 1", len(conflicts))
+	}
+	c := conflicts[0]
+	if c.Height != 2 || c.SignedHash != b1.Hash() || c.Header.Hash() != b2.Hash() {
+		t.Errorf("got conflict at height %d between %s and %s, want 2 between %s and %s",
+			c.Height, c.SignedHash, c.Header.Hash(), b1.Hash(), b2.Hash())
+	}
+}
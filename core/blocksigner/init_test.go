@@ -0,0 +1,3 @@
+package blocksigner
+
+import _ "chain/protocol/tx" // for BlockHeaderHashFunc init
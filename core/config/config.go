@@ -3,6 +3,7 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -20,6 +21,7 @@ import (
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/state"
+	"chain/protocol/vmutil"
 )
 
 const (
@@ -33,6 +35,10 @@ var (
 	ErrBadQuorum         = errors.New("quorum must be greater than 0 if there are signers")
 	ErrNoProdBlockPub    = errors.New("blockpub cannot be empty in production")
 	ErrNoProdBlockHSMURL = errors.New("block hsm URL cannot be empty in production")
+	ErrBadBlockPeriod    = errors.New("block period must be greater than 0")
+	ErrBadMaxBlockTxs    = errors.New("max block txs must be greater than 0")
+	ErrBadHeartbeat      = errors.New("heartbeat period must be 0 or at least the block period")
+	ErrBadNextQuorum     = errors.New("quorum must be between 1 and the number of block signers")
 
 	Version, BuildCommit, BuildDate string
 	Production                      bool
@@ -53,6 +59,21 @@ type Config struct {
 	Signers              []BlockSigner `json:"block_signer_urls"`
 	Quorum               int
 	MaxIssuanceWindow    chainjson.Duration
+
+	// Block production parameters, used only by generators.
+	// If HeartbeatPeriod is nonzero, the generator makes an
+	// empty block whenever that much time has passed since the
+	// last block, so participants can tell an idle network from
+	// an unavailable generator.
+	BlockPeriod     chainjson.Duration `json:"block_period"`
+	MaxBlockTxs     int                `json:"max_block_txs"`
+	HeartbeatPeriod chainjson.Duration `json:"heartbeat_period"`
+
+	// NextConsensusProgram, if set, is the consensus program
+	// announced with SetNextConsensusProgram. A generator puts it
+	// in the next block it makes; a signer will sign blocks that
+	// switch to it.
+	NextConsensusProgram chainjson.HexBytes `json:"next_consensus_program,omitempty"`
 }
 
 type BlockSigner struct {
@@ -67,7 +88,9 @@ func Load(ctx context.Context, db pg.DB) (*Config, error) {
 			SELECT id, is_signer, is_generator,
 			blockchain_id, generator_url, generator_access_token, block_pub,
 			block_hsm_url, block_hsm_access_token,
-			remote_block_signers, max_issuance_window_ms, configured_at
+			remote_block_signers, max_issuance_window_ms,
+			block_period_ms, max_block_txs, heartbeat_period_ms,
+			next_consensus_program, configured_at
 			FROM config
 		`
 
@@ -75,6 +98,8 @@ func Load(ctx context.Context, db pg.DB) (*Config, error) {
 	var (
 		blockSignerData []byte
 		miw             int64
+		blockPeriod     int64
+		heartbeat       int64
 	)
 	err := db.QueryRow(ctx, q).Scan(
 		&c.ID,
@@ -88,6 +113,10 @@ func Load(ctx context.Context, db pg.DB) (*Config, error) {
 		&c.BlockHSMAccessToken,
 		&blockSignerData,
 		&miw,
+		&blockPeriod,
+		&c.MaxBlockTxs,
+		&heartbeat,
+		&c.NextConsensusProgram,
 		&c.ConfiguredAt,
 	)
 	if err == sql.ErrNoRows {
@@ -104,6 +133,8 @@ func Load(ctx context.Context, db pg.DB) (*Config, error) {
 	}
 
 	c.MaxIssuanceWindow = chainjson.Duration{time.Duration(miw) * time.Millisecond}
+	c.BlockPeriod.Duration = time.Duration(blockPeriod) * time.Millisecond
+	c.HeartbeatPeriod.Duration = time.Duration(heartbeat) * time.Millisecond
 	return c, nil
 }
 
@@ -171,6 +202,16 @@ func Configure(ctx context.Context, db pg.DB, c *Config) error {
 			return errors.Wrap(ErrBadQuorum)
 		}
 
+		if c.BlockPeriod.Duration <= 0 {
+			return errors.Wrap(ErrBadBlockPeriod)
+		}
+		if c.MaxBlockTxs <= 0 {
+			return errors.Wrap(ErrBadMaxBlockTxs)
+		}
+		if h := c.HeartbeatPeriod.Duration; h != 0 && h < c.BlockPeriod.Duration {
+			return errors.Wrap(ErrBadHeartbeat)
+		}
+
 		block, err := protocol.NewInitialBlock(signingKeys, c.Quorum, time.Now())
 		if err != nil {
 			return err
@@ -212,8 +253,9 @@ func Configure(ctx context.Context, db pg.DB, c *Config) error {
 		INSERT INTO config (id, is_signer, block_pub, is_generator,
 			blockchain_id, generator_url, generator_access_token,
 			block_hsm_url, block_hsm_access_token,
-			remote_block_signers, max_issuance_window_ms, configured_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+			remote_block_signers, max_issuance_window_ms,
+			block_period_ms, max_block_txs, heartbeat_period_ms, configured_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW())
 	`
 	_, err = db.Exec(
 		ctx,
@@ -229,10 +271,133 @@ func Configure(ctx context.Context, db pg.DB, c *Config) error {
 		c.BlockHSMAccessToken,
 		blockSignerData,
 		bc.DurationMillis(c.MaxIssuanceWindow.Duration),
+		bc.DurationMillis(c.BlockPeriod.Duration),
+		c.MaxBlockTxs,
+		bc.DurationMillis(c.HeartbeatPeriod.Duration),
 	)
 	return err
 }
 
+// SetNextConsensusProgram records the consensus program requiring
+// quorum signatures from the given block signers as the next one
+// for the blockchain, and returns it. The program lists the
+// signers' keys in the order given. The generator switches to it
+// in the next block it makes, and signers refuse to sign blocks
+// that change the consensus program to anything else.
+//
+// On a generator, signers with a URL are also added to the
+// configured remote block signers, replacing any existing entry
+// with the same key. Signers without a URL are expected to be
+// the generator's own local signer. Existing remote signers are
+// kept until the generator switches to the new program, since
+// their signatures are needed for the block that switches; then
+// PruneSigners removes them. As with Configure, a cored process
+// must reload the configuration to use the new signers.
+func SetNextConsensusProgram(ctx context.Context, db pg.DB, c *Config, signers []BlockSigner, quorum int) ([]byte, error) {
+	if quorum < 1 || quorum > len(signers) {
+		return nil, errors.Wrap(ErrBadNextQuorum)
+	}
+	var pubkeys []ed25519.PublicKey
+	for _, signer := range signers {
+		if len(signer.Pubkey) != ed25519.PublicKeySize {
+			return nil, errors.WithDetailf(ErrBadSignerPubkey, "pubkey %x", signer.Pubkey)
+		}
+		if c.IsGenerator && signer.URL != "" {
+			_, err := url.Parse(signer.URL)
+			if err != nil {
+				return nil, errors.Sub(ErrBadSignerURL, err)
+			}
+		}
+		pubkeys = append(pubkeys, ed25519.PublicKey(signer.Pubkey))
+	}
+	prog, err := vmutil.BlockMultiSigProgram(pubkeys, quorum)
+	if err != nil {
+		return nil, errors.Sub(ErrBadNextQuorum, err)
+	}
+
+	remote := c.Signers
+	if c.IsGenerator {
+		remote = mergeSigners(c.Signers, signers)
+	}
+	var blockSignerData []byte
+	if len(remote) > 0 {
+		blockSignerData, err = json.Marshal(remote)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+	}
+
+	const q = `
+		UPDATE config SET next_consensus_program = $1, remote_block_signers = $2
+	`
+	_, err = db.Exec(ctx, q, prog, blockSignerData)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating config")
+	}
+	c.NextConsensusProgram = prog
+	c.Signers = remote
+	return prog, nil
+}
+
+// mergeSigners returns the signers in old, updated with those
+// in next that have a URL.
+func mergeSigners(old, next []BlockSigner) []BlockSigner {
+	res := append([]BlockSigner(nil), old...)
+	for _, s := range next {
+		if s.URL == "" {
+			continue
+		}
+		i := 0
+		for i < len(res) && !bytes.Equal(res[i].Pubkey, s.Pubkey) {
+			i++
+		}
+		if i < len(res) {
+			res[i] = s
+		} else {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// PruneSigners removes the remote block signers whose keys
+// aren't in the consensus program prog, leaving only the
+// signers of prog. The generator calls it once it has
+// switched to a new consensus program.
+func PruneSigners(ctx context.Context, db pg.DB, c *Config, prog []byte) error {
+	pubkeys, _, err := vmutil.ParseBlockMultiSigProgram(prog)
+	if err != nil {
+		return errors.Wrap(err, "parsing consensus program")
+	}
+	var remote []BlockSigner
+	for _, s := range c.Signers {
+		for _, pubkey := range pubkeys {
+			if bytes.Equal(s.Pubkey, pubkey) {
+				remote = append(remote, s)
+				break
+			}
+		}
+	}
+	if len(remote) == len(c.Signers) {
+		return nil
+	}
+
+	var blockSignerData []byte
+	if len(remote) > 0 {
+		blockSignerData, err = json.Marshal(remote)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+	}
+	const q = `UPDATE config SET remote_block_signers = $1`
+	_, err = db.Exec(ctx, q, blockSignerData)
+	if err != nil {
+		return errors.Wrap(err, "updating config")
+	}
+	c.Signers = remote
+	return nil
+}
+
 func tryGenerator(ctx context.Context, url, accessToken, blockchainID string) error {
 	client := &rpc.Client{
 		BaseURL:      url,
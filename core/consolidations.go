@@ -0,0 +1,24 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/account"
+)
+
+// POST /list-utxo-consolidations
+//
+// listUTXOConsolidations returns the most recent transactions
+// the leader's consolidation job submitted to merge accounts'
+// small UTXOs, newest first. If account_id is given, it returns
+// only that account's.
+func (a *API) listUTXOConsolidations(ctx context.Context, in struct {
+	AccountID string `json:"account_id"`
+	PageSize  int    `json:"page_size"`
+}) ([]*account.Consolidation, error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+	return a.Accounts.ListConsolidations(ctx, in.AccountID, limit)
+}
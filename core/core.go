@@ -9,9 +9,11 @@ import (
 	"chain/core/config"
 	"chain/core/fetch"
 	"chain/core/leader"
+	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/log"
 	"chain/net/http/httpjson"
+	"chain/protocol"
 )
 
 var (
@@ -109,10 +111,14 @@ func (a *API) leaderInfo(ctx context.Context) (map[string]interface{}, error) {
 
 	// Add in snapshot information if we're downloading a snapshot.
 	if snapshot != nil {
+		height := snapshot.Height
+		if snapshot.DeltaHeight > 0 {
+			height = snapshot.DeltaHeight
+		}
 		m["snapshot"] = map[string]interface{}{
 			"attempt":     snapshot.Attempt,
-			"height":      snapshot.Height,
-			"size":        snapshot.Size,
+			"height":      height,
+			"size":        snapshot.Size + snapshot.DeltaSize,
 			"downloaded":  snapshot.BytesRead(),
 			"in_progress": snapshot.InProgress(),
 		}
@@ -128,6 +134,12 @@ func (a *API) configure(ctx context.Context, x *config.Config) error {
 	if x.IsGenerator && x.MaxIssuanceWindow.Duration == 0 {
 		x.MaxIssuanceWindow.Duration = 24 * time.Hour
 	}
+	if x.IsGenerator && x.BlockPeriod.Duration == 0 {
+		x.BlockPeriod.Duration = time.Second
+	}
+	if x.IsGenerator && x.MaxBlockTxs == 0 {
+		x.MaxBlockTxs = protocol.DefaultMaxBlockTxs
+	}
 
 	err := config.Configure(ctx, a.DB, x)
 	if err != nil {
@@ -139,6 +151,39 @@ func (a *API) configure(ctx context.Context, x *config.Config) error {
 	panic("unreached")
 }
 
+// POST /set-next-consensus-program
+//
+// setNextConsensusProgram announces the consensus program requiring
+// quorum signatures from the given block signers. Signers must
+// announce it before the generator does, since the generator starts
+// making blocks that switch to it right away. Those blocks must
+// still be signed by a quorum of the current block signers.
+//
+// On a generator, the leader process restarts to load the new
+// signers, and the response has no body.
+func (a *API) setNextConsensusProgram(ctx context.Context, x struct {
+	Quorum       int                  `json:"quorum"`
+	BlockSigners []config.BlockSigner `json:"block_signers"`
+}) (map[string]interface{}, error) {
+	if !leader.IsLeading() {
+		var resp map[string]interface{}
+		err := a.forwardToLeader(ctx, "/set-next-consensus-program", x, &resp)
+		return resp, err
+	}
+
+	prog, err := config.SetNextConsensusProgram(ctx, a.DB, a.Config, x.BlockSigners, x.Quorum)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Config.IsGenerator {
+		return map[string]interface{}{"consensus_program": chainjson.HexBytes(prog)}, nil
+	}
+
+	closeConnOK(httpjson.ResponseWriter(ctx), httpjson.Request(ctx))
+	execSelf("")
+	panic("unreached")
+}
+
 func closeConnOK(w http.ResponseWriter, req *http.Request) {
 	w.Header().Add("Connection", "close")
 	w.WriteHeader(http.StatusNoContent)
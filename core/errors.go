@@ -8,6 +8,7 @@ import (
 	"chain/core/asset"
 	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/generator"
 	"chain/core/query"
 	"chain/core/query/filter"
 	"chain/core/rpc"
@@ -40,6 +41,10 @@ func isTemporary(info errorInfo, err error) bool {
 		return true
 	case "CH001": // request timed out
 		return true
+	case "CH740": // generator pool full
+		return true
+	case "CH741": // too many pending txs from submitter
+		return true
 	case "CH761": // outputs currently reserved
 		return true
 	case "CH706": // 1 or more action errors
@@ -78,20 +83,25 @@ var (
 		txfeed.ErrDuplicateAlias:   errorInfo{400, "CH050", "Alias already exists"},
 
 		// Core error namespace
-		errUnconfigured:                errorInfo{400, "CH100", "This core still needs to be configured"},
-		errAlreadyConfigured:           errorInfo{400, "CH101", "This core has already been configured"},
-		config.ErrBadGenerator:         errorInfo{400, "CH102", "Generator URL returned an invalid response"},
-		errBadBlockPub:                 errorInfo{400, "CH103", "Provided Block XPub is invalid"},
-		rpc.ErrWrongNetwork:            errorInfo{502, "CH104", "A peer core is operating on a different blockchain network"},
-		protocol.ErrTheDistantFuture:   errorInfo{400, "CH105", "Requested height is too far ahead"},
-		config.ErrBadSignerURL:         errorInfo{400, "CH106", "Block signer URL is invalid"},
-		config.ErrBadSignerPubkey:      errorInfo{400, "CH107", "Block signer pubkey is invalid"},
-		config.ErrBadQuorum:            errorInfo{400, "CH108", "Quorum must be greater than 0 if there are signers"},
-		config.ErrNoProdBlockPub:       errorInfo{400, "CH109", "Block Pub cannot be empty when configuring a production signer"},
-		errProduction:                  errorInfo{400, "CH110", "This endpoint can only be called in a development system"},
-		config.ErrNoProdBlockHSMURL:    errorInfo{400, "CH111", "Block HSM URL cannot be empty when configuring a signer in production"},
-		errNoClientTokens:              errorInfo{400, "CH120", "Cannot enable client authentication with no client tokens"},
-		blocksigner.ErrConsensusChange: errorInfo{400, "CH150", "Refuse to sign block with consensus change"},
+		errUnconfigured:                 errorInfo{400, "CH100", "This core still needs to be configured"},
+		errAlreadyConfigured:            errorInfo{400, "CH101", "This core has already been configured"},
+		config.ErrBadGenerator:          errorInfo{400, "CH102", "Generator URL returned an invalid response"},
+		errBadBlockPub:                  errorInfo{400, "CH103", "Provided Block XPub is invalid"},
+		rpc.ErrWrongNetwork:             errorInfo{502, "CH104", "A peer core is operating on a different blockchain network"},
+		protocol.ErrTheDistantFuture:    errorInfo{400, "CH105", "Requested height is too far ahead"},
+		config.ErrBadSignerURL:          errorInfo{400, "CH106", "Block signer URL is invalid"},
+		config.ErrBadSignerPubkey:       errorInfo{400, "CH107", "Block signer pubkey is invalid"},
+		config.ErrBadQuorum:             errorInfo{400, "CH108", "Quorum must be greater than 0 if there are signers"},
+		config.ErrNoProdBlockPub:        errorInfo{400, "CH109", "Block Pub cannot be empty when configuring a production signer"},
+		errProduction:                   errorInfo{400, "CH110", "This endpoint can only be called in a development system"},
+		config.ErrNoProdBlockHSMURL:     errorInfo{400, "CH111", "Block HSM URL cannot be empty when configuring a signer in production"},
+		config.ErrBadBlockPeriod:        errorInfo{400, "CH112", "Block period must be greater than 0"},
+		config.ErrBadMaxBlockTxs:        errorInfo{400, "CH113", "Max block transactions must be greater than 0"},
+		config.ErrBadHeartbeat:          errorInfo{400, "CH114", "Heartbeat period must be 0 or at least the block period"},
+		config.ErrBadNextQuorum:         errorInfo{400, "CH115", "Quorum must be between 1 and the number of block signers"},
+		errNoClientTokens:               errorInfo{400, "CH120", "Cannot enable client authentication with no client tokens"},
+		blocksigner.ErrConsensusChange:  errorInfo{400, "CH150", "Refuse to sign block with consensus change"},
+		blocksigner.ErrConflictingBlock: errorInfo{400, "CH151", "Refuse to sign a different block at an already signed height"},
 
 		// Signers error namespace (2xx)
 		signers.ErrBadQuorum: errorInfo{400, "CH200", "Quorum must be greater than 1 and less than or equal to the length of xpubs"},
@@ -131,9 +141,15 @@ var (
 		txbuilder.ErrTxSignatureFailure:    errorInfo{400, "CH737", "Transaction signature missing, client may be missing signature key"},
 		txbuilder.ErrNoTxSighashAttempt:    errorInfo{400, "CH738", "Transaction signature was not attempted"},
 
+		// Generator pool error namespace (74x)
+		generator.ErrPoolFull:       errorInfo{503, "CH740", "Generator's transaction pool is full; try again later"},
+		generator.ErrTooManyPending: errorInfo{429, "CH741", "Too many pending transactions from this submitter; try again later"},
+		generator.ErrPoolConflict:   errorInfo{400, "CH742", "Transaction spends an output already spent by a pending transaction"},
+
 		// account action error namespace (76x)
-		account.ErrInsufficient: errorInfo{400, "CH760", "Insufficient funds for tx"},
-		account.ErrReserved:     errorInfo{400, "CH761", "Some outputs are reserved; try again"},
+		account.ErrInsufficient:         errorInfo{400, "CH760", "Insufficient funds for tx"},
+		account.ErrReserved:             errorInfo{400, "CH761", "Some outputs are reserved; try again"},
+		account.ErrBadSelectionStrategy: errorInfo{400, "CH762", "Invalid coin selection strategy"},
 
 		// Mock HSM error namespace (80x)
 	}
@@ -0,0 +1,25 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/evidence"
+)
+
+// POST /list-block-evidence
+//
+// listBlockEvidence returns the evidence of conflicting signed
+// blocks found by this core. Each item holds both block headers,
+// which anyone with the preceding block can verify.
+func (a *API) listBlockEvidence(ctx context.Context) ([]*evidence.Evidence, error) {
+	return evidence.List(ctx, a.DB)
+}
+
+// POST /list-sign-request-conflicts
+//
+// listSignRequestConflicts returns the requests this core, as a
+// block signer, refused because it had already signed a different
+// block at the same height.
+func (a *API) listSignRequestConflicts(ctx context.Context) ([]*evidence.SignRequestConflict, error) {
+	return evidence.ListSignRequestConflicts(ctx, a.DB)
+}
@@ -0,0 +1,210 @@
+// Package evidence records proof that block signers
+// signed conflicting blocks.
+package evidence
+
+import (
+	"context"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+	"chain/protocol/vm"
+	"chain/protocol/vmutil"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNoConflict is returned by New and Verify when
+	// the headers aren't for different blocks at the
+	// height after the previous block.
+	ErrNoConflict = errors.New("headers do not conflict")
+
+	// ErrBadWitness is returned by New and Verify when a
+	// header doesn't satisfy the previous block's
+	// consensus program.
+	ErrBadWitness = errors.New("header does not satisfy consensus program")
+)
+
+// Evidence is proof that two different blocks were signed
+// at the same height. Anyone with the block preceding them
+// can check it with Verify.
+type Evidence struct {
+	Height  uint64             `json:"height"`
+	Headers [2]*bc.BlockHeader `json:"headers"`
+
+	// Signers holds the keys in the previous block's consensus
+	// program with signatures in both headers. It's empty if
+	// the program isn't a multisig program.
+	Signers []chainjson.HexBytes `json:"signers"`
+
+	// Source identifies where the second header came from.
+	Source     string    `json:"source"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// New returns evidence that a and b conflict, if they are
+// different blocks following prev and both satisfy its
+// consensus program.
+func New(prev, a, b *bc.BlockHeader, source string) (*Evidence, error) {
+	e := &Evidence{
+		Height:     a.Height,
+		Headers:    [2]*bc.BlockHeader{a, b},
+		Source:     source,
+		DetectedAt: time.Now(),
+	}
+	err := e.Verify(prev)
+	if err != nil {
+		return nil, err
+	}
+	e.Signers = signedBoth(prev.ConsensusProgram, a, b)
+	return e, nil
+}
+
+// Verify checks that e holds two different headers
+// following prev, both satisfying its consensus program.
+func (e *Evidence) Verify(prev *bc.BlockHeader) error {
+	a, b := e.Headers[0], e.Headers[1]
+	if a == nil || b == nil || a.Hash() == b.Hash() {
+		return errors.Wrap(ErrNoConflict)
+	}
+	for _, h := range e.Headers {
+		if h.Height != prev.Height+1 || h.PreviousBlockHash != prev.Hash() {
+			return errors.WithDetailf(ErrNoConflict, "header %s does not follow block %d", h.Hash(), prev.Height)
+		}
+		err := vm.VerifyBlockHeader(prev, &bc.Block{BlockHeader: *h})
+		if err != nil {
+			return errors.Sub(ErrBadWitness, err)
+		}
+	}
+	return nil
+}
+
+// signedBoth returns the keys in the multisig program prog
+// that signed both a and b.
+func signedBoth(prog []byte, a, b *bc.BlockHeader) []chainjson.HexBytes {
+	pubkeys, _, err := vmutil.ParseBlockMultiSigProgram(prog)
+	if err != nil {
+		return nil
+	}
+	signedA, signedB := signedBy(pubkeys, a), signedBy(pubkeys, b)
+	var res []chainjson.HexBytes
+	for i, pub := range pubkeys {
+		if signedA[i] && signedB[i] {
+			res = append(res, chainjson.HexBytes(pub))
+		}
+	}
+	return res
+}
+
+func signedBy(pubkeys []ed25519.PublicKey, h *bc.BlockHeader) []bool {
+	hash := h.Hash()
+	res := make([]bool, len(pubkeys))
+	for _, sig := range h.Witness {
+		for i, pub := range pubkeys {
+			if ed25519.Verify(pub, hash[:], sig) {
+				res[i] = true
+			}
+		}
+	}
+	return res
+}
+
+// Save stores e in the database and logs it. Only the
+// first evidence found at each height is kept.
+func Save(ctx context.Context, db pg.DB, e *Evidence) error {
+	log.Write(ctx,
+		"at", "conflicting blocks",
+		"height", e.Height,
+		"block", e.Headers[0].Hash(),
+		"conflicting_block", e.Headers[1].Hash(),
+		"source", e.Source,
+	)
+	signers := [][]byte{} // not NULL
+	for _, pub := range e.Signers {
+		signers = append(signers, pub)
+	}
+	const q = `
+		INSERT INTO block_evidence
+			(block_height, header, conflicting_header, signers, source, detected_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (block_height) DO NOTHING
+	`
+	_, err := db.Exec(ctx, q, e.Height, e.Headers[0], e.Headers[1], pq.ByteaArray(signers), e.Source, e.DetectedAt)
+	return errors.Wrap(err, "saving block evidence")
+}
+
+// List returns the evidence in the database, ordered by height.
+func List(ctx context.Context, db pg.DB) ([]*Evidence, error) {
+	const q = `
+		SELECT header, conflicting_header, signers, source, detected_at
+		FROM block_evidence ORDER BY block_height
+	`
+	var res []*Evidence
+	err := pg.ForQueryRows(ctx, db, q, func(a, b bc.BlockHeader, signers pq.ByteaArray, source string, detectedAt time.Time) {
+		e := &Evidence{
+			Height:     a.Height,
+			Headers:    [2]*bc.BlockHeader{&a, &b},
+			Source:     source,
+			DetectedAt: detectedAt,
+		}
+		for _, pub := range signers {
+			e.Signers = append(e.Signers, pub)
+		}
+		res = append(res, e)
+	})
+	return res, errors.Wrap(err, "listing block evidence")
+}
+
+// SignRequestConflict records that a block signer was asked to
+// sign a block at a height where it had already signed a different
+// one. Only the generator proposes blocks, so it points to a faulty
+// or compromised generator. Unlike Evidence, the requested header
+// isn't signed, so only the signer that saw it can vouch for it.
+type SignRequestConflict struct {
+	Height     uint64          `json:"height"`
+	SignedHash bc.Hash         `json:"signed_hash"`
+	Header     *bc.BlockHeader `json:"header"`
+	DetectedAt time.Time       `json:"detected_at"`
+}
+
+// SaveSignRequestConflict stores c in the database and logs it.
+// Only the first conflict found at each height is kept.
+func SaveSignRequestConflict(ctx context.Context, db pg.DB, c *SignRequestConflict) error {
+	log.Write(ctx,
+		"at", "conflicting sign request",
+		"height", c.Height,
+		"signed_block", c.SignedHash,
+		"requested_block", c.Header.Hash(),
+	)
+	const q = `
+		INSERT INTO sign_request_conflicts (block_height, signed_hash, header, detected_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (block_height) DO NOTHING
+	`
+	_, err := db.Exec(ctx, q, c.Height, c.SignedHash, c.Header, c.DetectedAt)
+	return errors.Wrap(err, "saving sign request conflict")
+}
+
+// ListSignRequestConflicts returns the sign request
+// conflicts in the database, ordered by height.
+func ListSignRequestConflicts(ctx context.Context, db pg.DB) ([]*SignRequestConflict, error) {
+	const q = `
+		SELECT signed_hash, header, detected_at
+		FROM sign_request_conflicts ORDER BY block_height
+	`
+	var res []*SignRequestConflict
+	err := pg.ForQueryRows(ctx, db, q, func(signedHash bc.Hash, h bc.BlockHeader, detectedAt time.Time) {
+		res = append(res, &SignRequestConflict{
+			Height:     h.Height,
+			SignedHash: signedHash,
+			Header:     &h,
+			DetectedAt: detectedAt,
+		})
+	})
+	return res, errors.Wrap(err, "listing sign request conflicts")
+}
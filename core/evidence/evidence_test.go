@@ -0,0 +1,67 @@
+package evidence
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestNew(t *testing.T) {
+	var (
+		pubs  []ed25519.PublicKey
+		privs []ed25519.PrivateKey
+	)
+	for i := 0; i < 3; i++ {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		pubs = append(pubs, pub)
+		privs = append(privs, priv)
+	}
+	prev, err := protocol.NewInitialBlock(pubs, 2, time.Now())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	newBlock := func(ms uint64, signers ...int) *bc.BlockHeader {
+		b := &bc.BlockHeader{
+			Version:           bc.NewBlockVersion,
+			Height:            2,
+			PreviousBlockHash: prev.Hash(),
+			TimestampMS:       prev.TimestampMS + ms,
+			BlockCommitment:   prev.BlockCommitment,
+		}
+		hash := b.Hash()
+		for _, i := range signers {
+			b.Witness = append(b.Witness, ed25519.Sign(privs[i], hash[:]))
+		}
+		return b
+	}
+
+	a, b := newBlock(1, 0, 1), newBlock(2, 1, 2)
+	e, err := New(&prev.BlockHeader, a, b, "test")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(e.Signers) != 1 || !bytes.Equal(e.Signers[0], pubs[1]) {
+		t.Errorf("got signers %x, want [%x]", e.Signers, pubs[1])
+	}
+
+	_, err = New(&prev.BlockHeader, a, a, "test")
+	if errors.Root(err) != ErrNoConflict {
+		t.Errorf("New(same block) error = %v, want %v", err, ErrNoConflict)
+	}
+
+	// A block without a quorum of signatures isn't evidence.
+	_, err = New(&prev.BlockHeader, a, newBlock(3, 2), "test")
+	if errors.Root(err) != ErrBadWitness {
+		t.Errorf("New(unsigned block) error = %v, want %v", err, ErrBadWitness)
+	}
+}
@@ -0,0 +1,3 @@
+package evidence
+
+import _ "chain/protocol/tx" // for BlockHeaderHashFunc init
@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"context"
+	"time"
+
+	"chain/core/evidence"
+	"chain/core/rpc"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol"
+)
+
+const crossCheckTimeout = 10 * time.Second
+
+// CrossChecker compares the blocks in a Chain with those served
+// by other Cores. When a peer serves a properly signed block that
+// conflicts with one in the Chain, it saves the two headers as
+// evidence of misbehavior by the block signers.
+type CrossChecker struct {
+	Chain *protocol.Chain
+	DB    pg.DB
+	Peers []*rpc.Client
+}
+
+// Run checks the latest block in the Chain with every peer
+// once per period. It returns when its context is canceled.
+func (cc *CrossChecker) Run(ctx context.Context, period time.Duration) {
+	ticks := time.Tick(period)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Messagef(ctx, "Deposed, CrossCheck exiting")
+			return
+		case <-ticks:
+			height := cc.Chain.Height()
+			for _, peer := range cc.Peers {
+				_, err := cc.Check(ctx, peer, height)
+				if err != nil {
+					logNetworkError(ctx, err)
+				}
+			}
+		}
+	}
+}
+
+// Check compares the block at height in the Chain with the one
+// served by peer. If they conflict, it saves and returns the
+// evidence. If peer doesn't have the block yet, or has the same
+// one, Check returns nil.
+func (cc *CrossChecker) Check(ctx context.Context, peer *rpc.Client, height uint64) (*evidence.Evidence, error) {
+	if height < 2 {
+		// Cores with different initial blocks are
+		// on different blockchains.
+		return nil, nil
+	}
+	theirs, err := getBlock(ctx, peer, height, crossCheckTimeout)
+	if err != nil || theirs == nil {
+		return nil, err
+	}
+	ours, err := cc.Chain.GetBlock(ctx, height)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting block %d", height)
+	}
+	if theirs.Hash() == ours.Hash() {
+		return nil, nil
+	}
+	prev, err := cc.Chain.GetBlock(ctx, height-1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting block %d", height-1)
+	}
+	e, err := evidence.New(&prev.BlockHeader, &ours.BlockHeader, &theirs.BlockHeader, peer.BaseURL)
+	if err != nil {
+		// Without valid signatures, the peer's
+		// block proves nothing.
+		return nil, errors.Wrapf(err, "peer %s served bad block %d", peer.BaseURL, height)
+	}
+	err = evidence.Save(ctx, cc.DB, e)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -5,7 +5,6 @@ package fetch
 import (
 	"context"
 	"io"
-	"io/ioutil"
 	"math"
 	"math/rand"
 	"net"
@@ -14,12 +13,13 @@ import (
 	"time"
 
 	"chain/core/rpc"
-	"chain/core/txdb"
 	"chain/errors"
 	"chain/log"
 	"chain/protocol"
 	"chain/protocol/bc"
+	"chain/protocol/lightclient"
 	"chain/protocol/state"
+	"chain/protocol/validation"
 )
 
 const heightPollingPeriod = 3 * time.Second
@@ -53,13 +53,23 @@ func Init(ctx context.Context, peer *rpc.Client) {
 	go pollGeneratorHeight(ctx, peer)
 }
 
-// BootstrapSnapshot downloads and stores the most recent snapshot from the
-// provided peer. It's run when bootstrapping a new Core to an existing
-// network. It should be run before invoking Chain.Recover.
-func BootstrapSnapshot(ctx context.Context, c *protocol.Chain, peer *rpc.Client, health func(error)) {
+// BootstrapSnapshot downloads and stores the most recent snapshot from one
+// of the provided peers, trying them in turn. It's run when bootstrapping a
+// new Core to an existing network. It should be run before invoking
+// Chain.Recover.
+//
+// Before storing a snapshot, it checks that the snapshot's block descends
+// from checkpoint, or from the initial block if checkpoint is nil, and
+// that the snapshot matches the block's assets merkle root.
+func BootstrapSnapshot(ctx context.Context, c *protocol.Chain, peers []*rpc.Client, checkpoint *Checkpoint, health func(error)) {
 	const maxAttempts = 5
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		err := fetchSnapshot(ctx, peer, c.Store(), attempt)
+	if checkpoint == nil {
+		checkpoint = &Checkpoint{Height: 1, Hash: c.InitialBlockHash}
+	}
+	chunks := make(map[bc.Hash][]byte)
+	for attempt := 1; attempt <= maxAttempts*len(peers); attempt++ {
+		peer := peers[(attempt-1)%len(peers)]
+		err := fetchSnapshot(ctx, peer, c, checkpoint, chunks, attempt)
 		health(err)
 		if err == nil {
 			break
@@ -75,7 +85,11 @@ func BootstrapSnapshot(ctx context.Context, c *protocol.Chain, peer *rpc.Client,
 // It returns when its context is canceled.
 // After each attempt to fetch and apply a block, it calls health
 // to report either an error or nil to indicate success.
-func Fetch(ctx context.Context, c *protocol.Chain, peer *rpc.Client, health func(error), prevBlock *bc.Block, prevSnapshot *state.Snapshot) {
+//
+// If cc is not nil and the peer serves a block that doesn't
+// follow the latest block in c, Fetch uses cc to check for
+// evidence of conflicting blocks before giving up.
+func Fetch(ctx context.Context, c *protocol.Chain, peer *rpc.Client, health func(error), cc *CrossChecker, prevBlock *bc.Block, prevSnapshot *state.Snapshot) {
 	// If we downloaded a snapshot, now that we've recovered and successfully
 	// booted from the snapshot, mark it as done.
 	if sp := SnapshotProgress(); sp != nil {
@@ -100,6 +114,14 @@ func Fetch(ctx context.Context, c *protocol.Chain, peer *rpc.Client, health func
 			health(err)
 			logNetworkError(ctx, err)
 		case b := <-blockch:
+			if cc != nil && prevBlock != nil && b.PreviousBlockHash != prevBlock.Hash() {
+				// The peer's chain has diverged from ours. If its
+				// block at our height is signed, that's evidence.
+				_, err := cc.Check(ctx, peer, prevBlock.Height)
+				if err != nil {
+					log.Error(ctx, err)
+				}
+			}
 			for {
 				prevSnapshot, prevBlock, err = applyBlock(ctx, c, prevSnapshot, prevBlock, b)
 				if err == protocol.ErrBadBlock {
@@ -267,9 +289,11 @@ func logNetworkError(ctx context.Context, err error) {
 
 // Snapshot describes a snapshot being downloaded from a peer Core.
 type Snapshot struct {
-	Attempt int
-	Height  uint64
-	Size    uint64
+	Attempt     int
+	Height      uint64
+	Size        uint64
+	DeltaHeight uint64 `json:"delta_height"`
+	DeltaSize   uint64 `json:"delta_size"`
 	progressReader
 
 	stopped   bool
@@ -288,13 +312,12 @@ func (s *Snapshot) done() {
 	s.stopped = true
 }
 
-// fetchSnapshot fetches the latest snapshot from the generator and applies it
+// fetchSnapshot fetches the latest snapshot from peer and applies it
 // to the store. It should only be called on freshly configured cores--
 // cores that have been operating should replay all transactions so that
 // they can index them properly.
-func fetchSnapshot(ctx context.Context, peer *rpc.Client, s protocol.Store, attempt int) error {
+func fetchSnapshot(ctx context.Context, peer *rpc.Client, c *protocol.Chain, checkpoint *Checkpoint, chunks map[bc.Hash][]byte, attempt int) error {
 	const getBlockTimeout = 30 * time.Second
-	const readSnapshotTimeout = 30 * time.Second
 
 	info := &Snapshot{Attempt: attempt}
 	err := peer.Call(ctx, "/rpc/get-snapshot-info", nil, &info)
@@ -309,26 +332,21 @@ func fetchSnapshot(ctx context.Context, peer *rpc.Client, s protocol.Store, atte
 	downloadingSnapshot = info
 	downloadingSnapshotMu.Unlock()
 
-	downloadCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	// Download the snapshot, recording our progress as we go.
-	body, err := peer.CallRaw(downloadCtx, "/rpc/get-snapshot", info.Height)
-	if err != nil {
-		return errors.Wrap(err, "getting snapshot")
-	}
-	defer body.Close()
-
-	// Wrap the response body reader in our progress reader.
-	info.progressReader.reader = body
-	info.progressReader.setTimeout(readSnapshotTimeout, cancel)
-	b, err := ioutil.ReadAll(&info.progressReader)
+	// Download the full snapshot and, if the peer has one,
+	// the latest delta from it.
+	snapshot, err := downloadSnapshot(ctx, peer, info, info.Height, nil, chunks)
 	if err != nil {
 		return err
 	}
-	snapshot, err := txdb.DecodeSnapshot(b)
-	if err != nil {
-		return err
+	height := info.Height
+	if info.DeltaHeight > 0 {
+		snapshot, err = downloadSnapshot(ctx, peer, info, info.DeltaHeight, snapshot, chunks)
+		if err != nil {
+			return err
+		}
+		height = info.DeltaHeight
 	}
+
 	// Delete the snapshot issuances because we don't have any commitment
 	// to them in the block. This means that Cores bootstrapping from a
 	// snapshot cannot guarantee uniqueness of issuances until the max
@@ -344,9 +362,16 @@ func fetchSnapshot(ctx context.Context, peer *rpc.Client, s protocol.Store, atte
 		// Something seriously funny is afoot.
 		return errors.New("could not get initial block from generator")
 	}
+	if initialBlock.Hash() != c.InitialBlockHash {
+		return errors.Wrapf(ErrBadSnapshot, "peer's initial block is %s, want %s", initialBlock.Hash(), c.InitialBlockHash)
+	}
+	err = checkTxsMerkleRoot(initialBlock)
+	if err != nil {
+		return err
+	}
 
 	// Also get the corresponding block.
-	snapshotBlock, err := getBlock(ctx, peer, info.Height, getBlockTimeout)
+	snapshotBlock, err := getBlock(ctx, peer, height, getBlockTimeout)
 	if err != nil {
 		return err
 	}
@@ -355,10 +380,30 @@ func fetchSnapshot(ctx context.Context, peer *rpc.Client, s protocol.Store, atte
 		return errors.New("generator provided snapshot but could not provide block")
 	}
 	if snapshotBlock.AssetsMerkleRoot != snapshot.Tree.RootHash() {
-		return errors.New("snapshot merkle root doesn't match block")
+		return errors.Wrap(ErrBadSnapshot, "snapshot merkle root doesn't match block")
+	}
+	err = checkTxsMerkleRoot(snapshotBlock)
+	if err != nil {
+		return err
+	}
+
+	// Check the signatures on the headers from
+	// the checkpoint to the snapshot block.
+	if height < checkpoint.Height {
+		return errors.Wrapf(ErrBadSnapshot, "snapshot height %d is below checkpoint %d", height, checkpoint.Height)
+	}
+	lc := lightclient.NewFromCheckpoint(peer, checkpoint.Height, checkpoint.Hash)
+	err = lc.SyncTo(ctx, height)
+	if err != nil {
+		return errors.Sub(ErrBadSnapshot, err)
+	}
+	header, _ := lc.Header(height)
+	if snapshotBlock.Hash() != header.Hash() {
+		return errors.Wrapf(ErrBadSnapshot, "snapshot block %s is not in the blockchain", snapshotBlock.Hash())
 	}
 
 	// Commit the snapshot, initial block and snapshot block.
+	s := c.Store()
 	err = s.SaveBlock(ctx, initialBlock)
 	if err != nil {
 		return errors.Wrap(err, "saving the initial block")
@@ -371,6 +416,20 @@ func fetchSnapshot(ctx context.Context, peer *rpc.Client, s protocol.Store, atte
 	return errors.Wrap(err, "saving bootstrap snaphot")
 }
 
+// checkTxsMerkleRoot checks that b's transactions match
+// the merkle root in its header. The header hash covers
+// only the root, not the transactions themselves.
+func checkTxsMerkleRoot(b *bc.Block) error {
+	root, err := validation.CalcMerkleRoot(b.Transactions)
+	if err != nil {
+		return errors.Wrap(err, "computing transactions merkle root")
+	}
+	if root != b.TransactionsMerkleRoot {
+		return errors.Wrapf(ErrBadSnapshot, "block %d transactions don't match merkle root", b.Height)
+	}
+	return nil
+}
+
 type progressReader struct {
 	reader io.Reader
 	read   uint64
@@ -0,0 +1,3 @@
+package fetch
+
+import _ "chain/protocol/tx" // for BlockHeaderHashFunc init
@@ -0,0 +1,131 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+
+	"chain/core/rpc"
+	"chain/core/snapshotenc"
+	"chain/crypto/sha3pool"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/state"
+)
+
+// SnapshotChunkSize is the size of the chunks snapshots
+// are downloaded in. The last chunk may be smaller.
+const SnapshotChunkSize = 4 << 20
+
+// ErrBadSnapshot is returned when a peer serves a snapshot
+// that doesn't match the blockchain.
+var ErrBadSnapshot = errors.New("snapshot does not match blockchain")
+
+// A Checkpoint is a block trusted to be in the blockchain.
+// Bootstrapping checks the signatures on every block header
+// from the checkpoint to the snapshot's block.
+type Checkpoint struct {
+	Height uint64
+	Hash   bc.Hash
+}
+
+// SnapshotManifest describes the chunks of a stored snapshot.
+// Peers serve it from /rpc/get-snapshot-manifest.
+type SnapshotManifest struct {
+	Height      uint64    `json:"height"`
+	Size        uint64    `json:"size"`
+	ChunkHashes []bc.Hash `json:"chunk_hashes"`
+}
+
+// NewSnapshotManifest returns the manifest for
+// data, the snapshot at height.
+func NewSnapshotManifest(height uint64, data []byte) *SnapshotManifest {
+	m := &SnapshotManifest{
+		Height:      height,
+		Size:        uint64(len(data)),
+		ChunkHashes: []bc.Hash{}, // not null
+	}
+	for len(data) > 0 {
+		n := SnapshotChunkSize
+		if n > len(data) {
+			n = len(data)
+		}
+		m.ChunkHashes = append(m.ChunkHashes, chunkHash(data[:n]))
+		data = data[n:]
+	}
+	return m
+}
+
+func chunkHash(chunk []byte) (h bc.Hash) {
+	sha3pool.Sum256(h[:], chunk)
+	return h
+}
+
+// downloadSnapshot downloads and decodes the snapshot at height,
+// recording progress in info. If the snapshot is a delta, base
+// must be the full snapshot it's relative to.
+//
+// It downloads the snapshot in chunks, checking each against the
+// peer's manifest. Chunks are kept in chunks, by hash, so a later
+// attempt can resume where an earlier one failed.
+func downloadSnapshot(ctx context.Context, peer *rpc.Client, info *Snapshot, height uint64, base *state.Snapshot, chunks map[bc.Hash][]byte) (*state.Snapshot, error) {
+	var m SnapshotManifest
+	err := peer.Call(ctx, "/rpc/get-snapshot-manifest", height, &m)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting snapshot manifest")
+	}
+	if m.Height != height {
+		return nil, errors.Wrapf(ErrBadSnapshot, "peer sent manifest for height %d, want %d", m.Height, height)
+	}
+
+	var buf bytes.Buffer
+	for i, h := range m.ChunkHashes {
+		chunk, ok := chunks[h]
+		if ok {
+			atomic.AddUint64(&info.progressReader.read, uint64(len(chunk)))
+		} else {
+			chunk, err = downloadChunk(ctx, peer, info, height, i)
+			if err != nil {
+				return nil, err
+			}
+			if chunkHash(chunk) != h {
+				return nil, errors.Wrapf(ErrBadSnapshot, "chunk %d of snapshot %d doesn't match its checksum", i, height)
+			}
+			chunks[h] = chunk
+		}
+		buf.Write(chunk)
+	}
+	if uint64(buf.Len()) != m.Size {
+		return nil, errors.Wrapf(ErrBadSnapshot, "snapshot %d is %d bytes, want %d", height, buf.Len(), m.Size)
+	}
+	snapshot, err := snapshotenc.Decode(buf.Bytes(), base)
+	if err != nil {
+		return nil, errors.Sub(ErrBadSnapshot, err)
+	}
+	return snapshot, nil
+}
+
+func downloadChunk(ctx context.Context, peer *rpc.Client, info *Snapshot, height uint64, index int) ([]byte, error) {
+	const readSnapshotTimeout = 30 * time.Second
+
+	downloadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req := struct {
+		Height uint64 `json:"height"`
+		Index  int    `json:"index"`
+	}{height, index}
+	body, err := peer.CallRaw(downloadCtx, "/rpc/get-snapshot-chunk", req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting chunk %d of snapshot %d", index, height)
+	}
+	defer body.Close()
+
+	// Wrap the response body reader in our progress reader.
+	info.progressReader.reader = body
+	info.progressReader.setTimeout(readSnapshotTimeout, cancel)
+	defer info.progressReader.timer.Stop()
+	return ioutil.ReadAll(io.LimitReader(&info.progressReader, SnapshotChunkSize))
+}
@@ -0,0 +1,189 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chain/core/rpc"
+	"chain/core/snapshotenc"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/memstore"
+	"chain/protocol/prottest"
+	"chain/protocol/state"
+	"chain/testutil"
+)
+
+func TestFetchSnapshot(t *testing.T) {
+	ctx := context.Background()
+	src := prottest.NewChain(t)
+	prottest.MakeBlock(t, src, []*bc.Tx{prottest.NewIssuanceTx(t, src)})
+	b3 := prottest.MakeBlock(t, src, nil)
+	_, snapshot := src.State()
+	data, err := snapshotenc.Encode(snapshot)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	wrongState := state.Copy(snapshot)
+	err = wrongState.Tree.Insert(bc.Hash{3}.Bytes())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	wrongData, err := snapshotenc.Encode(wrongState)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	badManifest := NewSnapshotManifest(3, data)
+	badManifest.ChunkHashes[0] = bc.Hash{1}
+	extraTx := prottest.NewIssuanceTx(t, src)
+	addTx := func(height uint64) func(*bc.Block) {
+		return func(b *bc.Block) {
+			if b.Height == height {
+				b.Transactions = append(b.Transactions, extraTx)
+			}
+		}
+	}
+
+	cases := []struct {
+		name         string
+		data         []byte
+		manifest     *SnapshotManifest
+		initialBlock bc.Hash
+		checkpoint   *Checkpoint
+		tamper       func(*bc.Block)
+		wantErr      error
+	}{{
+		name: "ok",
+	}, {
+		name:    "wrong root",
+		data:    wrongData,
+		wantErr: ErrBadSnapshot,
+	}, {
+		name:         "wrong initial block",
+		initialBlock: bc.Hash{1},
+		wantErr:      ErrBadSnapshot,
+	}, {
+		name:     "bad chunk hash",
+		manifest: badManifest,
+		wantErr:  ErrBadSnapshot,
+	}, {
+		name:       "outside checkpoint chain",
+		checkpoint: &Checkpoint{Height: 2, Hash: bc.Hash{1}},
+		wantErr:    ErrBadSnapshot,
+	}, {
+		name:    "initial block txs not in merkle root",
+		tamper:  addTx(1),
+		wantErr: ErrBadSnapshot,
+	}, {
+		name:    "snapshot block txs not in merkle root",
+		tamper:  addTx(3),
+		wantErr: ErrBadSnapshot,
+	}}
+	for _, c := range cases {
+		peer := &snapshotPeer{c: src, height: 3, data: data, manifest: c.manifest, tamper: c.tamper}
+		if c.data != nil {
+			peer.data = c.data
+		}
+		server := httptest.NewServer(peer)
+
+		initialBlock := src.InitialBlockHash
+		if c.initialBlock != (bc.Hash{}) {
+			initialBlock = c.initialBlock
+		}
+		store := memstore.New()
+		dst, err := protocol.NewChain(ctx, initialBlock, store, nil)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		checkpoint := &Checkpoint{Height: 1, Hash: src.InitialBlockHash}
+		if c.checkpoint != nil {
+			checkpoint = c.checkpoint
+		}
+
+		err = fetchSnapshot(ctx, &rpc.Client{BaseURL: server.URL}, dst, checkpoint, make(map[bc.Hash][]byte), 1)
+		server.Close()
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("%s: fetchSnapshot() = %v want %v", c.name, err, c.wantErr)
+			continue
+		}
+		if c.wantErr != nil {
+			continue
+		}
+		got, height, err := store.LatestSnapshot(ctx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if height != 3 || got.Tree.RootHash() != b3.AssetsMerkleRoot {
+			t.Errorf("%s: stored snapshot at height %d with root %x, want height 3 and root %x", c.name, height, got.Tree.RootHash().Bytes(), b3.AssetsMerkleRoot.Bytes())
+		}
+	}
+}
+
+// snapshotPeer serves the network RPCs used by fetchSnapshot,
+// with blocks from a Chain and data as the snapshot at height.
+type snapshotPeer struct {
+	c        *protocol.Chain
+	height   uint64
+	data     []byte
+	manifest *SnapshotManifest // if nil, computed from data
+	tamper   func(*bc.Block)   // if non-nil, applied to served blocks
+}
+
+func (p *snapshotPeer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	var resp interface{}
+	switch req.URL.Path {
+	case "/rpc/get-snapshot-info":
+		resp = &Snapshot{Height: p.height, Size: uint64(len(p.data))}
+	case "/rpc/get-snapshot-manifest":
+		resp = p.manifest
+		if p.manifest == nil {
+			resp = NewSnapshotManifest(p.height, p.data)
+		}
+	case "/rpc/get-snapshot-chunk":
+		var x struct {
+			Height uint64 `json:"height"`
+			Index  int    `json:"index"`
+		}
+		json.NewDecoder(req.Body).Decode(&x)
+		start := x.Index * SnapshotChunkSize
+		if x.Height != p.height || start >= len(p.data) {
+			http.NotFound(rw, req)
+			return
+		}
+		end := start + SnapshotChunkSize
+		if end > len(p.data) {
+			end = len(p.data)
+		}
+		rw.Write(p.data[start:end])
+		return
+	case "/rpc/get-block", "/rpc/get-block-header":
+		var height uint64
+		json.NewDecoder(req.Body).Decode(&height)
+		b, err := p.c.GetBlock(ctx, height)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if req.URL.Path == "/rpc/get-block-header" {
+			resp = &b.BlockHeader
+			break
+		}
+		if p.tamper != nil {
+			copy := *b
+			copy.Transactions = append([]*bc.Tx(nil), b.Transactions...)
+			p.tamper(&copy)
+			b = &copy
+		}
+		resp = b
+	default:
+		http.NotFound(rw, req)
+		return
+	}
+	json.NewEncoder(rw).Encode(resp)
+}
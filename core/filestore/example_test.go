@@ -0,0 +1,58 @@
+package filestore_test
+
+import (
+	"context"
+	"log"
+
+	"chain/core/filestore"
+	"chain/core/rpc"
+	"chain/protocol"
+	"chain/protocol/bc"
+)
+
+// This example follows a blockchain served by a Core,
+// validating every block and keeping the blocks and
+// state snapshots in a directory instead of a database.
+func Example() {
+	ctx := context.Background()
+
+	// The hash of the blockchain's initial block.
+	var blockchainID bc.Hash
+
+	store, err := filestore.Open("/var/lib/chain-validator")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	c, err := protocol.NewChain(ctx, blockchainID, store, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prev, snapshot, err := c.Recover(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	peer := &rpc.Client{
+		BaseURL:      "https://core.example.com:1999",
+		AccessToken:  "network-token-id:secret",
+		BlockchainID: blockchainID.String(),
+	}
+	for height := c.Height() + 1; ; height++ {
+		var b bc.Block
+		err = peer.Call(ctx, "/rpc/get-block", height, &b)
+		if err != nil {
+			log.Fatal(err)
+		}
+		snapshot, err = c.ValidateBlock(ctx, snapshot, prev, &b)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = c.CommitBlock(ctx, &b, snapshot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		prev = &b
+	}
+}
@@ -0,0 +1,504 @@
+// Package filestore provides a protocol.Store that keeps blocks
+// and state snapshots in files in a single directory, so a Core
+// can validate the blockchain without a database.
+//
+// Blocks are appended to a log, each record carrying its height
+// and a checksum. An index file maps heights to offsets in the
+// log. SaveBlock returns only after the log is synced to disk;
+// the index is not synced, because Open rebuilds any part of it
+// that was lost from the log. A record torn by a crash is
+// discarded when the store is next opened. Snapshots are written
+// to temporary files and renamed into place.
+//
+// A Store can back a protocol.Chain that follows a blockchain
+// from a Core's network RPCs, validating each block without a
+// database; see the example. A full Core keeps its blocks in
+// Postgres with package txdb instead, and doesn't use this one.
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"chain/core/snapshotenc"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/state"
+)
+
+const (
+	logName     = "blocks.log"
+	indexName   = "blocks.idx"
+	snapshotDir = "snapshots"
+
+	// recordHeaderLen is the size of the height, length,
+	// and checksum preceding each block in the log.
+	recordHeaderLen = 16
+
+	// indexEntryLen is the size of a height and
+	// log offset in the index.
+	indexEntryLen = 16
+
+	snapshotExt = ".snapshot"
+
+	// snapshotsKept is how many of the latest
+	// snapshots SaveSnapshot keeps.
+	snapshotsKept = 2
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrNoBlock is returned by GetBlock for a
+	// height without a saved block.
+	ErrNoBlock = errors.New("no block at height")
+
+	// ErrConflictingBlock is returned by SaveBlock for a block
+	// at a height where a different block is already saved.
+	ErrConflictingBlock = errors.New("already have a different block at height")
+
+	// ErrBlockOrder is returned by SaveBlock for a block
+	// below the height of the latest block saved.
+	ErrBlockOrder = errors.New("blocks must be saved in increasing order of height")
+
+	// errTorn is returned by readRecord for a record
+	// that is incomplete or fails its checksum.
+	errTorn = errors.New("torn record")
+)
+
+// Store satisfies the protocol.Store interface. Only one
+// process may use a directory at a time.
+type Store struct {
+	dir string
+
+	mu      sync.Mutex // protects the following
+	log     *os.File
+	index   *os.File
+	size    int64            // end of the last intact record in log
+	offsets map[uint64]int64 // block height -> record offset in log
+	height  uint64
+}
+
+var _ protocol.Store = (*Store)(nil)
+
+// Open opens the store in dir, creating it if necessary,
+// and recovers from any earlier crash.
+func Open(dir string) (*Store, error) {
+	err := os.MkdirAll(filepath.Join(dir, snapshotDir), 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating data directory")
+	}
+	log, err := os.OpenFile(filepath.Join(dir, logName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening block log")
+	}
+	index, err := os.OpenFile(filepath.Join(dir, indexName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		log.Close()
+		return nil, errors.Wrap(err, "opening block index")
+	}
+	s := &Store{
+		dir:     dir,
+		log:     log,
+		index:   index,
+		offsets: make(map[uint64]int64),
+	}
+	err = s.recover()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the store's files.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.log.Close()
+	if err2 := s.index.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// recover loads the index, adds any blocks it's missing
+// from the log, and truncates any torn record at the end
+// of the log.
+func (s *Store) recover() error {
+	info, err := s.log.Stat()
+	if err != nil {
+		return errors.Wrap(err, "reading block log size")
+	}
+	logSize := info.Size()
+
+	buf, err := ioutil.ReadAll(s.index)
+	if err != nil {
+		return errors.Wrap(err, "reading block index")
+	}
+	indexSize := len(buf)
+	type entry struct {
+		height uint64
+		offset int64
+	}
+	var entries []entry
+	for len(buf) >= indexEntryLen {
+		entries = append(entries, entry{
+			height: binary.BigEndian.Uint64(buf),
+			offset: int64(binary.BigEndian.Uint64(buf[8:])),
+		})
+		buf = buf[indexEntryLen:]
+	}
+
+	// Trust the index only if its entries are in order
+	// and the last one is an intact record.
+	var pos int64
+	for i, e := range entries {
+		if i > 0 && (e.height <= entries[i-1].height || e.offset <= entries[i-1].offset) {
+			entries = nil
+			break
+		}
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		height, data, err := readRecord(s.log, last.offset, logSize)
+		if err == nil && height == last.height {
+			pos = last.offset + recordHeaderLen + int64(len(data))
+		} else if err != nil && err != errTorn {
+			return err
+		} else {
+			entries = nil
+		}
+	}
+	for _, e := range entries {
+		s.offsets[e.height] = e.offset
+		s.height = e.height
+	}
+	rewrite := len(entries)*indexEntryLen != indexSize
+
+	// Scan for records the index is missing.
+	for pos < logSize {
+		height, data, err := readRecord(s.log, pos, logSize)
+		if err == errTorn || (err == nil && height <= s.height) {
+			break
+		} else if err != nil {
+			return err
+		}
+		s.offsets[height] = pos
+		s.height = height
+		pos += recordHeaderLen + int64(len(data))
+		rewrite = true
+	}
+
+	if pos < logSize {
+		err = s.log.Truncate(pos)
+		if err != nil {
+			return errors.Wrap(err, "truncating torn block")
+		}
+		err = s.log.Sync()
+		if err != nil {
+			return errors.Wrap(err, "syncing block log")
+		}
+	}
+	s.size = pos
+
+	if rewrite {
+		return s.rewriteIndex()
+	}
+	return nil
+}
+
+func (s *Store) rewriteIndex() error {
+	heights := make([]uint64, 0, len(s.offsets))
+	for h := range s.offsets {
+		heights = append(heights, h)
+	}
+	sort.Sort(uint64s(heights))
+
+	buf := make([]byte, 0, len(heights)*indexEntryLen)
+	for _, h := range heights {
+		buf = appendIndexEntry(buf, h, s.offsets[h])
+	}
+	err := s.index.Truncate(0)
+	if err != nil {
+		return errors.Wrap(err, "truncating block index")
+	}
+	_, err = s.index.WriteAt(buf, 0)
+	if err != nil {
+		return errors.Wrap(err, "writing block index")
+	}
+	return errors.Wrap(s.index.Sync(), "syncing block index")
+}
+
+// readRecord reads the record at offset off in f, which is
+// size bytes long. It returns the record's height and block data.
+// The record's length is recordHeaderLen+len(data).
+func readRecord(f *os.File, off, size int64) (height uint64, data []byte, err error) {
+	if off+recordHeaderLen > size {
+		return 0, nil, errTorn
+	}
+	var header [recordHeaderLen]byte
+	_, err = f.ReadAt(header[:], off)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "reading block record")
+	}
+	height = binary.BigEndian.Uint64(header[:])
+	length := int64(binary.BigEndian.Uint32(header[8:]))
+	sum := binary.BigEndian.Uint32(header[12:])
+	if off+recordHeaderLen+length > size {
+		return 0, nil, errTorn
+	}
+	data = make([]byte, length)
+	_, err = f.ReadAt(data, off+recordHeaderLen)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "reading block record")
+	}
+	if crc32.Checksum(data, crcTable) != sum {
+		return 0, nil, errTorn
+	}
+	return height, data, nil
+}
+
+func appendIndexEntry(buf []byte, height uint64, offset int64) []byte {
+	var e [indexEntryLen]byte
+	binary.BigEndian.PutUint64(e[:], height)
+	binary.BigEndian.PutUint64(e[8:], uint64(offset))
+	return append(buf, e[:]...)
+}
+
+// Height returns the height of the latest block saved.
+func (s *Store) Height(context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.height, nil
+}
+
+// GetBlock returns the block at height.
+func (s *Store) GetBlock(ctx context.Context, height uint64) (*bc.Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getBlock(height)
+}
+
+func (s *Store) getBlock(height uint64) (*bc.Block, error) {
+	off, ok := s.offsets[height]
+	if !ok {
+		return nil, errors.WithDetailf(ErrNoBlock, "height %d", height)
+	}
+	_, data, err := readRecord(s.log, off, s.size)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading block %d", height)
+	}
+	var b bc.Block
+	err = b.Scan(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding block %d", height)
+	}
+	return &b, nil
+}
+
+// SaveBlock appends b to the block log and syncs it to disk.
+// Blocks must be saved in increasing order of height, but
+// heights may be skipped, as when bootstrapping from a
+// snapshot. Saving a block that is already saved does nothing.
+func (s *Store) SaveBlock(ctx context.Context, b *bc.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.offsets[b.Height]; ok {
+		existing, err := s.getBlock(b.Height)
+		if err != nil {
+			return err
+		}
+		if existing.Hash() != b.Hash() {
+			return errors.WithDetailf(ErrConflictingBlock, "height %d", b.Height)
+		}
+		return nil
+	}
+	if b.Height <= s.height {
+		return errors.WithDetailf(ErrBlockOrder, "cannot save block %d after block %d", b.Height, s.height)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, recordHeaderLen))
+	_, err := b.WriteTo(&buf)
+	if err != nil {
+		return errors.Wrap(err, "serializing block")
+	}
+	rec := buf.Bytes()
+	data := rec[recordHeaderLen:]
+	binary.BigEndian.PutUint64(rec, b.Height)
+	binary.BigEndian.PutUint32(rec[8:], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[12:], crc32.Checksum(data, crcTable))
+
+	_, err = s.log.WriteAt(rec, s.size)
+	if err != nil {
+		return errors.Wrap(err, "writing block log")
+	}
+	err = s.log.Sync()
+	if err != nil {
+		return errors.Wrap(err, "syncing block log")
+	}
+
+	entry := appendIndexEntry(nil, b.Height, s.size)
+	_, err = s.index.WriteAt(entry, int64(len(s.offsets))*indexEntryLen)
+	if err != nil {
+		return errors.Wrap(err, "writing block index")
+	}
+
+	s.offsets[b.Height] = s.size
+	s.size += int64(len(rec))
+	s.height = b.Height
+	return nil
+}
+
+// FinalizeBlock does nothing. Blocks are durable once
+// SaveBlock returns, and no other process is watching.
+func (s *Store) FinalizeBlock(context.Context, uint64) error { return nil }
+
+// SaveSnapshot writes a snapshot of the state at height
+// and removes all but the latest few snapshots.
+func (s *Store) SaveSnapshot(ctx context.Context, height uint64, snapshot *state.Snapshot) error {
+	data, err := snapshotenc.Encode(snapshot)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(s.dir, snapshotDir)
+	f, err := ioutil.TempFile(dir, "tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating snapshot file")
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return errors.Wrap(err, "writing snapshot file")
+	}
+	err = os.Rename(f.Name(), filepath.Join(dir, snapshotName(height)))
+	if err != nil {
+		os.Remove(f.Name())
+		return errors.Wrap(err, "renaming snapshot file")
+	}
+	err = syncDir(dir)
+	if err != nil {
+		return err
+	}
+
+	heights, err := s.snapshotHeights()
+	if err != nil {
+		return err
+	}
+	for len(heights) > snapshotsKept {
+		err = os.Remove(filepath.Join(dir, snapshotName(heights[0])))
+		if err != nil {
+			return errors.Wrap(err, "removing old snapshot")
+		}
+		heights = heights[1:]
+	}
+	return nil
+}
+
+// LatestSnapshot returns the most recent state snapshot
+// and its block height.
+func (s *Store) LatestSnapshot(ctx context.Context) (*state.Snapshot, uint64, error) {
+	heights, err := s.snapshotHeights()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(heights) == 0 {
+		return state.Empty(), 0, nil
+	}
+	height := heights[len(heights)-1]
+	data, err := s.GetSnapshot(ctx, height)
+	if err != nil {
+		return nil, height, err
+	}
+	snapshot, err := snapshotenc.Decode(data, nil)
+	if err != nil {
+		return nil, height, errors.Wrap(err, "decoding snapshot")
+	}
+	return snapshot, height, nil
+}
+
+// LatestSnapshotInfo returns the height and size of the
+// most recent state snapshot.
+func (s *Store) LatestSnapshotInfo(ctx context.Context) (height uint64, size uint64, err error) {
+	heights, err := s.snapshotHeights()
+	if err != nil || len(heights) == 0 {
+		return 0, 0, err
+	}
+	height = heights[len(heights)-1]
+	info, err := os.Stat(filepath.Join(s.dir, snapshotDir, snapshotName(height)))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "reading snapshot size")
+	}
+	return height, uint64(info.Size()), nil
+}
+
+// GetSnapshot returns the state snapshot stored at the provided
+// height, in Chain Core's binary protobuf representation.
+func (s *Store) GetSnapshot(ctx context.Context, height uint64) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, snapshotDir, snapshotName(height)))
+	return data, errors.Wrapf(err, "reading snapshot %d", height)
+}
+
+// snapshotHeights returns the heights of the
+// stored snapshots, in increasing order.
+func (s *Store) snapshotHeights() ([]uint64, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(s.dir, snapshotDir))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing snapshots")
+	}
+	var heights []uint64
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		h, err := strconv.ParseUint(strings.TrimSuffix(name, snapshotExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		heights = append(heights, h)
+	}
+	sort.Sort(uint64s(heights))
+	return heights, nil
+}
+
+func snapshotName(height uint64) string {
+	return fmt.Sprintf("%020d%s", height, snapshotExt)
+}
+
+type uint64s []uint64
+
+func (a uint64s) Len() int           { return len(a) }
+func (a uint64s) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a uint64s) Less(i, j int) bool { return a[i] < a[j] }
+
+// syncDir syncs the directory dir, making
+// renames of files in it durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "opening directory")
+	}
+	defer d.Close()
+	return errors.Wrap(d.Sync(), "syncing directory")
+}
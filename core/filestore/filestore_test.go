@@ -0,0 +1,194 @@
+package filestore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+	"chain/protocol/state"
+	"chain/testutil"
+)
+
+func TestSaveBlocks(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	blocks := makeBlocks(t, 3)
+
+	s, err := Open(dir)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	for _, b := range blocks {
+		err = s.SaveBlock(ctx, b)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	// Saving the same block again is fine,
+	// but a different one is not.
+	err = s.SaveBlock(ctx, blocks[1])
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	other := *blocks[1]
+	other.TimestampMS++
+	err = s.SaveBlock(ctx, &other)
+	if errors.Root(err) != ErrConflictingBlock {
+		t.Errorf("saving conflicting block: got error %v, want %v", err, ErrConflictingBlock)
+	}
+	s.Close()
+
+	s = reopen(t, dir, 3)
+	_, err = s.GetBlock(ctx, 4)
+	if errors.Root(err) != ErrNoBlock {
+		t.Errorf("GetBlock(4) error = %v, want %v", err, ErrNoBlock)
+	}
+	got, err := s.GetBlock(ctx, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Hash() != blocks[1].Hash() || len(got.Transactions) != 1 {
+		t.Errorf("got block %s with %d txs, want %s with 1", got.Hash(), len(got.Transactions), blocks[1].Hash())
+	}
+	s.Close()
+}
+
+func TestRecover(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	blocks := makeBlocks(t, 3)
+
+	s, err := Open(dir)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	for _, b := range blocks[:2] {
+		err = s.SaveBlock(ctx, b)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	s.Close()
+
+	// Simulate a crash after writing part of a
+	// block record and losing the index.
+	f, err := os.OpenFile(filepath.Join(dir, logName), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 1})
+	f.Close()
+	err = os.Remove(filepath.Join(dir, indexName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s = reopen(t, dir, 2)
+	err = s.SaveBlock(ctx, blocks[2])
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	s.Close()
+
+	s = reopen(t, dir, 3)
+	got, err := s.GetBlock(ctx, 3)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Hash() != blocks[2].Hash() {
+		t.Errorf("got block %s, want %s", got.Hash(), blocks[2].Hash())
+	}
+	s.Close()
+}
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s, err := Open(dir)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	defer s.Close()
+
+	snapshot, height, err := s.LatestSnapshot(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if height != 0 || snapshot.Tree.RootHash() != (bc.Hash{}) {
+		t.Errorf("got snapshot at height %d, want empty snapshot", height)
+	}
+
+	snapshot = state.Empty()
+	for h := uint64(1); h <= 4; h++ {
+		snapshot.Tree.Insert([]byte{byte(h)})
+		snapshot.Issuances[bc.Hash{byte(h)}] = h
+		err = s.SaveSnapshot(ctx, h, snapshot)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	got, height, err := s.LatestSnapshot(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if height != 4 || got.Tree.RootHash() != snapshot.Tree.RootHash() || len(got.Issuances) != 4 {
+		t.Errorf("got snapshot at height %d with root %s, want height 4 root %s", height, got.Tree.RootHash(), snapshot.Tree.RootHash())
+	}
+
+	heights, err := s.snapshotHeights()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(heights) != snapshotsKept {
+		t.Errorf("got %d snapshots, want %d", len(heights), snapshotsKept)
+	}
+}
+
+func makeBlocks(t *testing.T, n uint64) []*bc.Block {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	for c.Height() < n {
+		prottest.MakeBlock(t, c, []*bc.Tx{prottest.NewIssuanceTx(t, c)})
+	}
+	var blocks []*bc.Block
+	for h := uint64(1); h <= n; h++ {
+		b, err := c.GetBlock(ctx, h)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func reopen(t *testing.T, dir string, wantHeight uint64) *Store {
+	s, err := Open(dir)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	height, err := s.Height(context.Background())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if height != wantHeight {
+		t.Fatalf("reopened store at height %d, want %d", height, wantHeight)
+	}
+	return s
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -0,0 +1,3 @@
+package filestore
+
+import _ "chain/protocol/tx" // for BlockHeaderHashFunc init
@@ -1,12 +1,11 @@
 package generator
 
 import (
+	"bytes"
 	"context"
-	"fmt"
 	"sync"
 	"time"
 
-	"chain/crypto/ed25519"
 	"chain/database/pg"
 	"chain/database/sql"
 	"chain/errors"
@@ -14,7 +13,7 @@ import (
 	"chain/metrics"
 	"chain/protocol/bc"
 	"chain/protocol/state"
-	"chain/protocol/vmutil"
+	"chain/protocol/vm"
 )
 
 // errTooFewSigners is returned when a block-signing attempt finds
@@ -43,24 +42,59 @@ func (g *Generator) makeBlock(ctx context.Context) error {
 	t0 := time.Now()
 	defer recordSince(t0)
 
+	// Take the current pool, leaving poolHashes intact so
+	// that txs being considered for this block still count
+	// as pending. Txs that don't make it into the block and
+	// aren't rejected are returned to the pool by requeue.
+	// Expired txs are dropped first.
+	now := time.Now()
 	g.mu.Lock()
+	evicted := g.evictExpired(bc.Millis(now))
 	txs := g.pool
 	g.pool = nil
-	g.poolHashes = make(map[bc.Hash]bool)
 	g.mu.Unlock()
 
-	b, s, err := g.chain.GenerateBlock(ctx, g.latestBlock, g.latestSnapshot, time.Now(), txs)
+	for _, tx := range evicted {
+		g.reject(ctx, tx, errTxExpired)
+	}
+
+	reject := func(tx *bc.Tx, err error) { g.reject(ctx, tx, err) }
+	b, s, err := g.chain.GenerateBlockWithRejects(ctx, g.latestBlock, g.latestSnapshot, now, txs, reject)
 	if err != nil {
+		g.requeue(txs, nil)
 		return errors.Wrap(err, "generate")
 	}
-	if len(b.Transactions) == 0 {
+	g.requeue(txs, b)
+	rotate := len(g.NextConsensusProgram) > 0 && !bytes.Equal(b.ConsensusProgram, g.NextConsensusProgram)
+	if len(b.Transactions) == 0 && !g.heartbeatDue(now) && !rotate {
 		return nil // don't bother making an empty block
 	}
+	if rotate {
+		b.ConsensusProgram = g.NextConsensusProgram
+	}
 	err = savePendingBlock(ctx, g.db, b)
+	if err == nil {
+		err = g.commitBlock(ctx, b, s)
+	}
 	if err != nil {
+		// Try again with these txs in the next block.
+		g.requeue(b.Transactions, nil)
 		return err
 	}
-	return g.commitBlock(ctx, b, s)
+	g.forget(b.Transactions)
+	if rotate && g.Rotated != nil {
+		g.Rotated(ctx, b.ConsensusProgram)
+	}
+	return nil
+}
+
+// heartbeatDue returns whether the generator should make
+// a block at time now even if it would be empty.
+func (g *Generator) heartbeatDue(now time.Time) bool {
+	if g.HeartbeatPeriod <= 0 || g.latestBlock == nil {
+		return false
+	}
+	return now.Sub(g.latestBlock.Time()) >= g.HeartbeatPeriod
 }
 
 func (g *Generator) commitBlock(ctx context.Context, b *bc.Block, s *state.Snapshot) error {
@@ -74,6 +108,13 @@ func (g *Generator) commitBlock(ctx context.Context, b *bc.Block, s *state.Snaps
 		return errors.Wrap(err, "commit")
 	}
 
+	err = deletePoolTxs(ctx, g.db, b.Transactions...)
+	if err != nil {
+		// The txs will be rejected as duplicates if
+		// they're reloaded by a future leader.
+		log.Error(ctx, err)
+	}
+
 	g.latestBlock = b
 	g.latestSnapshot = s
 	return nil
@@ -84,55 +125,70 @@ func (g *Generator) getAndAddBlockSignatures(ctx context.Context, b, prevBlock *
 		return nil // no signatures needed for initial block
 	}
 
-	pubkeys, quorum, err := vmutil.ParseBlockMultiSigProgram(prevBlock.ConsensusProgram)
+	w, err := g.newWitness(prevBlock.ConsensusProgram, b)
 	if err != nil {
 		return errors.Wrap(err, "parsing prevblock output script")
 	}
-	if len(g.signers) < quorum {
-		return errTooFewSigners
+	witness, err := w.Arguments()
+	if err != nil {
+		// The program needs signatures.
+		witness, err = g.collectSignatures(ctx, w, b)
+		if err != nil {
+			return err
+		}
 	}
+	b.Witness = witness
+
+	// Make sure the strategy did its job; a block
+	// that doesn't satisfy the program can't be
+	// committed.
+	err = vm.VerifyBlockHeader(&prevBlock.BlockHeader, b)
+	return errors.Wrap(err, "verifying block witness")
+}
 
-	hashForSig := b.Hash()
+// collectSignatures asks all the block signers to sign b
+// and adds their signatures to w until it's complete.
+func (g *Generator) collectSignatures(ctx context.Context, w Witness, b *bc.Block) ([][]byte, error) {
+	if len(g.signers) < w.Needed() {
+		return nil, errTooFewSigners
+	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	goodSigs := make([][]byte, len(pubkeys))
 	replies := make([][]byte, len(g.signers))
 	done := make(chan int, len(g.signers))
 	for i, signer := range g.signers {
 		go getSig(ctx, signer, b, &replies[i], i, done)
 	}
 
-	nready := 0
-	for i := 0; i < len(g.signers) && nready < quorum; i++ {
+	for i := 0; i < len(g.signers); i++ {
 		sig := replies[<-done]
 		if sig == nil {
 			continue
 		}
-		k := indexKey(pubkeys, hashForSig[:], sig)
-		if k >= 0 && goodSigs[k] == nil {
-			goodSigs[k] = sig
-			nready++
-		} else if k < 0 {
-			log.Write(ctx, "error", "invalid signature", "block", b.Hash(), "signature", sig)
+		complete, err := w.Add(sig)
+		if err != nil {
+			log.Write(ctx, "error", err, "block", b.Hash(), "signature", sig)
+		}
+		if complete {
+			break
 		}
 	}
-
-	if nready < quorum {
-		return fmt.Errorf("got %d of %d needed signatures", nready, quorum)
-	}
-	b.Witness = nonNilSigs(goodSigs)
-	return nil
+	return w.Arguments()
 }
 
-func indexKey(keys []ed25519.PublicKey, msg, sig []byte) int {
-	for i, key := range keys {
-		if ed25519.Verify(key, msg, sig) {
-			return i
+// newWitness returns a Witness for b from the first of the
+// generator's strategies to support prog.
+func (g *Generator) newWitness(prog []byte, b *bc.Block) (Witness, error) {
+	for _, s := range g.WitnessStrategies {
+		w, err := s.NewWitness(prog, b)
+		if errors.Root(err) == ErrUnsupportedProgram {
+			continue
 		}
+		return w, err
 	}
-	return -1
+	return MultiSigStrategy{}.NewWitness(prog, b)
 }
 
 func getSig(ctx context.Context, signer BlockSigner, b *bc.Block, sig *[]byte, i int, done chan int) {
@@ -144,15 +200,6 @@ func getSig(ctx context.Context, signer BlockSigner, b *bc.Block, sig *[]byte, i
 	done <- i
 }
 
-func nonNilSigs(a [][]byte) (b [][]byte) {
-	for _, p := range a {
-		if p != nil {
-			b = append(b, p)
-		}
-	}
-	return b
-}
-
 // getPendingBlock retrieves the generated, uncommitted block if it exists.
 func getPendingBlock(ctx context.Context, db pg.DB) (*bc.Block, error) {
 	const q = `SELECT data FROM generator_pending_block`
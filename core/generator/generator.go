@@ -9,7 +9,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/golang/groupcache/lru"
+
 	"chain/database/pg"
+	"chain/errors"
 	"chain/log"
 	"chain/protocol"
 	"chain/protocol/bc"
@@ -17,6 +20,49 @@ import (
 	"chain/protocol/validation"
 )
 
+// maxRejectedTxs is the number of rejected transactions
+// for which the generator remembers the reason.
+const maxRejectedTxs = 10000
+
+// maxCommittedTxs is the number of recently committed
+// transactions the generator remembers, so it can accept
+// them again from participants that are behind.
+const maxCommittedTxs = 10000
+
+var (
+	// maxPoolTxs is the maximum number of pending txs.
+	maxPoolTxs = 100000
+
+	// maxSubmitterTxs is the maximum number of pending txs
+	// from a single submitter. See NewSubmitterContext.
+	maxSubmitterTxs = 10000
+)
+
+// Errors returned by Submit when it refuses to admit a tx to the
+// pool. Txs that fail validation are refused with an error whose
+// root is validation.ErrBadTx.
+var (
+	ErrPoolFull       = errors.New("pending tx pool is full")
+	ErrTooManyPending = errors.New("too many pending txs from submitter")
+	ErrPoolConflict   = errors.New("tx conflicts with a pending tx")
+
+	errTxExpired = errors.WithDetail(validation.ErrBadTx, "transaction max time has passed")
+)
+
+// Values of TxStatus.Status.
+const (
+	TxUnknown  = "unknown"
+	TxPending  = "pending"
+	TxRejected = "rejected"
+)
+
+// TxStatus describes what the generator knows about
+// a submitted transaction.
+type TxStatus struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
 // A BlockSigner signs blocks.
 type BlockSigner interface {
 	// SignBlock returns an ed25519 signature over the block's sighash.
@@ -28,6 +74,27 @@ type BlockSigner interface {
 // Generator collects pending transactions and produces new blocks on
 // an interval.
 type Generator struct {
+	// HeartbeatPeriod, if nonzero, is the longest the generator
+	// waits between blocks. When no txs are pending for that
+	// long, it makes an empty block.
+	HeartbeatPeriod time.Duration
+
+	// NextConsensusProgram, if set, replaces the consensus
+	// program of the latest block in the next block the
+	// generator makes. Blocks need signatures from enough
+	// of the signers in the old program to switch.
+	NextConsensusProgram []byte
+
+	// Rotated, if set, is called after the generator commits
+	// a block that switches to NextConsensusProgram. It runs
+	// in the Generate goroutine, so it may call SetSigners.
+	Rotated func(ctx context.Context, prog []byte)
+
+	// WitnessStrategies build the witnesses of blocks whose
+	// consensus programs aren't simple multisig programs.
+	// They're tried in order before MultiSigStrategy.
+	WitnessStrategies []WitnessStrategy
+
 	// config
 	db      pg.DB
 	chain   *protocol.Chain
@@ -36,6 +103,14 @@ type Generator struct {
 	mu         sync.Mutex
 	pool       []*bc.Tx // in topological order
 	poolHashes map[bc.Hash]bool
+	rejected   *lru.Cache // tx ID -> error
+	committed  *lru.Cache // tx ID -> struct{}
+
+	// Indexes of the txs in poolHashes, for admission control.
+	poolSpends   map[bc.Hash]bc.Hash // spent output ID -> tx ID
+	poolOutputs  map[bc.Hash]bool
+	txSubmitters map[bc.Hash]string
+	submitterTxs map[string]int
 
 	// latestBlock and latestSnapshot are current as long as this
 	// process remains the leader process. If the process is demoted,
@@ -56,9 +131,39 @@ func New(
 		chain:      c,
 		signers:    s,
 		poolHashes: make(map[bc.Hash]bool),
+		rejected:   lru.New(maxRejectedTxs),
+		committed:  lru.New(maxCommittedTxs),
+
+		poolSpends:   make(map[bc.Hash]bc.Hash),
+		poolOutputs:  make(map[bc.Hash]bool),
+		txSubmitters: make(map[bc.Hash]string),
+		submitterTxs: make(map[string]int),
 	}
 }
 
+// SetSigners replaces the signers the generator asks to sign
+// blocks. It must not be called while Generate is running.
+func (g *Generator) SetSigners(s []BlockSigner) {
+	g.signers = s
+}
+
+type submitterKey struct{}
+
+// NewSubmitterContext returns a context identifying the submitter
+// of any tx passed to Submit with it. Each submitter may have
+// only a limited number of txs pending at once. The API sets
+// the submitter to the authenticated caller of each request.
+// Txs submitted without an identified submitter count toward
+// a shared limit.
+func NewSubmitterContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, submitterKey{}, id)
+}
+
+func submitterFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(submitterKey{}).(string)
+	return id
+}
+
 // PendingTxs returns all of the pendings txs that will be
 // included in the generator's next block.
 func (g *Generator) PendingTxs() []*bc.Tx {
@@ -71,19 +176,260 @@ func (g *Generator) PendingTxs() []*bc.Tx {
 }
 
 // Submit adds a new pending tx to the pending tx pool.
+// The tx is persisted so that it remains in the pool
+// if another process becomes the leader.
+//
+// Submit refuses txs that are invalid, that spend outputs
+// already spent by pending txs or absent from the current
+// state, or that would exceed the pool's limits. It accepts
+// a tx that's already pending or committed, doing nothing.
 func (g *Generator) Submit(ctx context.Context, tx *bc.Tx) error {
+	err := g.chain.ValidateTxCached(tx)
+	if err != nil {
+		g.reject(ctx, tx, err)
+		return err
+	}
+
+	g.mu.Lock()
+	if g.poolHashes[tx.ID] || g.isCommitted(tx) {
+		g.mu.Unlock()
+		return nil
+	}
+	evicted, err := g.admit(tx, submitterFromContext(ctx))
+	g.mu.Unlock()
+
+	for _, e := range evicted {
+		g.reject(ctx, e, errTxExpired)
+	}
+	// A tx whose spent outputs are absent from the state isn't
+	// recorded as rejected: it may be a committed tx, no longer
+	// recognizable as such, that spent those outputs itself.
+	if errors.Root(err) == ErrPoolConflict {
+		g.reject(ctx, tx, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = savePoolTx(ctx, g.db, tx)
+
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	if g.poolHashes[tx.ID] {
-		return nil
+	if err != nil {
+		g.remove(tx)
+		return err
 	}
 
-	g.poolHashes[tx.ID] = true
+	// Give a previously rejected tx another chance;
+	// the state it conflicted with might have changed.
+	g.rejected.Remove(tx.ID)
+
 	g.pool = append(g.pool, tx)
 	return nil
 }
 
+// admit checks whether tx may join the pool and if so, records it
+// in poolHashes and the admission indexes. The caller must add it
+// to the pool itself. If the pool is full, admit first evicts
+// expired txs, and returns them so they can be rejected.
+//
+// The caller must hold g.mu.
+func (g *Generator) admit(tx *bc.Tx, submitter string) (evicted []*bc.Tx, err error) {
+	now := bc.Millis(time.Now())
+	if tx.MaxTime > 0 && tx.MaxTime < now {
+		return nil, errTxExpired
+	}
+
+	_, snapshot := g.chain.State()
+	for i, in := range tx.Inputs {
+		if _, ok := in.TypedInput.(*bc.SpendInput); !ok {
+			continue
+		}
+		outputID := in.SpentOutputID()
+		if other, ok := g.poolSpends[outputID]; ok {
+			return nil, errors.WithDetailf(ErrPoolConflict, "output %s for input %d is spent by pending tx %s", outputID, i, other)
+		}
+		if !g.poolOutputs[outputID] && snapshot != nil && !snapshot.Tree.Contains(outputID.Bytes()) {
+			return nil, errors.WithDetailf(validation.ErrBadTx, "output %s for input %d is invalid", outputID, i)
+		}
+	}
+
+	if len(g.poolHashes) >= maxPoolTxs {
+		evicted = g.evictExpired(now)
+		if len(g.poolHashes) >= maxPoolTxs {
+			return evicted, ErrPoolFull
+		}
+	}
+	if g.submitterTxs[submitter] >= maxSubmitterTxs {
+		return evicted, errors.WithDetailf(ErrTooManyPending, "limit is %d", maxSubmitterTxs)
+	}
+
+	g.track(tx, submitter)
+	return evicted, nil
+}
+
+// track records tx in poolHashes and the admission indexes.
+//
+// The caller must hold g.mu.
+func (g *Generator) track(tx *bc.Tx, submitter string) {
+	g.poolHashes[tx.ID] = true
+	for _, in := range tx.Inputs {
+		if _, ok := in.TypedInput.(*bc.SpendInput); ok {
+			g.poolSpends[in.SpentOutputID()] = tx.ID
+		}
+	}
+	for i := range tx.Outputs {
+		g.poolOutputs[tx.OutputID(uint32(i))] = true
+	}
+	g.txSubmitters[tx.ID] = submitter
+	g.submitterTxs[submitter]++
+}
+
+// evictExpired removes txs from the pool whose max time
+// is before nowMS, and returns them.
+//
+// The caller must hold g.mu.
+func (g *Generator) evictExpired(nowMS uint64) (evicted []*bc.Tx) {
+	var remaining []*bc.Tx
+	for _, tx := range g.pool {
+		if tx.MaxTime > 0 && tx.MaxTime < nowMS {
+			g.remove(tx)
+			evicted = append(evicted, tx)
+			continue
+		}
+		remaining = append(remaining, tx)
+	}
+	g.pool = remaining
+	return evicted
+}
+
+// remove deletes tx from poolHashes and the admission indexes.
+// It does not remove tx from the pool.
+//
+// The caller must hold g.mu.
+func (g *Generator) remove(tx *bc.Tx) {
+	if !g.poolHashes[tx.ID] {
+		return
+	}
+	delete(g.poolHashes, tx.ID)
+	for _, in := range tx.Inputs {
+		if _, ok := in.TypedInput.(*bc.SpendInput); ok && g.poolSpends[in.SpentOutputID()] == tx.ID {
+			delete(g.poolSpends, in.SpentOutputID())
+		}
+	}
+	for i := range tx.Outputs {
+		delete(g.poolOutputs, tx.OutputID(uint32(i)))
+	}
+	submitter := g.txSubmitters[tx.ID]
+	delete(g.txSubmitters, tx.ID)
+	g.submitterTxs[submitter]--
+	if g.submitterTxs[submitter] <= 0 {
+		delete(g.submitterTxs, submitter)
+	}
+}
+
+// TxStatus reports whether the transaction with the given ID
+// is waiting in the pending pool or was recently rejected
+// from a block, and if so, why.
+func (g *Generator) TxStatus(ctx context.Context, txID bc.Hash) (*TxStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.poolHashes[txID] {
+		return &TxStatus{Status: TxPending}, nil
+	}
+	if v, ok := g.rejected.Get(txID); ok {
+		return &TxStatus{Status: TxRejected, Reason: rejectionReason(v.(error))}, nil
+	}
+	return &TxStatus{Status: TxUnknown}, nil
+}
+
+func (g *Generator) reject(ctx context.Context, tx *bc.Tx, err error) {
+	log.Write(ctx, "message", "rejected transaction", "tx", tx.ID, "reason", rejectionReason(err))
+
+	// If this fails, the tx will be reloaded and rejected
+	// again by the next leader, so there's no need to retry.
+	if delErr := deletePoolTxs(ctx, g.db, tx); delErr != nil {
+		log.Error(ctx, delErr)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rejected.Add(tx.ID, err)
+}
+
+// requeue forgets txs that were rejected and returns those that
+// weren't included in b to the front of the pool so they're
+// considered again for the next block. Txs included in b are
+// still reported as pending until forget is called for them,
+// once b has been committed or abandoned.
+func (g *Generator) requeue(txs []*bc.Tx, b *bc.Block) {
+	included := make(map[bc.Hash]bool)
+	if b != nil {
+		for _, tx := range b.Transactions {
+			included[tx.ID] = true
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var remaining []*bc.Tx
+	for _, tx := range txs {
+		if _, ok := g.rejected.Get(tx.ID); ok {
+			g.remove(tx)
+			continue
+		}
+		if !included[tx.ID] {
+			remaining = append(remaining, tx)
+		}
+	}
+	g.pool = append(remaining, g.pool...)
+}
+
+// forget removes txs, which have been committed,
+// from the set of pending tx hashes.
+func (g *Generator) forget(txs []*bc.Tx) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, tx := range txs {
+		g.remove(tx)
+		g.committed.Add(tx.ID, struct{}{})
+	}
+}
+
+// isCommitted reports whether tx is already in the blockchain.
+// A participant that's behind may submit it again, and it then
+// fails the state check in admit against the outputs it spent
+// itself. Besides the recently committed txs, it checks whether
+// any of tx's outputs are in the current state.
+//
+// The caller must hold g.mu.
+func (g *Generator) isCommitted(tx *bc.Tx) bool {
+	if _, ok := g.committed.Get(tx.ID); ok {
+		return true
+	}
+	_, snapshot := g.chain.State()
+	if snapshot == nil {
+		return false
+	}
+	for i := range tx.Outputs {
+		id := tx.OutputID(uint32(i))
+		if snapshot.Tree.Contains(id.Bytes()) {
+			return true
+		}
+	}
+	return false
+}
+
+func rejectionReason(err error) string {
+	if d := errors.Detail(err); d != "" {
+		return d
+	}
+	return err.Error()
+}
+
 // Generate runs in a loop, making one new block
 // every block period. It returns when its context
 // is canceled.
@@ -119,6 +465,23 @@ func (g *Generator) Generate(
 		}
 	}
 
+	// Restore the pending txs submitted to previous leaders.
+	txs, err := loadPool(ctx, g.db, bc.Millis(time.Now()))
+	if err != nil {
+		log.Fatal(ctx, log.KeyError, err)
+	}
+	g.mu.Lock()
+	for _, tx := range txs {
+		if g.poolHashes[tx.ID] {
+			continue
+		}
+		// Reloaded txs were admitted once already; the
+		// submitter isn't persisted, so they share a limit.
+		g.track(tx, "")
+		g.pool = append(g.pool, tx)
+	}
+	g.mu.Unlock()
+
 	ticks := time.Tick(period)
 	for {
 		select {
@@ -1,18 +1,25 @@
 package generator
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"testing"
 	"time"
 
 	"chain/crypto/ed25519"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/prottest"
 	"chain/protocol/state"
+	"chain/protocol/validation"
 	"chain/protocol/vm"
+	"chain/protocol/vmutil"
 	"chain/testutil"
+
+	"github.com/golang/groupcache/lru"
 )
 
 func TestGeneratorRecovery(t *testing.T) {
@@ -107,6 +114,457 @@ func TestGetAndAddBlockSignaturesInitialBlock(t *testing.T) {
 	}
 }
 
+func TestWitnessStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	var signers []BlockSigner
+	var progs [][]byte
+	for i := 0; i < 2; i++ {
+		pubKey, privKey, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		signers = append(signers, testSigner{pubKey, privKey})
+		prog, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{pubKey}, 1)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		progs = append(progs, prog)
+	}
+
+	// Require both multisig programs to succeed.
+	prog := append(append(progs[0], byte(vm.OP_VERIFY)), progs[1]...)
+	prev, err := protocol.NewInitialBlockWithProgram(prog, time.Now())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	b := &bc.Block{BlockHeader: bc.BlockHeader{Height: 2, TimestampMS: prev.TimestampMS + 1}}
+
+	g := New(nil, signers, nil)
+	err = g.getAndAddBlockSignatures(ctx, b, prev)
+	if errors.Root(err) != ErrUnsupportedProgram {
+		t.Fatalf("got error %v, want %v", err, ErrUnsupportedProgram)
+	}
+
+	g.WitnessStrategies, err = StrategiesByName([]string{"conjunction"})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Both groups must sign.
+	g.signers = signers[:1]
+	err = g.getAndAddBlockSignatures(ctx, b, prev)
+	if err == nil {
+		t.Fatal("expected error with signatures from one group")
+	}
+
+	g.signers = signers
+	err = g.getAndAddBlockSignatures(ctx, b, prev)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(b.Witness) != 2 {
+		t.Errorf("got %d witness args, want 2", len(b.Witness))
+	}
+
+	_, err = StrategiesByName([]string{"multisig", "bogus"})
+	if errors.Root(err) != ErrUnknownStrategy {
+		t.Errorf("StrategiesByName(bogus) = %v, want %v", err, ErrUnknownStrategy)
+	}
+}
+
+func TestPoolRecovery(t *testing.T) {
+	dbtx := pgtest.NewTx(t)
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	b, s := c.State()
+
+	// Submit txs to one generator, then start another
+	// to simulate a change of leader.
+	tx1 := prottest.NewIssuanceTx(t, c)
+	tx2 := prottest.NewIssuanceTx(t, c)
+	expired := bc.NewTx(bc.TxData{
+		Version: 1,
+		MaxTime: bc.Millis(time.Now().Add(-time.Minute)),
+	})
+	g := New(c, nil, dbtx)
+	for _, tx := range []*bc.Tx{tx1, tx2} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	// Submit would refuse an expired tx.
+	err := savePoolTx(ctx, dbtx, expired)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g = New(c, nil, dbtx)
+	go g.Generate(ctx, time.Hour, func(error) {}, b, s)
+
+	// Wait until the pool is reloaded.
+	for i := 0; i < 100 && len(g.PendingTxs()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := g.PendingTxs()
+	if len(got) != 2 || got[0].ID != tx1.ID || got[1].ID != tx2.ID {
+		t.Errorf("PendingTxs() = %v, want [%s %s]", got, tx1.ID, tx2.ID)
+	}
+}
+
+func TestTxStatus(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+
+	// An issuance in the pool that's rejected as a duplicate
+	// at block time. (Submit would accept it without adding
+	// it to the pool, since it's already committed.)
+	dup := prottest.NewIssuanceTx(t, c)
+	prottest.MakeBlock(t, c, []*bc.Tx{dup})
+	g.latestBlock, g.latestSnapshot = c.State()
+	g.mu.Lock()
+	g.track(dup, "")
+	g.pool = append(g.pool, dup)
+	g.mu.Unlock()
+	err := g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	pending := prottest.NewIssuanceTx(t, c)
+	err = g.Submit(ctx, pending)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	cases := []struct {
+		id   bc.Hash
+		want string
+	}{
+		{dup.ID, TxRejected},
+		{pending.ID, TxPending},
+		{bc.Hash{3}, TxUnknown},
+	}
+	for _, c := range cases {
+		got, err := g.TxStatus(ctx, c.id)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.Status != c.want {
+			t.Errorf("TxStatus(%s) = %s, want %s", c.id, got.Status, c.want)
+		}
+	}
+	if got := g.PendingTxs(); len(got) != 1 || got[0].ID != pending.ID {
+		t.Errorf("PendingTxs() = %v, want [%s]", got, pending.ID)
+	}
+}
+
+func TestSubmitAdmission(t *testing.T) {
+	defer func(p, s int) { maxPoolTxs, maxSubmitterTxs = p, s }(maxPoolTxs, maxSubmitterTxs)
+
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+
+	issuance := newTrueIssuance(t, c)
+	spend1 := newTrueSpend(issuance, []byte("1"))
+	spend2 := newTrueSpend(issuance, []byte("2"))
+	missing := bc.NewTx(bc.TxData{
+		Version: 1,
+		Inputs: []*bc.TxInput{
+			bc.NewSpendInput(bc.Hash{1}, nil, bc.AssetID{}, 1, []byte{byte(vm.OP_TRUE)}, nil),
+		},
+		Outputs: []*bc.TxOutput{
+			bc.NewTxOutput(bc.AssetID{}, 1, []byte{byte(vm.OP_TRUE)}, nil),
+		},
+	})
+
+	maxSubmitterTxs = 2
+	maxPoolTxs = 3
+	other := NewSubmitterContext(ctx, "other")
+	cases := []struct {
+		ctx  context.Context
+		tx   *bc.Tx
+		want error
+	}{
+		{ctx, issuance, nil},
+		{ctx, spend1, nil}, // spends a pending tx's output
+		{ctx, spend2, ErrPoolConflict},
+		{ctx, missing, validation.ErrBadTx},
+		{ctx, newTrueIssuance(t, c), ErrTooManyPending},
+		{other, newTrueIssuance(t, c), nil},
+		{other, newTrueIssuance(t, c), ErrPoolFull},
+	}
+	for i, c := range cases {
+		err := g.Submit(c.ctx, c.tx)
+		if errors.Root(err) != c.want {
+			t.Errorf("case %d: Submit() = %v, want %v", i, err, c.want)
+		}
+	}
+
+	got, err := g.TxStatus(ctx, spend2.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Status != TxRejected {
+		t.Errorf("TxStatus(conflicting tx) = %s, want %s", got.Status, TxRejected)
+	}
+}
+
+func TestResubmitCommitted(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+	g.latestBlock, g.latestSnapshot = c.State()
+
+	issuance := newTrueIssuance(t, c)
+	spend := newTrueSpend(issuance, nil)
+	for _, tx := range []*bc.Tx{issuance, spend} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		err = g.makeBlock(ctx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	// A participant that's behind submits spend again. It's
+	// accepted, first because it was recently committed, then,
+	// once that's forgotten, because its output is in the state.
+	for i := 0; i < 2; i++ {
+		err := g.Submit(ctx, spend)
+		if err != nil {
+			t.Errorf("try %d: Submit(committed tx) = %v, want nil", i, err)
+		}
+		got, err := g.TxStatus(ctx, spend.ID)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.Status != TxUnknown {
+			t.Errorf("try %d: TxStatus(committed tx) = %s, want %s", i, got.Status, TxUnknown)
+		}
+		if n := len(g.PendingTxs()); n != 0 {
+			t.Errorf("try %d: %d pending txs, want 0", i, n)
+		}
+		g.committed = lru.New(maxCommittedTxs)
+	}
+}
+
+func TestMakeBlockEvictsExpired(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+	g.latestBlock, g.latestSnapshot = c.State()
+
+	// A tx that expired while waiting in the pool.
+	data := newTrueIssuance(t, c).TxData
+	data.MaxTime = bc.Millis(time.Now().Add(-time.Second))
+	expired := bc.NewTx(data)
+	g.mu.Lock()
+	g.track(expired, "sub")
+	g.pool = append(g.pool, expired)
+	g.mu.Unlock()
+
+	err := g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := g.TxStatus(ctx, expired.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Status != TxRejected {
+		t.Errorf("TxStatus(expired tx) = %s, want %s", got.Status, TxRejected)
+	}
+	if n := g.submitterTxs["sub"]; n != 0 {
+		t.Errorf("submitter has %d pending txs, want 0", n)
+	}
+}
+
+// newTrueIssuance returns a tx issuing a new asset
+// to the control program OP_TRUE.
+func newTrueIssuance(tb testing.TB, c *protocol.Chain) *bc.Tx {
+	b1, err := c.GetBlock(context.Background(), 1)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	var nonce [8]byte
+	_, err = rand.Read(nonce[:])
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	prog := []byte{byte(vm.OP_TRUE)}
+	in := bc.NewIssuanceInput(nonce[:], 10, nil, b1.Hash(), prog, nil, nil)
+	return bc.NewTx(bc.TxData{
+		Version: 1,
+		MinTime: bc.Millis(time.Now().Add(-time.Minute)),
+		MaxTime: bc.Millis(time.Now().Add(time.Minute)),
+		Inputs:  []*bc.TxInput{in},
+		Outputs: []*bc.TxOutput{bc.NewTxOutput(in.AssetID(), 10, prog, nil)},
+	})
+}
+
+// newTrueSpend returns a tx spending the first output of
+// a tx made by newTrueIssuance.
+func newTrueSpend(prev *bc.Tx, refData []byte) *bc.Tx {
+	out := prev.Outputs[0]
+	return bc.NewTx(bc.TxData{
+		Version:       1,
+		Inputs:        []*bc.TxInput{bc.NewSpendInput(prev.OutputID(0), nil, out.AssetID, out.Amount, out.ControlProgram, nil)},
+		Outputs:       []*bc.TxOutput{bc.NewTxOutput(out.AssetID, out.Amount, out.ControlProgram, nil)},
+		ReferenceData: refData,
+	})
+}
+
+func TestHeartbeatDue(t *testing.T) {
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+	g.latestBlock, g.latestSnapshot = c.State()
+	last := g.latestBlock.Time()
+
+	cases := []struct {
+		heartbeat time.Duration
+		now       time.Time
+		want      bool
+	}{
+		{0, last.Add(time.Hour), false},
+		{time.Minute, last.Add(time.Second), false},
+		{time.Minute, last.Add(time.Minute), true},
+	}
+	for _, c := range cases {
+		g.HeartbeatPeriod = c.heartbeat
+		if got := g.heartbeatDue(c.now); got != c.want {
+			t.Errorf("heartbeatDue(%s) with period %s = %t, want %t", c.now.Sub(last), c.heartbeat, got, c.want)
+		}
+	}
+}
+
+func TestRotateConsensusProgram(t *testing.T) {
+	ctx := context.Background()
+	dbtx := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	g := New(c, nil, dbtx)
+	g.latestBlock, g.latestSnapshot = c.State()
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	prog, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{pubKey}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// The generator makes a block switching to the new
+	// program even if there are no txs.
+	var rotated [][]byte
+	g.NextConsensusProgram = prog
+	g.Rotated = func(ctx context.Context, prog []byte) { rotated = append(rotated, prog) }
+	err = g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	b2 := g.latestBlock
+	if b2.Height != 2 || !bytes.Equal(b2.ConsensusProgram, prog) {
+		t.Fatalf("got block %d with program %x, want block 2 with program %x", b2.Height, b2.ConsensusProgram, prog)
+	}
+	if len(rotated) != 1 || !bytes.Equal(rotated[0], prog) {
+		t.Errorf("Rotated called with %x, want once with %x", rotated, prog)
+	}
+
+	// Once it has switched, it doesn't make empty blocks.
+	err = g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if g.latestBlock.Height != 2 {
+		t.Fatalf("got height %d, want 2", g.latestBlock.Height)
+	}
+
+	// Later blocks need signatures from the new signer.
+	g.HeartbeatPeriod = time.Nanosecond
+	err = g.makeBlock(ctx)
+	if errors.Root(err) != errTooFewSigners {
+		t.Fatalf("got error %v, want %v", err, errTooFewSigners)
+	}
+	g.SetSigners([]BlockSigner{testSigner{pubKey, privKey}})
+	err = g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = vm.VerifyBlockHeader(&b2.BlockHeader, g.latestBlock)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+}
+
+func TestMakeBlockCommitFails(t *testing.T) {
+	ctx := context.Background()
+	dbtx := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	g := New(c, nil, dbtx)
+	g.latestBlock, g.latestSnapshot = c.State()
+
+	// Switch to a program the generator can't sign for yet.
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	g.NextConsensusProgram, err = vmutil.BlockMultiSigProgram([]ed25519.PublicKey{pubKey}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	tx := prottest.NewIssuanceTx(t, c)
+	err = g.Submit(ctx, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = g.makeBlock(ctx)
+	if errors.Root(err) != errTooFewSigners {
+		t.Fatalf("got error %v, want %v", err, errTooFewSigners)
+	}
+
+	// The tx stays in the pool for the next block.
+	if got := g.PendingTxs(); len(got) != 1 || got[0].ID != tx.ID {
+		t.Fatalf("PendingTxs() = %v, want [%s]", got, tx.ID)
+	}
+	status, err := g.TxStatus(ctx, tx.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if status.Status != TxPending {
+		t.Fatalf("TxStatus() = %s, want %s", status.Status, TxPending)
+	}
+
+	g.SetSigners([]BlockSigner{testSigner{pubKey, privKey}})
+	err = g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(g.latestBlock.Transactions) != 1 || g.latestBlock.Transactions[0].ID != tx.ID {
+		t.Errorf("got block with txs %v, want [%s]", g.latestBlock.Transactions, tx.ID)
+	}
+	status, err = g.TxStatus(ctx, tx.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if status.Status != TxUnknown {
+		t.Errorf("TxStatus() after commit = %s, want %s", status.Status, TxUnknown)
+	}
+}
+
 type testSigner struct {
 	pubKey  ed25519.PublicKey
 	privKey ed25519.PrivateKey
@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// savePoolTx persists a pending tx so that it survives
+// a change of leader. The generator should save a tx
+// *before* adding it to the in-memory pool.
+func savePoolTx(ctx context.Context, db pg.DB, tx *bc.Tx) error {
+	const q = `
+		INSERT INTO generator_pool (tx_hash, data, max_time) VALUES($1, $2, $3)
+		ON CONFLICT (tx_hash) DO NOTHING
+	`
+	_, err := db.Exec(ctx, q, tx.ID[:], &tx.TxData, tx.MaxTime)
+	return errors.Wrap(err, "generator_pool insert query")
+}
+
+// deletePoolTxs removes txs that are no longer pending, because
+// they were either included in a block or rejected.
+func deletePoolTxs(ctx context.Context, db pg.DB, txs ...*bc.Tx) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	hashes := make(pq.ByteaArray, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, tx.ID.Bytes())
+	}
+	const q = `DELETE FROM generator_pool WHERE tx_hash = ANY($1)`
+	_, err := db.Exec(ctx, q, hashes)
+	return errors.Wrap(err, "generator_pool delete query")
+}
+
+// loadPool retrieves the persisted pending txs in the order
+// they were submitted. Txs whose max time is before nowMS
+// can never be included in a block, so they are deleted
+// instead.
+func loadPool(ctx context.Context, db pg.DB, nowMS uint64) ([]*bc.Tx, error) {
+	const expireQ = `DELETE FROM generator_pool WHERE max_time > 0 AND max_time < $1`
+	_, err := db.Exec(ctx, expireQ, nowMS)
+	if err != nil {
+		return nil, errors.Wrap(err, "expiring generator_pool txs")
+	}
+
+	const q = `SELECT data FROM generator_pool ORDER BY seq`
+	var txs []*bc.Tx
+	err = pg.ForQueryRows(ctx, db, q, func(data bc.TxData) {
+		txs = append(txs, bc.NewTx(data))
+	})
+	return txs, errors.Wrap(err, "generator_pool select query")
+}
@@ -0,0 +1,244 @@
+package generator
+
+import (
+	"fmt"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/vm"
+	"chain/protocol/vmutil"
+)
+
+var (
+	// ErrUnsupportedProgram is returned by a WitnessStrategy when
+	// asked for a witness for a consensus program it doesn't handle.
+	ErrUnsupportedProgram = errors.New("unsupported consensus program")
+
+	// ErrBadSignature is returned by a Witness when given a
+	// signature it can't use.
+	ErrBadSignature = errors.New("invalid signature")
+
+	// ErrUnknownStrategy is returned by StrategiesByName
+	// for a name it doesn't recognize.
+	ErrUnknownStrategy = errors.New("unknown witness strategy")
+)
+
+// strategies are the WitnessStrategies that
+// can be selected by name; see StrategiesByName.
+var strategies = map[string]WitnessStrategy{
+	"multisig":    MultiSigStrategy{},
+	"conjunction": ConjunctionStrategy{},
+}
+
+// StrategiesByName returns the WitnessStrategies with the
+// given names, for use in Generator.WitnessStrategies.
+// Known names are "multisig" and "conjunction".
+func StrategiesByName(names []string) ([]WitnessStrategy, error) {
+	var res []WitnessStrategy
+	for _, name := range names {
+		s, ok := strategies[name]
+		if !ok {
+			return nil, errors.WithDetailf(ErrUnknownStrategy, "strategy %q", name)
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// A WitnessStrategy builds block witnesses satisfying one kind
+// of consensus program.
+type WitnessStrategy interface {
+	// NewWitness returns a Witness for block b, which must
+	// satisfy prog, the consensus program of the previous block.
+	// If prog isn't of the kind the strategy handles, it returns
+	// ErrUnsupportedProgram.
+	NewWitness(prog []byte, b *bc.Block) (Witness, error)
+}
+
+// A Witness accumulates block signatures until it has
+// enough to satisfy a consensus program.
+type Witness interface {
+	// Add considers a signature over the block's hash from one
+	// of the generator's block signers. It reports whether the
+	// witness is now complete. If the signature can't be part
+	// of the witness, it returns ErrBadSignature.
+	Add(sig []byte) (complete bool, err error)
+
+	// Arguments returns the witness arguments for the block.
+	// It returns an error if the witness is incomplete.
+	Arguments() ([][]byte, error)
+
+	// Needed returns the least number of further signatures,
+	// from distinct signers, that could complete the witness.
+	Needed() int
+}
+
+// MultiSigStrategy is the default WitnessStrategy. It handles
+// programs made by vmutil.BlockMultiSigProgram.
+type MultiSigStrategy struct{}
+
+// NewWitness implements WitnessStrategy.
+func (MultiSigStrategy) NewWitness(prog []byte, b *bc.Block) (Witness, error) {
+	pubkeys, quorum, err := vmutil.ParseBlockMultiSigProgram(prog)
+	if err != nil {
+		return nil, errors.Sub(ErrUnsupportedProgram, err)
+	}
+	hash := b.Hash()
+	return NewMultiSigWitness(pubkeys, quorum, hash[:]), nil
+}
+
+// MultiSigWitness collects signatures for a block multisig
+// program. Strategies for programs built from multisig programs
+// can use it for each part.
+type MultiSigWitness struct {
+	pubkeys []ed25519.PublicKey
+	quorum  int
+	msg     []byte
+	sigs    [][]byte // indexed like pubkeys
+	n       int
+}
+
+// NewMultiSigWitness returns a witness requiring quorum
+// signatures of msg by keys in pubkeys.
+func NewMultiSigWitness(pubkeys []ed25519.PublicKey, quorum int, msg []byte) *MultiSigWitness {
+	return &MultiSigWitness{
+		pubkeys: pubkeys,
+		quorum:  quorum,
+		msg:     msg,
+		sigs:    make([][]byte, len(pubkeys)),
+	}
+}
+
+// Add implements Witness.
+func (w *MultiSigWitness) Add(sig []byte) (bool, error) {
+	k := indexKey(w.pubkeys, w.msg, sig)
+	if k < 0 {
+		return w.n >= w.quorum, errors.Wrap(ErrBadSignature)
+	}
+	if w.sigs[k] == nil && w.n < w.quorum {
+		w.sigs[k] = sig
+		w.n++
+	}
+	return w.n >= w.quorum, nil
+}
+
+// Arguments implements Witness. The signatures are
+// in the same order as their keys in the program.
+func (w *MultiSigWitness) Arguments() ([][]byte, error) {
+	if w.n < w.quorum {
+		return nil, fmt.Errorf("got %d of %d needed signatures", w.n, w.quorum)
+	}
+	return nonNilSigs(w.sigs), nil
+}
+
+// Needed implements Witness.
+func (w *MultiSigWitness) Needed() int {
+	return w.quorum - w.n
+}
+
+// ConjunctionStrategy handles programs made of block multisig
+// programs joined by VERIFY, all of which must succeed, such as
+// a program requiring signatures from two groups of signers.
+type ConjunctionStrategy struct{}
+
+// NewWitness implements WitnessStrategy.
+func (ConjunctionStrategy) NewWitness(prog []byte, b *bc.Block) (Witness, error) {
+	insts, err := vm.ParseProgram(prog)
+	if err != nil {
+		return nil, errors.Sub(ErrUnsupportedProgram, err)
+	}
+	var (
+		w     conjunctionWitness
+		start uint32
+		pc    uint32
+	)
+	for i, inst := range insts {
+		pc += inst.Len
+		if inst.Op != vm.OP_VERIFY && i < len(insts)-1 {
+			continue
+		}
+		end := pc
+		if inst.Op == vm.OP_VERIFY {
+			end -= inst.Len
+		}
+		part, err := MultiSigStrategy{}.NewWitness(prog[start:end], b)
+		if err != nil {
+			return nil, err
+		}
+		w = append(w, part)
+		start = pc
+	}
+	if len(w) < 2 {
+		return nil, errors.WithDetail(ErrUnsupportedProgram, "not a conjunction")
+	}
+	return w, nil
+}
+
+// conjunctionWitness holds a witness for
+// each part of a conjunction program.
+type conjunctionWitness []Witness
+
+func (w conjunctionWitness) Add(sig []byte) (bool, error) {
+	var (
+		complete = true
+		used     bool
+		err      error
+	)
+	for _, part := range w {
+		done, partErr := part.Add(sig)
+		if partErr != nil {
+			err = partErr
+		} else {
+			used = true
+		}
+		complete = complete && done
+	}
+	if used {
+		err = nil
+	}
+	return complete, err
+}
+
+func (w conjunctionWitness) Arguments() ([][]byte, error) {
+	// Each part consumes its arguments from the top of the
+	// stack, so the first part's arguments go last.
+	var args [][]byte
+	for i := len(w) - 1; i >= 0; i-- {
+		partArgs, err := w[i].Arguments()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, partArgs...)
+	}
+	return args, nil
+}
+
+func (w conjunctionWitness) Needed() int {
+	// A signer may count toward every part.
+	var n int
+	for _, part := range w {
+		if partN := part.Needed(); partN > n {
+			n = partN
+		}
+	}
+	return n
+}
+
+func indexKey(keys []ed25519.PublicKey, msg, sig []byte) int {
+	for i, key := range keys {
+		if ed25519.Verify(key, msg, sig) {
+			return i
+		}
+	}
+	return -1
+}
+
+func nonNilSigs(a [][]byte) (b [][]byte) {
+	for _, p := range a {
+		if p != nil {
+			b = append(b, p)
+		}
+	}
+	return b
+}
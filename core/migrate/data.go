@@ -42,4 +42,87 @@ var migrations = []migration{
 			DROP COLUMN index;
 		ALTER TABLE account_utxos ADD PRIMARY KEY (output_id);
 	`},
+	{Name: `2017-03-01.0.generator.pool.sql`, SQL: `
+		CREATE SEQUENCE generator_pool_seq;
+		CREATE TABLE generator_pool (
+			tx_hash bytea PRIMARY KEY,
+			data bytea NOT NULL,
+			max_time bigint NOT NULL,
+			seq bigint DEFAULT nextval('generator_pool_seq') NOT NULL
+		);
+	`},
+	{Name: `2017-03-02.0.core.block-params.sql`, SQL: `
+		ALTER TABLE config
+			ADD COLUMN block_period_ms bigint DEFAULT 1000 NOT NULL,
+			ADD COLUMN max_block_txs integer DEFAULT 10000 NOT NULL,
+			ADD COLUMN heartbeat_period_ms bigint DEFAULT 0 NOT NULL;
+	`},
+	{Name: `2017-03-03.0.core.next-consensus-program.sql`, SQL: `
+		ALTER TABLE config ADD COLUMN next_consensus_program bytea;
+	`},
+	{Name: `2017-03-04.0.core.block-evidence.sql`, SQL: `
+		CREATE TABLE block_evidence (
+			block_height bigint PRIMARY KEY,
+			header bytea NOT NULL,
+			conflicting_header bytea NOT NULL,
+			signers bytea[] NOT NULL,
+			source text NOT NULL,
+			detected_at timestamp with time zone NOT NULL
+		);
+	`},
+	{Name: `2017-03-05.0.core.snapshot-deltas.sql`, SQL: `
+		ALTER TABLE snapshots ADD COLUMN base_height bigint DEFAULT 0 NOT NULL;
+	`},
+	{Name: `2017-03-06.0.account.coin-selection.sql`, SQL: `
+		ALTER TABLE accounts ADD COLUMN coin_selection text NOT NULL DEFAULT '';
+		ALTER TABLE annotated_accounts ADD COLUMN coin_selection text NOT NULL DEFAULT '';
+	`},
+	{Name: `2017-03-07.0.account.consolidations.sql`, SQL: `
+		CREATE TABLE account_consolidations (
+			tx_hash bytea PRIMARY KEY,
+			account_id text NOT NULL,
+			asset_id bytea NOT NULL,
+			inputs integer NOT NULL,
+			amount bigint NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		CREATE INDEX account_consolidations_account_id_created_at_idx ON account_consolidations (account_id, created_at);
+	`},
+	{Name: `2017-03-08.0.account.reservations.sql`, SQL: `
+		CREATE TABLE reservations (
+			reservation_id bigserial PRIMARY KEY,
+			account_id text NOT NULL,
+			asset_id bytea NOT NULL,
+			change bigint NOT NULL,
+			client_token text UNIQUE,
+			expiry timestamp with time zone NOT NULL
+		);
+		CREATE INDEX reservations_expiry_idx ON reservations (expiry);
+		ALTER TABLE account_utxos
+			ADD COLUMN reservation_id bigint REFERENCES reservations ON DELETE SET NULL;
+		CREATE INDEX account_utxos_reservation_id_idx ON account_utxos (reservation_id);
+	`},
+	{Name: `2017-03-09.0.signers.versions.sql`, SQL: `
+		ALTER TABLE signers ADD COLUMN version integer DEFAULT 1 NOT NULL;
+		CREATE TABLE signer_versions (
+			signer_id text NOT NULL,
+			version integer NOT NULL,
+			xpubs bytea[] NOT NULL,
+			quorum integer NOT NULL,
+			PRIMARY KEY (signer_id, version)
+		);
+		ALTER TABLE account_control_programs ADD COLUMN key_version integer DEFAULT 1 NOT NULL;
+		ALTER TABLE account_utxos ADD COLUMN key_version integer DEFAULT 1 NOT NULL;
+	`},
+	{Name: `2017-03-10.0.blocksigner.sign-request-conflicts.sql`, SQL: `
+		CREATE TABLE sign_request_conflicts (
+			block_height bigint PRIMARY KEY,
+			signed_hash bytea NOT NULL,
+			header bytea NOT NULL,
+			detected_at timestamp with time zone NOT NULL
+		);
+	`},
+	{Name: `2017-03-11.0.query.tx-hash-index.sql`, SQL: `
+		CREATE INDEX annotated_txs_tx_hash_idx ON annotated_txs USING btree (tx_hash);
+	`},
 }
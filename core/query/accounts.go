@@ -19,12 +19,12 @@ func (ind *Indexer) SaveAnnotatedAccount(ctx context.Context, account *Annotated
 	}
 
 	const q = `
-		INSERT INTO annotated_accounts (id, alias, keys, quorum, tags)
-		VALUES($1, $2, $3::jsonb, $4, $5::jsonb)
-		ON CONFLICT (id) DO UPDATE SET tags = $5::jsonb
+		INSERT INTO annotated_accounts (id, alias, keys, quorum, tags, coin_selection)
+		VALUES($1, $2, $3::jsonb, $4, $5::jsonb, $6)
+		ON CONFLICT (id) DO UPDATE SET keys = $3::jsonb, quorum = $4, tags = $5::jsonb, coin_selection = $6
 	`
 	_, err = ind.db.Exec(ctx, q, account.ID, account.Alias, keysJSON,
-		account.Quorum, string(*account.Tags))
+		account.Quorum, string(*account.Tags), account.CoinSelection)
 	return errors.Wrap(err, "saving annotated account")
 }
 
@@ -60,6 +60,7 @@ func (ind *Indexer) Accounts(ctx context.Context, filt string, vals []interface{
 			&keysJSON,
 			&aa.Quorum,
 			&aa.Tags,
+			&aa.CoinSelection,
 		)
 		if err != nil {
 			return nil, "", errors.Wrap(err, "scanning account row")
@@ -79,7 +80,7 @@ func constructAccountsQuery(expr string, vals []interface{}, after string, limit
 	var buf bytes.Buffer
 
 	buf.WriteString("SELECT ")
-	buf.WriteString("id, alias, keys, quorum, tags")
+	buf.WriteString("id, alias, keys, quorum, tags, coin_selection")
 	buf.WriteString(" FROM annotated_accounts AS acc")
 	buf.WriteString(" WHERE ")
 
@@ -63,11 +63,12 @@ type AnnotatedOutput struct {
 }
 
 type AnnotatedAccount struct {
-	ID     string           `json:"id"`
-	Alias  string           `json:"alias,omitempty"`
-	Keys   []*AccountKey    `json:"keys"`
-	Quorum int              `json:"quorum"`
-	Tags   *json.RawMessage `json:"tags"`
+	ID            string           `json:"id"`
+	Alias         string           `json:"alias,omitempty"`
+	Keys          []*AccountKey    `json:"keys"`
+	Quorum        int              `json:"quorum"`
+	Tags          *json.RawMessage `json:"tags"`
+	CoinSelection string           `json:"coin_selection,omitempty"`
 }
 
 type AccountKey struct {
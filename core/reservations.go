@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"chain/core/account"
+	"chain/protocol/bc"
+)
+
+// POST /list-reservations
+//
+// listReservations returns the reservations of account outputs
+// made while building transactions that haven't yet expired
+// or been canceled, oldest first. Any combination of account,
+// asset, client token, and a time they expire before may be
+// given to narrow the list.
+func (a *API) listReservations(ctx context.Context, in struct {
+	AccountID     string     `json:"account_id"`
+	AccountAlias  string     `json:"account_alias"`
+	AssetID       bc.AssetID `json:"asset_id"`
+	ClientToken   string     `json:"client_token"`
+	ExpiresBefore time.Time  `json:"expires_before"`
+}) ([]*account.Reservation, error) {
+	if in.AccountAlias != "" {
+		acc, err := a.Accounts.FindByAlias(ctx, in.AccountAlias)
+		if err != nil {
+			return nil, err
+		}
+		in.AccountID = acc.ID
+	}
+	return a.Accounts.ListReservations(ctx, account.ReservationFilter{
+		AccountID:     in.AccountID,
+		AssetID:       in.AssetID,
+		ClientToken:   in.ClientToken,
+		ExpiresBefore: in.ExpiresBefore,
+	})
+}
+
+// POST /cancel-reservation
+//
+// cancelReservation releases the outputs held by a reservation,
+// so they can be spent by other transactions. A transaction
+// built with the reservation may still spend them if it's
+// submitted before they're reserved again.
+func (a *API) cancelReservation(ctx context.Context, in struct {
+	ID uint64 `json:"id"`
+}) error {
+	return a.Accounts.CancelReservation(ctx, in.ID)
+}
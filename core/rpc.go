@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"chain/core/fetch"
+	"chain/database/pg"
 	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/net/http/httpjson"
@@ -29,6 +31,23 @@ func (a *API) getBlockRPC(ctx context.Context, height uint64) (chainjson.HexByte
 	return rawBlock, nil
 }
 
+// getBlockHeaderRPC returns the header of the block at the
+// requested height, waiting like getBlockRPC. Light clients
+// use it to follow the blockchain without fetching transactions.
+func (a *API) getBlockHeaderRPC(ctx context.Context, height uint64) (*bc.BlockHeader, error) {
+	err := <-a.Chain.BlockSoonWaiter(ctx, height)
+	if err != nil {
+		return nil, errors.Wrapf(err, "waiting for block at height %d", height)
+	}
+
+	b, err := a.Chain.GetBlock(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return &b.BlockHeader, nil
+}
+
 // getBlocksRPC -- DEPRECATED: use getBlock instead
 func (a *API) getBlocksRPC(ctx context.Context, afterHeight uint64) ([]chainjson.HexBytes, error) {
 	block, err := a.getBlockRPC(ctx, afterHeight+1)
@@ -39,15 +58,24 @@ func (a *API) getBlocksRPC(ctx context.Context, afterHeight uint64) ([]chainjson
 	return []chainjson.HexBytes{block}, nil
 }
 
+// snapshotInfoResp describes the latest full snapshot and, if
+// there is one, the latest delta snapshot relative to it. Peers
+// that don't understand deltas can use the full snapshot alone.
 type snapshotInfoResp struct {
 	Height       uint64  `json:"height"`
 	Size         uint64  `json:"size"`
+	DeltaHeight  uint64  `json:"delta_height,omitempty"`
+	DeltaSize    uint64  `json:"delta_size,omitempty"`
 	BlockchainID bc.Hash `json:"blockchain_id"`
 }
 
 func (a *API) getSnapshotInfoRPC(ctx context.Context) (resp snapshotInfoResp, err error) {
 	// TODO(jackson): cache latest snapshot and its height & size in-memory.
 	resp.Height, resp.Size, err = a.Store.LatestSnapshotInfo(ctx)
+	if err != nil {
+		return resp, err
+	}
+	resp.DeltaHeight, resp.DeltaSize, err = a.Store.LatestSnapshotDeltaInfo(ctx, resp.Height)
 	resp.BlockchainID = a.Config.BlockchainID
 	return resp, err
 }
@@ -55,6 +83,8 @@ func (a *API) getSnapshotInfoRPC(ctx context.Context) (resp snapshotInfoResp, er
 // getSnapshotRPC returns the raw protobuf snapshot at the provided height.
 // Non-generators can call this endpoint to get raw data
 // that they can use to populate their own snapshot table.
+// At a delta height, the data is a delta, which must be
+// decoded with the full snapshot it's relative to.
 //
 // This handler doesn't use the httpjson.Handler format so that it can return
 // raw protobuf bytes on the wire.
@@ -79,3 +109,68 @@ func (a *API) getSnapshotRPC(rw http.ResponseWriter, req *http.Request) {
 	rw.Header().Set("Content-Type", "application/x-protobuf")
 	rw.Write(data)
 }
+
+// maxCachedManifests is the most snapshot
+// manifests getSnapshotManifestRPC caches.
+const maxCachedManifests = 8
+
+// getSnapshotManifestRPC returns the size and chunk
+// checksums of the snapshot at the provided height.
+// Manifests are cached, so that peers downloading a large
+// snapshot don't make us read all of it for each request.
+func (a *API) getSnapshotManifestRPC(ctx context.Context, height uint64) (*fetch.SnapshotManifest, error) {
+	a.manifestMu.Lock()
+	m := a.manifests[height]
+	a.manifestMu.Unlock()
+	if m != nil {
+		return m, nil
+	}
+
+	data, err := a.Store.GetSnapshot(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	m = fetch.NewSnapshotManifest(height, data)
+
+	a.manifestMu.Lock()
+	defer a.manifestMu.Unlock()
+	if a.manifests == nil || len(a.manifests) >= maxCachedManifests {
+		a.manifests = make(map[uint64]*fetch.SnapshotManifest)
+	}
+	a.manifests[height] = m
+	return m, nil
+}
+
+// getSnapshotChunkRPC returns one chunk of the raw protobuf
+// snapshot at the provided height, so peers can download large
+// snapshots in pieces and resume after failures.
+//
+// Like getSnapshotRPC, this handler returns raw bytes.
+func (a *API) getSnapshotChunkRPC(rw http.ResponseWriter, req *http.Request) {
+	if a.Config == nil {
+		alwaysError(errUnconfigured).ServeHTTP(rw, req)
+		return
+	}
+
+	var x struct {
+		Height uint64 `json:"height"`
+		Index  int    `json:"index"`
+	}
+	err := json.NewDecoder(req.Body).Decode(&x)
+	if err != nil || x.Index < 0 {
+		WriteHTTPError(req.Context(), rw, httpjson.ErrBadRequest)
+		return
+	}
+
+	data, err := a.Store.GetSnapshotChunk(req.Context(), x.Height, x.Index*fetch.SnapshotChunkSize, fetch.SnapshotChunkSize)
+	if err != nil {
+		WriteHTTPError(req.Context(), rw, err)
+		return
+	}
+	if len(data) == 0 {
+		WriteHTTPError(req.Context(), rw, errors.WithDetailf(pg.ErrUserInputNotFound, "snapshot %d has no chunk %d", x.Height, x.Index))
+		return
+	}
+	rw.Header().Set("Content-Type", "application/x-protobuf")
+	rw.Write(data)
+}
@@ -158,6 +158,20 @@ CREATE TABLE access_tokens (
 );
 
 
+--
+-- Name: account_consolidations; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE account_consolidations (
+    tx_hash bytea NOT NULL,
+    account_id text NOT NULL,
+    asset_id bytea NOT NULL,
+    inputs integer NOT NULL,
+    amount bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
 --
 -- Name: account_control_program_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
@@ -179,7 +193,8 @@ CREATE TABLE account_control_programs (
     key_index bigint NOT NULL,
     control_program bytea NOT NULL,
     change boolean NOT NULL,
-    expires_at timestamp with time zone
+    expires_at timestamp with time zone,
+    key_version integer DEFAULT 1 NOT NULL
 );
 
 
@@ -194,7 +209,9 @@ CREATE TABLE account_utxos (
     control_program_index bigint NOT NULL,
     control_program bytea NOT NULL,
     confirmed_in bigint NOT NULL,
-    output_id bytea NOT NULL
+    output_id bytea NOT NULL,
+    reservation_id bigint,
+    key_version integer DEFAULT 1 NOT NULL
 );
 
 
@@ -205,7 +222,8 @@ CREATE TABLE account_utxos (
 CREATE TABLE accounts (
     account_id text NOT NULL,
     tags jsonb,
-    alias text
+    alias text,
+    coin_selection text DEFAULT ''::text NOT NULL
 );
 
 
@@ -218,7 +236,8 @@ CREATE TABLE annotated_accounts (
     alias text NOT NULL,
     keys jsonb NOT NULL,
     quorum integer NOT NULL,
-    tags jsonb NOT NULL
+    tags jsonb NOT NULL,
+    coin_selection text DEFAULT ''::text NOT NULL
 );
 
 
@@ -348,6 +367,20 @@ CREATE SEQUENCE assets_key_index_seq
     CACHE 1;
 
 
+--
+-- Name: block_evidence; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE block_evidence (
+    block_height bigint NOT NULL,
+    header bytea NOT NULL,
+    conflicting_header bytea NOT NULL,
+    signers bytea[] NOT NULL,
+    source text NOT NULL,
+    detected_at timestamp with time zone NOT NULL
+);
+
+
 --
 -- Name: block_processors; Type: TABLE; Schema: public; Owner: -
 --
@@ -400,6 +433,10 @@ CREATE TABLE config (
     id text NOT NULL,
     block_hsm_url text DEFAULT ''::text,
     block_hsm_access_token text DEFAULT ''::text,
+    block_period_ms bigint DEFAULT 1000 NOT NULL,
+    max_block_txs integer DEFAULT 10000 NOT NULL,
+    heartbeat_period_ms bigint DEFAULT 0 NOT NULL,
+    next_consensus_program bytea,
     CONSTRAINT config_singleton CHECK (singleton)
 );
 
@@ -415,6 +452,30 @@ CREATE TABLE generator_pending_block (
 );
 
 
+--
+-- Name: generator_pool_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE generator_pool_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: generator_pool; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE generator_pool (
+    tx_hash bytea NOT NULL,
+    data bytea NOT NULL,
+    max_time bigint NOT NULL,
+    seq bigint DEFAULT nextval('generator_pool_seq'::regclass) NOT NULL
+);
+
+
 --
 -- Name: leader; Type: TABLE; Schema: public; Owner: -
 --
@@ -474,6 +535,51 @@ CREATE TABLE query_blocks (
 );
 
 
+--
+-- Name: reservations; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE reservations (
+    reservation_id bigint NOT NULL,
+    account_id text NOT NULL,
+    asset_id bytea NOT NULL,
+    change bigint NOT NULL,
+    client_token text,
+    expiry timestamp with time zone NOT NULL
+);
+
+
+--
+-- Name: reservations_reservation_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE reservations_reservation_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: reservations_reservation_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE reservations_reservation_id_seq OWNED BY reservations.reservation_id;
+
+
+--
+-- Name: sign_request_conflicts; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE sign_request_conflicts (
+    block_height bigint NOT NULL,
+    signed_hash bytea NOT NULL,
+    header bytea NOT NULL,
+    detected_at timestamp with time zone NOT NULL
+);
+
+
 --
 -- Name: signed_blocks; Type: TABLE; Schema: public; Owner: -
 --
@@ -484,6 +590,18 @@ CREATE TABLE signed_blocks (
 );
 
 
+--
+-- Name: signer_versions; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE signer_versions (
+    signer_id text NOT NULL,
+    version integer NOT NULL,
+    xpubs bytea[] NOT NULL,
+    quorum integer NOT NULL
+);
+
+
 --
 -- Name: signers; Type: TABLE; Schema: public; Owner: -
 --
@@ -494,7 +612,8 @@ CREATE TABLE signers (
     key_index bigint NOT NULL,
     quorum integer NOT NULL,
     client_token text,
-    xpubs bytea[] NOT NULL
+    xpubs bytea[] NOT NULL,
+    version integer DEFAULT 1 NOT NULL
 );
 
 
@@ -524,7 +643,8 @@ ALTER SEQUENCE signers_key_index_seq OWNED BY signers.key_index;
 CREATE TABLE snapshots (
     height bigint NOT NULL,
     data bytea NOT NULL,
-    created_at timestamp without time zone DEFAULT now()
+    created_at timestamp without time zone DEFAULT now(),
+    base_height bigint DEFAULT 0 NOT NULL
 );
 
 
@@ -552,6 +672,13 @@ CREATE TABLE txfeeds (
 );
 
 
+--
+-- Name: reservation_id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY reservations ALTER COLUMN reservation_id SET DEFAULT nextval('reservations_reservation_id_seq'::regclass);
+
+
 --
 -- Name: key_index; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -567,6 +694,14 @@ ALTER TABLE ONLY access_tokens
     ADD CONSTRAINT access_tokens_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: account_consolidations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY account_consolidations
+    ADD CONSTRAINT account_consolidations_pkey PRIMARY KEY (tx_hash);
+
+
 --
 -- Name: account_control_programs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -687,6 +822,14 @@ ALTER TABLE ONLY assets
     ADD CONSTRAINT assets_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: block_evidence_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY block_evidence
+    ADD CONSTRAINT block_evidence_pkey PRIMARY KEY (block_height);
+
+
 --
 -- Name: block_processors_name_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -727,6 +870,14 @@ ALTER TABLE ONLY generator_pending_block
     ADD CONSTRAINT generator_pending_block_pkey PRIMARY KEY (singleton);
 
 
+--
+-- Name: generator_pool_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY generator_pool
+    ADD CONSTRAINT generator_pool_pkey PRIMARY KEY (tx_hash);
+
+
 --
 -- Name: leader_singleton_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -767,6 +918,38 @@ ALTER TABLE ONLY query_blocks
     ADD CONSTRAINT query_blocks_pkey PRIMARY KEY (height);
 
 
+--
+-- Name: reservations_client_token_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY reservations
+    ADD CONSTRAINT reservations_client_token_key UNIQUE (client_token);
+
+
+--
+-- Name: reservations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY reservations
+    ADD CONSTRAINT reservations_pkey PRIMARY KEY (reservation_id);
+
+
+--
+-- Name: sign_request_conflicts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY sign_request_conflicts
+    ADD CONSTRAINT sign_request_conflicts_pkey PRIMARY KEY (block_height);
+
+
+--
+-- Name: signer_versions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY signer_versions
+    ADD CONSTRAINT signer_versions_pkey PRIMARY KEY (signer_id, version);
+
+
 --
 -- Name: signers_client_token_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -831,6 +1014,13 @@ ALTER TABLE ONLY txfeeds
     ADD CONSTRAINT txfeeds_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: account_consolidations_account_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX account_consolidations_account_id_created_at_idx ON account_consolidations USING btree (account_id, created_at);
+
+
 --
 -- Name: account_utxos_asset_id_account_id_confirmed_in_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -838,6 +1028,13 @@ ALTER TABLE ONLY txfeeds
 CREATE INDEX account_utxos_asset_id_account_id_confirmed_in_idx ON account_utxos USING btree (asset_id, account_id, confirmed_in);
 
 
+--
+-- Name: account_utxos_reservation_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX account_utxos_reservation_id_idx ON account_utxos USING btree (reservation_id);
+
+
 --
 -- Name: annotated_assets_sort_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -859,6 +1056,13 @@ CREATE INDEX annotated_outputs_timespan_idx ON annotated_outputs USING gist (tim
 CREATE INDEX annotated_txs_data_idx ON annotated_txs USING gin (data jsonb_path_ops);
 
 
+--
+-- Name: annotated_txs_tx_hash_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX annotated_txs_tx_hash_idx ON annotated_txs USING btree (tx_hash);
+
+
 --
 -- Name: assets_sort_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -873,6 +1077,13 @@ CREATE INDEX assets_sort_id ON assets USING btree (sort_id);
 CREATE INDEX query_blocks_timestamp_idx ON query_blocks USING btree ("timestamp");
 
 
+--
+-- Name: reservations_expiry_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX reservations_expiry_idx ON reservations USING btree (expiry);
+
+
 --
 -- Name: signed_blocks_block_height_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -887,6 +1098,14 @@ CREATE UNIQUE INDEX signed_blocks_block_height_idx ON signed_blocks USING btree
 CREATE INDEX signers_type_id_idx ON signers USING btree (type, id);
 
 
+--
+-- Name: account_utxos_reservation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY account_utxos
+    ADD CONSTRAINT account_utxos_reservation_id_fkey FOREIGN KEY (reservation_id) REFERENCES reservations(reservation_id) ON DELETE SET NULL;
+
+
 --
 -- PostgreSQL database dump complete
 --
@@ -895,3 +1114,14 @@ insert into migrations (filename, hash) values ('2017-02-03.0.core.schema-snapsh
 insert into migrations (filename, hash) values ('2017-02-07.0.query.non-null-alias.sql', '17028a0bdbc95911e299dc65fe641184e54c87a0d07b3c576d62d023b9a8defc');
 insert into migrations (filename, hash) values ('2017-02-16.0.query.spent-output.sql', '7cd52095b6f202d7a25ffe666b7b7d60e7700d314a7559b911e236b72661a738');
 insert into migrations (filename, hash) values ('2017-02-28.0.core.remove-outpoints.sql', '067638e2a826eac70d548f2d6bb234660f3200064072baf42db741456ecf8deb');
+insert into migrations (filename, hash) values ('2017-03-01.0.generator.pool.sql', '13156a8c1c60e5dd5fbd7e336a9f1cf2031ee68af9f04a4a41bca8de2f9cb407');
+insert into migrations (filename, hash) values ('2017-03-02.0.core.block-params.sql', '5fdc7fd4dad1da0d34d3d5c493de29f36f9d01e14a6ed8a2e6c58f97d593708f');
+insert into migrations (filename, hash) values ('2017-03-03.0.core.next-consensus-program.sql', 'b4cba29fbe9b652c9a9e35de591bb34b0acd9ae3945275eaffc253e433c32484');
+insert into migrations (filename, hash) values ('2017-03-04.0.core.block-evidence.sql', 'f1cefdc1d0e75b8020e9ee94675f0b8c3fc507a3cb8266d4eecc4f38316259d7');
+insert into migrations (filename, hash) values ('2017-03-05.0.core.snapshot-deltas.sql', 'd3126fe342a1ef51dffd1a58b48a72b05fb26782699d67848f1ac4b7dc4d8489');
+insert into migrations (filename, hash) values ('2017-03-06.0.account.coin-selection.sql', '037274806b451ccf2349967870fc4efac87d294d6e57e0e8c552d205d6eeefd6');
+insert into migrations (filename, hash) values ('2017-03-07.0.account.consolidations.sql', '9abc702409af1b3b21ed78cc6530112e353b53bd699f1d016afdb5fcda430a75');
+insert into migrations (filename, hash) values ('2017-03-08.0.account.reservations.sql', '9c3287d482b373f4ed4d06ff71a6930ac767bfc55d480fe5f7a04aefb7f087dc');
+insert into migrations (filename, hash) values ('2017-03-09.0.signers.versions.sql', '919f4d3e0109cb79103f062b3e65c1a6083030b36117b61a0b5c8740201a10fd');
+insert into migrations (filename, hash) values ('2017-03-10.0.blocksigner.sign-request-conflicts.sql', 'e5344d1260f20c2b8ada488db870aefb24801e0c9f70c825ac15a2a953c215ff');
+insert into migrations (filename, hash) values ('2017-03-11.0.query.tx-hash-index.sql', 'fcccb200a6befbd28334bf81b6d24cbe12ef3b885abc7423b9d43996ef31b662');
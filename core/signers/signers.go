@@ -59,6 +59,10 @@ type Signer struct {
 	XPubs    []chainkd.XPub
 	Quorum   int
 	KeyIndex uint64
+
+	// Version counts the times the signer's keys and quorum
+	// have been set, starting at 1 when it's created.
+	Version int
 }
 
 // Path returns the complete path for derived keys
@@ -77,24 +81,9 @@ func Path(s *Signer, ks keySpace, itemIndexes ...uint64) [][]byte {
 
 // Create creates and stores a Signer in the database
 func Create(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quorum int, clientToken string) (*Signer, error) {
-	if len(xpubs) == 0 {
-		return nil, errors.Wrap(ErrNoXPubs)
-	}
-
-	sort.Sort(sortKeys(xpubs)) // this transforms the input slice
-	for i := 1; i < len(xpubs); i++ {
-		if bytes.Equal(xpubs[i][:], xpubs[i-1][:]) {
-			return nil, errors.WithDetailf(ErrDupeXPub, "duplicated key=%x", xpubs[i])
-		}
-	}
-
-	if quorum == 0 || quorum > len(xpubs) {
-		return nil, errors.Wrap(ErrBadQuorum)
-	}
-
-	var xpubBytes [][]byte
-	for _, key := range xpubs {
-		xpubBytes = append(xpubBytes, key[:])
+	xpubBytes, err := checkKeys(xpubs, quorum)
+	if err != nil {
+		return nil, err
 	}
 
 	nullToken := sql.NullString{
@@ -106,14 +95,15 @@ func Create(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quo
 		INSERT INTO signers (id, type, xpubs, quorum, client_token)
 		VALUES (next_chain_id($1::text), $2, $3, $4, $5)
 		ON CONFLICT (client_token) DO NOTHING
-		RETURNING id, key_index
+		RETURNING id, key_index, version
   `
 	var (
 		id       string
 		keyIndex uint64
+		version  int
 	)
-	err := db.QueryRow(ctx, q, typeIDMap[typ], typ, pq.ByteaArray(xpubBytes), quorum, nullToken).
-		Scan(&id, &keyIndex)
+	err = db.QueryRow(ctx, q, typeIDMap[typ], typ, pq.ByteaArray(xpubBytes), quorum, nullToken).
+		Scan(&id, &keyIndex, &version)
 	if err == sql.ErrNoRows && clientToken != "" {
 		return findByClientToken(ctx, db, clientToken)
 	}
@@ -127,9 +117,72 @@ func Create(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quo
 		XPubs:    xpubs,
 		Quorum:   quorum,
 		KeyIndex: keyIndex,
+		Version:  version,
 	}, nil
 }
 
+// Update replaces the keys and quorum of the Signer with the given
+// type and id, and returns it with its new version. The old keys
+// and quorum are kept and can be retrieved with FindVersion.
+func Update(ctx context.Context, db pg.DB, typ, id string, xpubs []chainkd.XPub, quorum int) (*Signer, error) {
+	xpubBytes, err := checkKeys(xpubs, quorum)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		WITH old AS (
+			SELECT id, version, xpubs, quorum FROM signers
+			WHERE id = $1 AND type = $2
+			FOR UPDATE
+		), saved AS (
+			INSERT INTO signer_versions (signer_id, version, xpubs, quorum)
+			SELECT id, version, xpubs, quorum FROM old
+		)
+		UPDATE signers SET xpubs = $3, quorum = $4, version = old.version + 1
+		FROM old WHERE signers.id = old.id
+		RETURNING signers.key_index, signers.version
+	`
+	s := &Signer{
+		ID:     id,
+		Type:   typ,
+		XPubs:  xpubs,
+		Quorum: quorum,
+	}
+	err = db.QueryRow(ctx, q, id, typ, pq.ByteaArray(xpubBytes), quorum).Scan(&s.KeyIndex, &s.Version)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "%s id: %s", typ, id)
+	} else if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return s, nil
+}
+
+// checkKeys sorts xpubs, checks that they and quorum are
+// valid for a Signer, and returns the keys' bytes.
+func checkKeys(xpubs []chainkd.XPub, quorum int) ([][]byte, error) {
+	if len(xpubs) == 0 {
+		return nil, errors.Wrap(ErrNoXPubs)
+	}
+
+	sort.Sort(sortKeys(xpubs)) // this transforms the input slice
+	for i := 1; i < len(xpubs); i++ {
+		if bytes.Equal(xpubs[i][:], xpubs[i-1][:]) {
+			return nil, errors.WithDetailf(ErrDupeXPub, "duplicated key=%x", xpubs[i])
+		}
+	}
+
+	if quorum == 0 || quorum > len(xpubs) {
+		return nil, errors.Wrap(ErrBadQuorum)
+	}
+
+	var xpubBytes [][]byte
+	for _, key := range xpubs {
+		xpubBytes = append(xpubBytes, key[:])
+	}
+	return xpubBytes, nil
+}
+
 func New(id, typ string, xpubs [][]byte, quorum int, keyIndex uint64) (*Signer, error) {
 	keys, err := ConvertKeys(xpubs)
 	if err != nil {
@@ -146,7 +199,7 @@ func New(id, typ string, xpubs [][]byte, quorum int, keyIndex uint64) (*Signer,
 
 func findByClientToken(ctx context.Context, db pg.DB, clientToken string) (*Signer, error) {
 	const q = `
-		SELECT id, type, xpubs, quorum, key_index
+		SELECT id, type, xpubs, quorum, key_index, version
 		FROM signers WHERE client_token=$1
 	`
 
@@ -155,7 +208,7 @@ func findByClientToken(ctx context.Context, db pg.DB, clientToken string) (*Sign
 		xpubBytes [][]byte
 	)
 	err := db.QueryRow(ctx, q, clientToken).
-		Scan(&s.ID, &s.Type, (*pq.ByteaArray)(&xpubBytes), &s.Quorum, &s.KeyIndex)
+		Scan(&s.ID, &s.Type, (*pq.ByteaArray)(&xpubBytes), &s.Quorum, &s.KeyIndex, &s.Version)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
@@ -174,7 +227,7 @@ func findByClientToken(ctx context.Context, db pg.DB, clientToken string) (*Sign
 // using the type and id.
 func Find(ctx context.Context, db pg.DB, typ, id string) (*Signer, error) {
 	const q = `
-		SELECT id, type, xpubs, quorum, key_index
+		SELECT id, type, xpubs, quorum, key_index, version
 		FROM signers WHERE id=$1
 	`
 
@@ -188,6 +241,7 @@ func Find(ctx context.Context, db pg.DB, typ, id string) (*Signer, error) {
 		(*pq.ByteaArray)(&xpubBytes),
 		&s.Quorum,
 		&s.KeyIndex,
+		&s.Version,
 	)
 	if err == sql.ErrNoRows {
 		return nil, errors.Wrap(pg.ErrUserInputNotFound)
@@ -210,18 +264,64 @@ func Find(ctx context.Context, db pg.DB, typ, id string) (*Signer, error) {
 	return &s, nil
 }
 
+// FindVersion retrieves the given version of a Signer from
+// the database using the type and id. It has the keys and
+// quorum the Signer had at that version.
+func FindVersion(ctx context.Context, db pg.DB, typ, id string, version int) (*Signer, error) {
+	const q = `
+		SELECT s.id, s.type, v.xpubs, v.quorum, s.key_index, v.version
+		FROM signers s JOIN signer_versions v ON v.signer_id = s.id
+		WHERE s.id=$1 AND v.version=$2
+		UNION ALL
+		SELECT id, type, xpubs, quorum, key_index, version
+		FROM signers WHERE id=$1 AND version=$2
+	`
+
+	var (
+		s         Signer
+		xpubBytes [][]byte
+	)
+	err := db.QueryRow(ctx, q, id, version).Scan(
+		&s.ID,
+		&s.Type,
+		(*pq.ByteaArray)(&xpubBytes),
+		&s.Quorum,
+		&s.KeyIndex,
+		&s.Version,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "version %d", version)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	if s.Type != typ {
+		return nil, errors.Wrap(ErrBadType)
+	}
+
+	keys, err := ConvertKeys(xpubBytes)
+	if err != nil {
+		return nil, errors.WithDetail(errors.New("bad xpub in databse"), errors.Detail(err))
+	}
+
+	s.XPubs = keys
+
+	return &s, nil
+}
+
 // List returns a paginated set of Signers, limited to
 // the provided type.
 func List(ctx context.Context, db pg.DB, typ, prev string, limit int) ([]*Signer, string, error) {
 	const q = `
-		SELECT id, type, xpubs, quorum, key_index
+		SELECT id, type, xpubs, quorum, key_index, version
 		FROM signers WHERE type=$1 AND ($2='' OR $2<id)
 		ORDER BY id ASC LIMIT $3
 	`
 
 	var signers []*Signer
 	err := pg.ForQueryRows(ctx, db, q, typ, prev, limit,
-		func(id, typ string, xpubs pq.ByteaArray, quorum int, keyIndex uint64) error {
+		func(id, typ string, xpubs pq.ByteaArray, quorum int, keyIndex uint64, version int) error {
 			keys, err := ConvertKeys(xpubs)
 			if err != nil {
 				return errors.WithDetail(errors.New("bad xpub in databse"), errors.Detail(err))
@@ -233,6 +333,7 @@ func List(ctx context.Context, db pg.DB, typ, prev string, limit int) ([]*Signer
 				XPubs:    keys,
 				Quorum:   quorum,
 				KeyIndex: keyIndex,
+				Version:  version,
 			})
 			return nil
 		},
@@ -203,6 +203,47 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestUpdateFindVersion(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+
+	s1 := createFixture(ctx, db, t)
+	s2, err := Update(ctx, db, s1.Type, s1.ID, []chainkd.XPub{testutil.TestXPub, dummyXPub}, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if s2.Version != s1.Version+1 || s2.KeyIndex != s1.KeyIndex {
+		t.Errorf("Update got version %d key index %d, want %d and %d", s2.Version, s2.KeyIndex, s1.Version+1, s1.KeyIndex)
+	}
+
+	got, err := Find(ctx, db, s1.Type, s1.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !testutil.DeepEqual(got, s2) {
+		t.Errorf("Find after Update\n\tgot:  %+v\n\twant: %+v", got, s2)
+	}
+
+	for _, want := range []*Signer{s1, s2} {
+		got, err := FindVersion(ctx, db, s1.Type, s1.ID, want.Version)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if !testutil.DeepEqual(got, want) {
+			t.Errorf("FindVersion(%d)\n\tgot:  %+v\n\twant: %+v", want.Version, got, want)
+		}
+	}
+
+	_, err = FindVersion(ctx, db, s1.Type, s1.ID, s2.Version+1)
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("FindVersion(%d) = %q want %q", s2.Version+1, errors.Root(err), pg.ErrUserInputNotFound)
+	}
+	_, err = Update(ctx, db, s1.Type, "nonexistent", []chainkd.XPub{testutil.TestXPub}, 1)
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("Update(nonexistent) = %q want %q", errors.Root(err), pg.ErrUserInputNotFound)
+	}
+}
+
 var clientTokenCounter = createCounter()
 
 func createFixture(ctx context.Context, db pg.DB, t testing.TB) *Signer {
@@ -0,0 +1,129 @@
+// Code generated by protoc-gen-go.
+// source: snapshot.proto
+// DO NOT EDIT!
+
+/*
+Package storage is a generated protocol buffer package.
+
+It is generated from these files:
+	snapshot.proto
+
+It has these top-level messages:
+	Snapshot
+*/
+package storage
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Snapshot represents a snapshot of the blockchain, including the state
+// tree and issuance memory. A delta snapshot holds only the changes
+// since a full base snapshot.
+type Snapshot struct {
+	// Nodes contains every node within the state tree, including interior nodes.
+	// The nodes are ordered according to a pre-order traversal.
+	// In a delta, it contains the nodes inserted since the base snapshot.
+	Nodes []*Snapshot_StateTreeNode `protobuf:"bytes,1,rep,name=nodes" json:"nodes,omitempty"`
+	// Issuances contains the record of recent issuances for ensuring uniqueness
+	// of issuances. In a delta, it contains the issuances added or changed
+	// since the base snapshot.
+	Issuances []*Snapshot_Issuance `protobuf:"bytes,2,rep,name=issuances" json:"issuances,omitempty"`
+	// BaseHeight is the height of the snapshot this one is a delta from.
+	// It is zero for a full snapshot.
+	BaseHeight uint64 `protobuf:"varint,3,opt,name=base_height,json=baseHeight" json:"base_height,omitempty"`
+	// BaseRoot is the state tree root hash of the base snapshot.
+	BaseRoot []byte `protobuf:"bytes,4,opt,name=base_root,json=baseRoot,proto3" json:"base_root,omitempty"`
+	// DeletedNodes contains the nodes deleted since the base snapshot.
+	DeletedNodes []*Snapshot_StateTreeNode `protobuf:"bytes,5,rep,name=deleted_nodes,json=deletedNodes" json:"deleted_nodes,omitempty"`
+	// DeletedIssuances contains the hashes of the issuances removed
+	// since the base snapshot.
+	DeletedIssuances [][]byte `protobuf:"bytes,6,rep,name=deleted_issuances,json=deletedIssuances,proto3" json:"deleted_issuances,omitempty"`
+}
+
+func (m *Snapshot) Reset()                    { *m = Snapshot{} }
+func (m *Snapshot) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot) ProtoMessage()               {}
+func (*Snapshot) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Snapshot) GetNodes() []*Snapshot_StateTreeNode {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *Snapshot) GetIssuances() []*Snapshot_Issuance {
+	if m != nil {
+		return m.Issuances
+	}
+	return nil
+}
+
+func (m *Snapshot) GetDeletedNodes() []*Snapshot_StateTreeNode {
+	if m != nil {
+		return m.DeletedNodes
+	}
+	return nil
+}
+
+type Snapshot_Issuance struct {
+	Hash     []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	ExpiryMs uint64 `protobuf:"varint,2,opt,name=expiry_ms,json=expiryMs" json:"expiry_ms,omitempty"`
+}
+
+func (m *Snapshot_Issuance) Reset()                    { *m = Snapshot_Issuance{} }
+func (m *Snapshot_Issuance) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot_Issuance) ProtoMessage()               {}
+func (*Snapshot_Issuance) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
+
+type Snapshot_StateTreeNode struct {
+	Key []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *Snapshot_StateTreeNode) Reset()                    { *m = Snapshot_StateTreeNode{} }
+func (m *Snapshot_StateTreeNode) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot_StateTreeNode) ProtoMessage()               {}
+func (*Snapshot_StateTreeNode) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 1} }
+
+func init() {
+	proto.RegisterType((*Snapshot)(nil), "chain.core.snapshotenc.internal.storage.Snapshot")
+	proto.RegisterType((*Snapshot_Issuance)(nil), "chain.core.snapshotenc.internal.storage.Snapshot.Issuance")
+	proto.RegisterType((*Snapshot_StateTreeNode)(nil), "chain.core.snapshotenc.internal.storage.Snapshot.StateTreeNode")
+}
+
+func init() { proto.RegisterFile("snapshot.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 302 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x92, 0xc1, 0x4b, 0xfb, 0x30,
+	0x14, 0xc7, 0xe9, 0xaf, 0xdb, 0x7e, 0xdd, 0xdb, 0x26, 0x33, 0xa7, 0x32, 0x0f, 0x56, 0x2f, 0x16,
+	0x84, 0x1c, 0xdc, 0x45, 0xf0, 0xe6, 0x49, 0x0f, 0x0e, 0xcc, 0x3c, 0x09, 0x52, 0xd2, 0xf6, 0xb1,
+	0x14, 0x67, 0x52, 0x92, 0x08, 0xdb, 0x5f, 0xea, 0xbf, 0x23, 0x49, 0x53, 0xa6, 0x78, 0x12, 0x6f,
+	0xaf, 0xdf, 0xf7, 0xde, 0x87, 0xf7, 0x29, 0x81, 0x23, 0x23, 0x79, 0x6b, 0x84, 0xb2, 0xb4, 0xd5,
+	0xca, 0x2a, 0x72, 0x51, 0x09, 0xde, 0x48, 0x5a, 0x29, 0x8d, 0xb4, 0x6f, 0xa1, 0xac, 0x68, 0x23,
+	0x2d, 0x6a, 0xc9, 0xb7, 0xd4, 0x58, 0xa5, 0xf9, 0x06, 0xcf, 0x3f, 0x62, 0x48, 0xd6, 0x61, 0x80,
+	0xac, 0x60, 0x28, 0x55, 0x8d, 0x26, 0x8d, 0xb2, 0x38, 0x9f, 0x5c, 0x5d, 0xd3, 0x2f, 0x14, 0xbb,
+	0xab, 0xcb, 0x1f, 0xeb, 0xb4, 0x5f, 0xa5, 0x6b, 0xcb, 0x2d, 0x3e, 0x69, 0xc4, 0x95, 0xaa, 0x91,
+	0x75, 0x18, 0xf2, 0x08, 0xe3, 0xc6, 0x98, 0x77, 0x2e, 0x2b, 0x34, 0xe9, 0x3f, 0xcf, 0x5c, 0xfe,
+	0x82, 0x79, 0x1f, 0x76, 0xd9, 0x81, 0x42, 0x4e, 0x61, 0x52, 0x72, 0x83, 0x85, 0xc0, 0x66, 0x23,
+	0x6c, 0x1a, 0x67, 0x51, 0x3e, 0x60, 0xe0, 0xa2, 0x3b, 0x9f, 0x90, 0x13, 0x18, 0xfb, 0x01, 0xad,
+	0x94, 0x4d, 0x07, 0x59, 0x94, 0x4f, 0x59, 0xe2, 0x02, 0xa6, 0x94, 0x25, 0x2f, 0x30, 0xab, 0x71,
+	0x8b, 0x16, 0xeb, 0xa2, 0x13, 0x1d, 0xfe, 0x51, 0x74, 0x1a, 0x70, 0x2b, 0xef, 0x7b, 0x09, 0xc7,
+	0x3d, 0xfe, 0xe0, 0x3d, 0xca, 0xe2, 0x7c, 0xca, 0xe6, 0xa1, 0xd1, 0x3b, 0x99, 0xc5, 0x0d, 0x24,
+	0xfd, 0x07, 0x21, 0x30, 0x10, 0xdc, 0x88, 0x34, 0xf2, 0xf7, 0xfa, 0xda, 0x89, 0xe0, 0xae, 0x6d,
+	0xf4, 0xbe, 0x78, 0x73, 0x3f, 0xcf, 0x79, 0x26, 0x5d, 0xf0, 0x60, 0x16, 0x67, 0x30, 0xfb, 0x76,
+	0x08, 0x99, 0x43, 0xfc, 0x8a, 0xfb, 0x00, 0x70, 0xe5, 0xed, 0xf8, 0xf9, 0x7f, 0x38, 0xbe, 0x1c,
+	0xf9, 0x47, 0xb1, 0xfc, 0x1c, 0x00, 0x53, 0x18, 0x77, 0x41, 0x26, 0x02, 0x00, 0x00,
+}
@@ -0,0 +1,136 @@
+// Package snapshotenc encodes state snapshots in Chain Core's
+// binary, protobuf representation, which cores store and send
+// to each other when bootstrapping.
+package snapshotenc
+
+import (
+	"bytes"
+
+	"github.com/golang/protobuf/proto"
+
+	"chain/core/snapshotenc/internal/storage"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/patricia"
+	"chain/protocol/state"
+)
+
+// ErrMissingBase is returned by Decode when given a
+// delta snapshot without the base snapshot it's relative to.
+var ErrMissingBase = errors.New("delta snapshot requires its base snapshot")
+
+// Decode decodes a snapshot from the Chain Core's binary,
+// protobuf representation of the snapshot. If the data is a delta
+// snapshot, base must be the full snapshot it was computed from,
+// and is left unchanged. For a full snapshot, base is ignored and
+// may be nil.
+func Decode(data []byte, base *state.Snapshot) (*state.Snapshot, error) {
+	var storedSnapshot storage.Snapshot
+	err := proto.Unmarshal(data, &storedSnapshot)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling state snapshot proto")
+	}
+
+	snapshot := state.Empty()
+	if storedSnapshot.BaseHeight > 0 {
+		if base == nil {
+			return nil, errors.WithDetailf(ErrMissingBase, "snapshot is a delta from height %d", storedSnapshot.BaseHeight)
+		}
+		baseRoot := base.Tree.RootHash()
+		if !bytes.Equal(storedSnapshot.BaseRoot, baseRoot[:]) {
+			return nil, errors.WithDetailf(ErrMissingBase, "snapshot is a delta from height %d with a different state root", storedSnapshot.BaseHeight)
+		}
+		snapshot = state.Copy(base)
+	}
+
+	for _, node := range storedSnapshot.DeletedNodes {
+		snapshot.Tree.Delete(node.Key)
+	}
+	for _, node := range storedSnapshot.Nodes {
+		err = snapshot.Tree.Insert(node.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "reconstructing state tree")
+		}
+	}
+
+	for _, h := range storedSnapshot.DeletedIssuances {
+		var hash bc.Hash
+		copy(hash[:], h)
+		delete(snapshot.Issuances, hash)
+	}
+	for _, issuance := range storedSnapshot.Issuances {
+		var hash bc.Hash
+		copy(hash[:], issuance.Hash)
+		snapshot.Issuances[hash] = issuance.ExpiryMs
+	}
+	return snapshot, nil
+}
+
+// Encode encodes a snapshot in the Chain Core's binary,
+// protobuf representation. Decode reverses it.
+func Encode(snapshot *state.Snapshot) ([]byte, error) {
+	var storedSnapshot storage.Snapshot
+	err := patricia.Walk(snapshot.Tree, func(key []byte) error {
+		n := &storage.Snapshot_StateTreeNode{Key: key}
+		storedSnapshot.Nodes = append(storedSnapshot.Nodes, n)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "walking patricia tree")
+	}
+
+	storedSnapshot.Issuances = make([]*storage.Snapshot_Issuance, 0, len(snapshot.Issuances))
+	for k, v := range snapshot.Issuances {
+		hash := k
+		storedSnapshot.Issuances = append(storedSnapshot.Issuances, &storage.Snapshot_Issuance{
+			Hash:     hash[:],
+			ExpiryMs: v,
+		})
+	}
+
+	b, err := proto.Marshal(&storedSnapshot)
+	return b, errors.Wrap(err, "marshaling state snapshot")
+}
+
+// EncodeDelta encodes the changes from base, the full
+// snapshot at baseHeight, to snapshot. Decode reverses
+// it, given base.
+func EncodeDelta(base *state.Snapshot, baseHeight uint64, snapshot *state.Snapshot) ([]byte, error) {
+	baseRoot := base.Tree.RootHash()
+	storedSnapshot := storage.Snapshot{
+		BaseHeight: baseHeight,
+		BaseRoot:   baseRoot[:],
+	}
+	err := patricia.Diff(base.Tree, snapshot.Tree, func(key []byte) error {
+		n := &storage.Snapshot_StateTreeNode{Key: key}
+		storedSnapshot.Nodes = append(storedSnapshot.Nodes, n)
+		return nil
+	}, func(key []byte) error {
+		n := &storage.Snapshot_StateTreeNode{Key: key}
+		storedSnapshot.DeletedNodes = append(storedSnapshot.DeletedNodes, n)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "diffing patricia trees")
+	}
+
+	for k, v := range snapshot.Issuances {
+		if old, ok := base.Issuances[k]; ok && old == v {
+			continue
+		}
+		hash := k
+		storedSnapshot.Issuances = append(storedSnapshot.Issuances, &storage.Snapshot_Issuance{
+			Hash:     hash[:],
+			ExpiryMs: v,
+		})
+	}
+	for k := range base.Issuances {
+		if _, ok := snapshot.Issuances[k]; !ok {
+			hash := k
+			storedSnapshot.DeletedIssuances = append(storedSnapshot.DeletedIssuances, hash[:])
+		}
+	}
+
+	b, err := proto.Marshal(&storedSnapshot)
+	return b, errors.Wrap(err, "marshaling state snapshot delta")
+}
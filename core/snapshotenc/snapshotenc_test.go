@@ -0,0 +1,53 @@
+package snapshotenc
+
+import (
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/state"
+	"chain/testutil"
+)
+
+func TestSnapshotDelta(t *testing.T) {
+	base := state.Empty()
+	for i := byte(1); i <= 4; i++ {
+		base.Tree.Insert([]byte{i})
+		base.Issuances[bc.Hash{i}] = uint64(i)
+	}
+
+	snapshot := state.Copy(base)
+	snapshot.Tree.Delete([]byte{1})
+	snapshot.Tree.Insert([]byte{5})
+	delete(snapshot.Issuances, bc.Hash{2})
+	snapshot.Issuances[bc.Hash{3}] = 30
+	snapshot.Issuances[bc.Hash{5}] = 5
+
+	data, err := EncodeDelta(base, 10, snapshot)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	_, err = Decode(data, nil)
+	if errors.Root(err) != ErrMissingBase {
+		t.Errorf("decoding without base: got error %v, want %v", err, ErrMissingBase)
+	}
+	_, err = Decode(data, snapshot)
+	if errors.Root(err) != ErrMissingBase {
+		t.Errorf("decoding with wrong base: got error %v, want %v", err, ErrMissingBase)
+	}
+
+	got, err := Decode(data, base)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Tree.RootHash() != snapshot.Tree.RootHash() {
+		t.Errorf("got root %s, want %s", got.Tree.RootHash(), snapshot.Tree.RootHash())
+	}
+	if !testutil.DeepEqual(got.Issuances, snapshot.Issuances) {
+		t.Errorf("got issuances %v, want %v", got.Issuances, snapshot.Issuances)
+	}
+	if !base.Tree.Contains([]byte{1}) || len(base.Issuances) != 4 {
+		t.Error("decoding changed the base snapshot")
+	}
+}
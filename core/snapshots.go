@@ -0,0 +1,16 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/txdb"
+)
+
+// POST /list-snapshots
+//
+// listSnapshots returns the state snapshots this core has
+// stored, most recent first, with their heights, sizes and
+// creation times. Delta snapshots have a nonzero base height.
+func (a *API) listSnapshots(ctx context.Context) ([]*txdb.SnapshotInfo, error) {
+	return a.Store.ListSnapshots(ctx)
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"chain/core/fetch"
+	"chain/core/generator"
 	"chain/core/leader"
 	"chain/core/txbuilder"
 	"chain/database/pg"
@@ -15,10 +16,21 @@ import (
 	"chain/log"
 	"chain/net/http/reqid"
 	"chain/protocol/bc"
+	"chain/protocol/validation"
 )
 
 var defaultTxTTL = 5 * time.Minute
 
+// txStatusPollPeriod is how often a submit request waiting for
+// confirmation asks the generator whether the tx was rejected.
+var txStatusPollPeriod = time.Second
+
+// txStatuser is implemented by Submitters that can report
+// what the generator knows about a submitted transaction.
+type txStatuser interface {
+	TxStatus(context.Context, bc.Hash) (*generator.TxStatus, error)
+}
+
 func (a *API) actionDecoder(action string) (func([]byte) (txbuilder.Action, error), bool) {
 	var decoder func([]byte) (txbuilder.Action, error)
 	switch action {
@@ -94,16 +106,15 @@ func (a *API) buildSingle(ctx context.Context, req *buildRequest) (*txbuilder.Te
 }
 
 // POST /build-transaction
+//
+// Any cored process can build transactions; reservations are
+// shared through Postgres. UTXOs are checked against the
+// blockchain before they're reserved (see account's reserver),
+// but the account_utxos table can lag behind, so a template
+// built on a process other than the leader is somewhat more
+// likely to spend an output that was just spent and be
+// rejected when submitted.
 func (a *API) build(ctx context.Context, buildReqs []*buildRequest) (interface{}, error) {
-	// If we're not the leader, we don't have access to the current
-	// reservations. Forward the build call to the leader process.
-	// TODO(jackson): Distribute reservations across cored processes.
-	if !leader.IsLeading() {
-		var resp interface{}
-		err := a.forwardToLeader(ctx, "/build-transaction", buildReqs, &resp)
-		return resp, err
-	}
-
 	responses := make([]interface{}, len(buildReqs))
 	var wg sync.WaitGroup
 	wg.Add(len(responses))
@@ -247,13 +258,25 @@ func (a *API) finalizeTxWait(ctx context.Context, txTemplate *txbuilder.Template
 }
 
 func (a *API) waitForTxInBlock(ctx context.Context, tx *bc.Tx, height uint64) (uint64, error) {
+	ticker := time.NewTicker(txStatusPollPeriod)
+	defer ticker.Stop()
+
+	height++
+	blockWaiter := a.Chain.BlockWaiter(height)
 	for {
-		height++
 		select {
 		case <-ctx.Done():
 			return 0, ctx.Err()
 
-		case <-a.Chain.BlockWaiter(height):
+		case <-ticker.C:
+			// The generator doesn't make empty blocks, so if it
+			// rejected tx there might be no new block to wake us.
+			err := a.checkRejected(ctx, tx)
+			if err != nil {
+				return 0, err
+			}
+
+		case <-blockWaiter:
 			b, err := a.Chain.GetBlock(ctx, height)
 			if err != nil {
 				return 0, errors.Wrap(err, "getting block that just landed")
@@ -269,8 +292,14 @@ func (a *API) waitForTxInBlock(ctx context.Context, tx *bc.Tx, height uint64) (u
 				return 0, errors.Wrap(txbuilder.ErrRejected, "transaction max time exceeded")
 			}
 
-			// might still be in pool or might be rejected; we can't
-			// tell definitively until its max time elapses.
+			err = a.checkRejected(ctx, tx)
+			if err != nil {
+				return 0, err
+			}
+
+			// Might still be in the pool, or the generator might
+			// have forgotten about it; we can't tell definitively
+			// until its max time elapses.
 
 			// Re-insert into the pool in case it was dropped.
 			err = txbuilder.FinalizeTx(ctx, a.Chain, a.Submitter, tx)
@@ -278,12 +307,59 @@ func (a *API) waitForTxInBlock(ctx context.Context, tx *bc.Tx, height uint64) (u
 				return 0, err
 			}
 
-			// TODO(jackson): Do simple rejection checks like checking if
-			// the tx's blockchain prevouts still exist in the state tree.
+			height++
+			blockWaiter = a.Chain.BlockWaiter(height)
 		}
 	}
 }
 
+// checkRejected returns ErrRejected, with the generator's
+// reason as detail, if the generator has rejected tx.
+func (a *API) checkRejected(ctx context.Context, tx *bc.Tx) error {
+	s, ok := a.Submitter.(txStatuser)
+	if !ok {
+		return nil
+	}
+	status, err := s.TxStatus(ctx, tx.ID)
+	if err != nil {
+		// Not being able to reach the generator doesn't tell
+		// us anything about the tx; keep waiting.
+		return nil
+	}
+	if status.Status == generator.TxRejected {
+		return errors.WithDetail(txbuilder.ErrRejected, status.Reason)
+	}
+	return nil
+}
+
+// POST /rpc/submit
+func (a *API) submitRPC(ctx context.Context, tx *bc.Tx) error {
+	// The tx counts toward the pool limit of the authenticated
+	// caller; see apiAuthn.
+	err := a.Submitter.Submit(ctx, tx)
+	if errors.Root(err) == validation.ErrBadTx {
+		return errors.Sub(txbuilder.ErrRejected, err)
+	}
+	return err
+}
+
+// POST /rpc/get-tx-status
+func (a *API) getTxStatusRPC(ctx context.Context, x struct {
+	ID bc.Hash `json:"id"`
+}) (*generator.TxStatus, error) {
+	// Only the leader has the generator's pending pool.
+	if !leader.IsLeading() {
+		var resp generator.TxStatus
+		err := a.forwardToLeader(ctx, "/rpc/get-tx-status", x, &resp)
+		return &resp, err
+	}
+	g, ok := a.Submitter.(*generator.Generator)
+	if !ok {
+		return nil, errors.WithDetail(errNotFound, "this core is not a generator")
+	}
+	return g.TxStatus(ctx, x.ID)
+}
+
 type submitArg struct {
 	Transactions []txbuilder.Template
 	wait         chainjson.Duration
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 
+	"chain/core/generator"
 	"chain/core/rpc"
 	"chain/errors"
 	"chain/protocol"
@@ -48,6 +49,9 @@ func FinalizeTx(ctx context.Context, c *protocol.Chain, s Submitter, tx *bc.Tx)
 	}
 
 	err = s.Submit(ctx, tx)
+	if errors.Root(err) == validation.ErrBadTx {
+		return errors.Sub(ErrRejected, err)
+	}
 	return errors.Wrap(err)
 }
 
@@ -127,3 +131,13 @@ func (rg *RemoteGenerator) Submit(ctx context.Context, tx *bc.Tx) error {
 	err = errors.Wrap(err, "generator transaction notice")
 	return err
 }
+
+// TxStatus asks the remote generator what it knows about
+// the transaction with the given ID.
+func (rg *RemoteGenerator) TxStatus(ctx context.Context, txID bc.Hash) (*generator.TxStatus, error) {
+	var status generator.TxStatus
+	err := rg.Peer.Call(ctx, "/rpc/get-tx-status", struct {
+		ID bc.Hash `json:"id"`
+	}{txID}, &status)
+	return &status, errors.Wrap(err, "generator transaction status")
+}
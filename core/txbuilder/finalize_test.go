@@ -92,8 +92,8 @@ func TestSighashCheck(t *testing.T) {
 }
 
 // TestConflictingTxsInPool tests creating conflicting transactions, and
-// ensures that they both make it into the tx pool. Then, when a block
-// lands, only one of the txs should be confirmed.
+// ensures that the tx pool refuses the second. Then, when a block
+// lands, only the first tx should be confirmed.
 //
 // Conflicting txs are created by building a tx template with only a
 // source, and then building two different txs with that same source,
@@ -148,11 +148,11 @@ func TestConflictingTxsInPool(t *testing.T) {
 	secondTemplate.SigningInstructions[0].WitnessComponents[0].(*SignatureWitness).Sigs = nil
 	coretest.SignTxTemplate(t, ctx, secondTemplate, nil)
 	err = FinalizeTx(ctx, info.Chain, g, secondTemplate.Transaction)
-	if err != nil {
-		testutil.FatalErr(t, err)
+	if errors.Root(err) != generator.ErrPoolConflict {
+		t.Fatalf("got err = %v, want %v", err, generator.ErrPoolConflict)
 	}
 
-	// Make a block, which should reject one of the txs.
+	// Make a block, which should contain only the first tx.
 	dumpBlocks(ctx, t, db)
 	b := prottest.MakeBlock(t, info.Chain, g.PendingTxs())
 	<-info.pinStore.PinWaiter(account.PinName, info.Chain.Height())
@@ -299,7 +299,6 @@ func benchGenBlock(b *testing.B) {
 
 	ctx := context.Background()
 	c := prottest.NewChainWithStorage(b, memstore.New())
-	g := generator.New(c, nil, pgtest.NewTx(b))
 	initialBlock, err := c.GetBlock(ctx, 1)
 	if err != nil {
 		testutil.FatalErr(b, err)
@@ -314,18 +313,10 @@ func benchGenBlock(b *testing.B) {
 	if err != nil {
 		b.Fatal(err)
 	}
-	err = g.Submit(ctx, &tx1)
-	if err != nil {
-		b.Fatal(err)
-	}
-	err = g.Submit(ctx, &tx2)
-	if err != nil {
-		b.Fatal(err)
-	}
 
 	now := time.Now()
 	b.StartTimer()
-	_, _, err = c.GenerateBlock(ctx, initialBlock, state.Empty(), now, g.PendingTxs())
+	_, _, err = c.GenerateBlock(ctx, initialBlock, state.Empty(), now, []*bc.Tx{&tx1, &tx2})
 	b.StopTimer()
 	if err != nil {
 		b.Fatal(err)
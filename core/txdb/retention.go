@@ -0,0 +1,166 @@
+package txdb
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+)
+
+// A RetentionPolicy says which state snapshots to keep.
+// Whatever it says, PruneSnapshots always keeps the latest
+// snapshot, the latest full snapshot, and the full snapshot
+// each kept delta snapshot is relative to.
+type RetentionPolicy struct {
+	// KeepLast is how many of the most recent
+	// snapshots to keep.
+	KeepLast int
+
+	// KeepDailyDays is how many days, counting today, to keep
+	// the latest snapshot from. Days are in UTC.
+	KeepDailyDays int
+}
+
+// DefaultRetentionPolicy keeps a day of hourly
+// snapshots and a week of daily ones.
+var DefaultRetentionPolicy = RetentionPolicy{
+	KeepLast:      24,
+	KeepDailyDays: 7,
+}
+
+// SnapshotInfo describes a stored state snapshot.
+type SnapshotInfo struct {
+	Height uint64 `json:"height"`
+
+	// BaseHeight is the height of the full snapshot
+	// a delta snapshot is relative to, or 0 for a
+	// full snapshot.
+	BaseHeight uint64    `json:"base_height"`
+	Size       uint64    `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ListSnapshots returns the stored state snapshots,
+// most recent first.
+func (s *Store) ListSnapshots(ctx context.Context) ([]*SnapshotInfo, error) {
+	const q = `
+		SELECT height, base_height, octet_length(data), created_at::timestamptz
+		FROM snapshots ORDER BY height DESC
+	`
+	infos := []*SnapshotInfo{} // not null
+	err := pg.ForQueryRows(ctx, s.db, q, func(height, baseHeight, size uint64, createdAt time.Time) {
+		infos = append(infos, &SnapshotInfo{
+			Height:     height,
+			BaseHeight: baseHeight,
+			Size:       size,
+			CreatedAt:  createdAt,
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing snapshots")
+	}
+	return infos, nil
+}
+
+// PruneSnapshots deletes the stored state snapshots
+// that p doesn't keep. It returns the number deleted.
+func (s *Store) PruneSnapshots(ctx context.Context, p RetentionPolicy) (int, error) {
+	infos, err := s.ListSnapshots(ctx)
+	if err != nil {
+		return 0, err
+	}
+	prune := snapshotsToPrune(infos, p, time.Now())
+	if len(prune) == 0 {
+		return 0, nil
+	}
+
+	const q = `DELETE FROM snapshots WHERE height IN (SELECT unnest($1::bigint[]))`
+	_, err = s.db.Exec(ctx, q, pq.Int64Array(prune))
+	if err != nil {
+		return 0, errors.Wrap(err, "deleting snapshots")
+	}
+	return len(prune), nil
+}
+
+// PruneSnapshotsPeriodically calls PruneSnapshots with p every
+// period. It blocks and only returns when ctx is cancelled.
+func (s *Store) PruneSnapshotsPeriodically(ctx context.Context, p RetentionPolicy, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			n, err := s.PruneSnapshots(ctx, p)
+			if err != nil {
+				log.Error(ctx, err)
+			} else if n > 0 {
+				log.Messagef(ctx, "pruned %d state snapshots", n)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// snapshotsToPrune returns the heights of the snapshots in
+// infos that p doesn't keep as of now.
+func snapshotsToPrune(infos []*SnapshotInfo, p RetentionPolicy, now time.Time) []int64 {
+	infos = append([]*SnapshotInfo(nil), infos...)
+	sort.Sort(byHeightDesc(infos))
+
+	keep := make(map[uint64]bool)
+	for i, info := range infos {
+		if i < p.KeepLast || i == 0 {
+			keep[info.Height] = true
+		}
+	}
+	for _, info := range infos {
+		if info.BaseHeight == 0 {
+			keep[info.Height] = true // the latest full snapshot
+			break
+		}
+	}
+
+	// Keep the latest snapshot from each of the last
+	// KeepDailyDays days.
+	today := utcDay(now)
+	days := make(map[time.Time]bool)
+	for _, info := range infos {
+		day := utcDay(info.CreatedAt)
+		if days[day] || day.After(today) || !day.After(today.AddDate(0, 0, -p.KeepDailyDays)) {
+			continue
+		}
+		days[day] = true
+		keep[info.Height] = true
+	}
+
+	for _, info := range infos {
+		if keep[info.Height] && info.BaseHeight > 0 {
+			keep[info.BaseHeight] = true
+		}
+	}
+
+	var prune []int64
+	for _, info := range infos {
+		if !keep[info.Height] {
+			prune = append(prune, int64(info.Height))
+		}
+	}
+	return prune
+}
+
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+type byHeightDesc []*SnapshotInfo
+
+func (a byHeightDesc) Len() int           { return len(a) }
+func (a byHeightDesc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byHeightDesc) Less(i, j int) bool { return a[i].Height > a[j].Height }
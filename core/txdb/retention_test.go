@@ -0,0 +1,67 @@
+package txdb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotsToPrune(t *testing.T) {
+	now := time.Date(2017, 3, 10, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(n int) time.Time { return now.Add(-time.Duration(n) * time.Hour) }
+
+	cases := []struct {
+		infos  []*SnapshotInfo
+		policy RetentionPolicy
+		want   []int64
+	}{
+		{ // nothing stored
+			policy: DefaultRetentionPolicy,
+		},
+		{ // keep last 2
+			infos: []*SnapshotInfo{
+				{Height: 1, CreatedAt: hoursAgo(3)},
+				{Height: 2, CreatedAt: hoursAgo(2)},
+				{Height: 3, CreatedAt: hoursAgo(1)},
+			},
+			policy: RetentionPolicy{KeepLast: 2},
+			want:   []int64{1},
+		},
+		{ // always keep the latest, even with an empty policy
+			infos: []*SnapshotInfo{
+				{Height: 1, CreatedAt: hoursAgo(2)},
+				{Height: 2, CreatedAt: hoursAgo(1)},
+			},
+			want: []int64{1},
+		},
+		{ // keep the bases of kept deltas
+			infos: []*SnapshotInfo{
+				{Height: 1, CreatedAt: hoursAgo(4)},
+				{Height: 2, CreatedAt: hoursAgo(3)},
+				{Height: 3, BaseHeight: 2, CreatedAt: hoursAgo(2)},
+				{Height: 4, BaseHeight: 2, CreatedAt: hoursAgo(1)},
+			},
+			policy: RetentionPolicy{KeepLast: 1},
+			want:   []int64{3, 1},
+		},
+		{ // keep the latest snapshot from each of the last 3 days
+			infos: []*SnapshotInfo{
+				{Height: 1, CreatedAt: hoursAgo(24 * 3)},
+				{Height: 2, CreatedAt: hoursAgo(24*2 + 1)},
+				{Height: 3, CreatedAt: hoursAgo(24 * 2)},
+				{Height: 4, CreatedAt: hoursAgo(24)},
+				{Height: 5, CreatedAt: hoursAgo(2)},
+				{Height: 6, CreatedAt: hoursAgo(1)},
+			},
+			policy: RetentionPolicy{KeepLast: 1, KeepDailyDays: 3},
+			want:   []int64{5, 2, 1},
+		},
+	}
+
+	for i, c := range cases {
+		got := snapshotsToPrune(c.infos, c.policy, now)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("case %d: snapshotsToPrune = %v want %v", i, got, c.want)
+		}
+	}
+}
@@ -3,103 +3,63 @@ package txdb
 import (
 	"context"
 
-	"github.com/golang/protobuf/proto"
-
-	"chain/core/txdb/internal/storage"
+	"chain/core/snapshotenc"
 	"chain/database/pg"
 	"chain/database/sql"
 	"chain/errors"
-	"chain/protocol/bc"
-	"chain/protocol/patricia"
 	"chain/protocol/state"
 )
 
-// DecodeSnapshot decodes a snapshot from the Chain Core's binary,
-// protobuf representation of the snapshot.
-func DecodeSnapshot(data []byte) (*state.Snapshot, error) {
-	var storedSnapshot storage.Snapshot
-	err := proto.Unmarshal(data, &storedSnapshot)
-	if err != nil {
-		return nil, errors.Wrap(err, "unmarshaling state snapshot proto")
-	}
-
-	tree := new(patricia.Tree)
-	for _, node := range storedSnapshot.Nodes {
-		err = tree.Insert(node.Key)
-		if err != nil {
-			return nil, errors.Wrap(err, "reconstructing state tree")
-		}
-	}
-
-	issuances := make(state.PriorIssuances, len(storedSnapshot.Issuances))
-	for _, issuance := range storedSnapshot.Issuances {
-		var hash bc.Hash
-		copy(hash[:], issuance.Hash)
-		issuances[hash] = issuance.ExpiryMs
-	}
-
-	return &state.Snapshot{
-		Tree:      tree,
-		Issuances: issuances,
-	}, nil
-}
-
 func storeStateSnapshot(ctx context.Context, db pg.DB, snapshot *state.Snapshot, blockHeight uint64) error {
-	var storedSnapshot storage.Snapshot
-	err := patricia.Walk(snapshot.Tree, func(key []byte) error {
-		n := &storage.Snapshot_StateTreeNode{Key: key}
-		storedSnapshot.Nodes = append(storedSnapshot.Nodes, n)
-		return nil
-	})
-	if err != nil {
-		return errors.Wrap(err, "walking patricia tree")
-	}
-
-	storedSnapshot.Issuances = make([]*storage.Snapshot_Issuance, 0, len(snapshot.Issuances))
-	for k, v := range snapshot.Issuances {
-		hash := k
-		storedSnapshot.Issuances = append(storedSnapshot.Issuances, &storage.Snapshot_Issuance{
-			Hash:     hash[:],
-			ExpiryMs: v,
-		})
-	}
-
-	b, err := proto.Marshal(&storedSnapshot)
+	b, err := snapshotenc.Encode(snapshot)
 	if err != nil {
-		return errors.Wrap(err, "marshaling state snapshot")
+		return err
 	}
+	return insertSnapshot(ctx, db, b, blockHeight, 0)
+}
 
-	const insertQ = `
-		INSERT INTO snapshots (height, data) VALUES($1, $2)
-		ON CONFLICT (height) DO UPDATE SET data = $2, created_at = NOW()
+// insertSnapshot stores encoded snapshot data, which is a delta
+// if baseHeight is nonzero. Old snapshots are deleted separately,
+// by PruneSnapshots.
+func insertSnapshot(ctx context.Context, db pg.DB, data []byte, blockHeight, baseHeight uint64) error {
+	const q = `
+		INSERT INTO snapshots (height, data, base_height) VALUES($1, $2, $3)
+		ON CONFLICT (height) DO UPDATE SET data = $2, base_height = $3, created_at = NOW()
 	`
-	_, err = db.Exec(ctx, insertQ, blockHeight, b)
-	if err != nil {
-		return errors.Wrap(err, "writing state snapshot to database")
-	}
-
-	const deleteQ = `DELETE FROM snapshots WHERE created_at < NOW() - INTERVAL '24 hours'`
-	_, err = db.Exec(ctx, deleteQ)
-	return errors.Wrap(err, "deleting old snapshots")
+	_, err := db.Exec(ctx, q, blockHeight, data, baseHeight)
+	return errors.Wrap(err, "writing state snapshot to database")
 }
 
 func getStateSnapshot(ctx context.Context, db pg.DB) (*state.Snapshot, uint64, error) {
 	const q = `
-		SELECT data, height FROM snapshots ORDER BY height DESC LIMIT 1
+		SELECT data, height, base_height FROM snapshots ORDER BY height DESC LIMIT 1
 	`
 	var (
-		data   []byte
-		height uint64
+		data       []byte
+		height     uint64
+		baseHeight uint64
+		base       *state.Snapshot
 	)
 
-	err := db.QueryRow(ctx, q).Scan(&data, &height)
+	err := db.QueryRow(ctx, q).Scan(&data, &height, &baseHeight)
 	if err == sql.ErrNoRows {
 		return state.Empty(), 0, nil
 	} else if err != nil {
 		return nil, height, errors.Wrap(err, "retrieving state snapshot blob")
 	}
 
-	snapshot, err := DecodeSnapshot(data)
+	if baseHeight > 0 {
+		baseData, err := getRawSnapshot(ctx, db, baseHeight)
+		if err != nil {
+			return nil, height, errors.Wrapf(err, "retrieving base snapshot at height %d", baseHeight)
+		}
+		base, err = snapshotenc.Decode(baseData, nil)
+		if err != nil {
+			return nil, height, errors.Wrap(err, "decoding base snapshot")
+		}
+	}
+
+	snapshot, err := snapshotenc.Decode(data, base)
 	if err != nil {
 		return nil, height, errors.Wrap(err, "decoding snapshot")
 	}
@@ -97,6 +97,55 @@ func TestReadWriteStateSnapshot(t *testing.T) {
 	}
 }
 
+func TestSaveSnapshotAfterRestart(t *testing.T) {
+	dbtx := pgtest.NewTx(t)
+	ctx := context.Background()
+
+	snapshot := state.Empty()
+	for i := 0; i < 100; i++ {
+		err := snapshot.Tree.Insert([]byte{byte(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := NewStore(dbtx).SaveSnapshot(ctx, 1, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new Store, as after a restart, saves a delta
+	// relative to the full snapshot in the database.
+	err = snapshot.Tree.Insert([]byte{0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(dbtx)
+	err = s.SaveSnapshot(ctx, 2, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := s.ListSnapshots(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Height != 2 || infos[0].BaseHeight != 1 {
+		t.Fatalf("got snapshots %+v, want a delta at height 2 relative to height 1", infos)
+	}
+
+	// Pruning keeps the base the delta needs.
+	_, err = s.PruneSnapshots(ctx, RetentionPolicy{KeepLast: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, height, err := s.LatestSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 2 || got.Tree.RootHash() != snapshot.Tree.RootHash() {
+		t.Errorf("latest snapshot at height %d has root %x, want height 2 and root %x", height, got.Tree.RootHash().Bytes(), snapshot.Tree.RootHash().Bytes())
+	}
+}
+
 func BenchmarkStoreSnapshot100(b *testing.B) {
 	benchmarkStoreSnapshot(100, 100, b)
 }
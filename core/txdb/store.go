@@ -2,14 +2,21 @@ package txdb
 
 import (
 	"context"
+	"sync"
 
+	"chain/core/snapshotenc"
 	"chain/database/pg"
+	"chain/database/sql"
 	"chain/errors"
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/state"
 )
 
+// maxSnapshotDeltas is the most delta snapshots SaveSnapshot
+// stores before compacting them into a full snapshot.
+const maxSnapshotDeltas = 24
+
 // A Store encapsulates storage for blockchain validation.
 // It satisfies the interface protocol.Store, and provides additional
 // methods for querying current data.
@@ -17,6 +24,10 @@ type Store struct {
 	db pg.DB
 
 	cache blockCache
+
+	snapshotMu sync.Mutex // protects the following
+	base       *state.Snapshot
+	baseHeight uint64
 }
 
 var _ protocol.Store = (*Store)(nil)
@@ -63,15 +74,31 @@ func (s *Store) LatestSnapshot(ctx context.Context) (*state.Snapshot, uint64, er
 }
 
 // LatestSnapshotInfo returns the height and size of the most recent
-// state snapshot stored in the database.
+// full state snapshot stored in the database.
 func (s *Store) LatestSnapshotInfo(ctx context.Context) (height uint64, size uint64, err error) {
 	const q = `
-		SELECT height, octet_length(data) FROM snapshots ORDER BY height DESC LIMIT 1
+		SELECT height, octet_length(data) FROM snapshots
+		WHERE base_height = 0 ORDER BY height DESC LIMIT 1
 	`
 	err = s.db.QueryRow(ctx, q).Scan(&height, &size)
 	return height, size, err
 }
 
+// LatestSnapshotDeltaInfo returns the height and size of the most
+// recent delta snapshot relative to the full snapshot at baseHeight.
+// If there is none, it returns zero.
+func (s *Store) LatestSnapshotDeltaInfo(ctx context.Context, baseHeight uint64) (height uint64, size uint64, err error) {
+	const q = `
+		SELECT height, octet_length(data) FROM snapshots
+		WHERE base_height = $1 AND base_height > 0 ORDER BY height DESC LIMIT 1
+	`
+	err = s.db.QueryRow(ctx, q, baseHeight).Scan(&height, &size)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	return height, size, err
+}
+
 // GetSnapshot returns the state snapshot stored at the provided height,
 // in Chain Core's binary protobuf representation. If no snapshot exists
 // at the provided height, an error is returned.
@@ -79,6 +106,21 @@ func (s *Store) GetSnapshot(ctx context.Context, height uint64) ([]byte, error)
 	return getRawSnapshot(ctx, s.db, height)
 }
 
+// GetSnapshotChunk returns at most n bytes of the raw snapshot
+// stored at the provided height, starting at offset. It returns
+// no data if offset is past the end of the snapshot. If no
+// snapshot exists at the provided height, an error is returned.
+func (s *Store) GetSnapshotChunk(ctx context.Context, height uint64, offset, n int) ([]byte, error) {
+	// Postgres byte offsets start at 1.
+	const q = `SELECT substring(data FROM $2 FOR $3) FROM snapshots WHERE height = $1`
+	var data []byte
+	err := s.db.QueryRow(ctx, q, height, offset+1, n).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, pg.ErrUserInputNotFound
+	}
+	return data, err
+}
+
 // SaveBlock persists a new block in the database.
 func (s *Store) SaveBlock(ctx context.Context, block *bc.Block) error {
 	const q = `
@@ -95,10 +137,81 @@ func (s *Store) SaveBlock(ctx context.Context, block *bc.Block) error {
 	return nil
 }
 
-// SaveSnapshot saves a state snapshot to the database.
+// SaveSnapshot saves a state snapshot to the database. When it can,
+// it saves only the changes since the latest full snapshot in the
+// database. It saves a full snapshot instead when there is none,
+// after maxSnapshotDeltas deltas, or when the delta would be more
+// than half the size of the full snapshot.
 func (s *Store) SaveSnapshot(ctx context.Context, height uint64, snapshot *state.Snapshot) error {
-	err := storeStateSnapshot(ctx, s.db, snapshot, height)
-	return errors.Wrap(err, "saving state tree")
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	baseSize, deltas, err := s.loadSnapshotBase(ctx)
+	if err != nil {
+		return errors.Wrap(err, "loading base snapshot")
+	}
+
+	if s.base != nil && height > s.baseHeight && deltas < maxSnapshotDeltas {
+		data, err := snapshotenc.EncodeDelta(s.base, s.baseHeight, snapshot)
+		if err != nil {
+			return errors.Wrap(err, "encoding state tree delta")
+		}
+		if len(data) <= baseSize/2 {
+			err = insertSnapshot(ctx, s.db, data, height, s.baseHeight)
+			return errors.Wrap(err, "saving state tree delta")
+		}
+	}
+
+	data, err := snapshotenc.Encode(snapshot)
+	if err != nil {
+		return errors.Wrap(err, "encoding state tree")
+	}
+	err = insertSnapshot(ctx, s.db, data, height, 0)
+	if err != nil {
+		return errors.Wrap(err, "saving state tree")
+	}
+	s.base = state.Copy(snapshot)
+	s.baseHeight = height
+	return nil
+}
+
+// loadSnapshotBase sets s.base to the latest full snapshot in
+// the database, reading it only if it isn't already cached.
+// This keeps deltas relative to a snapshot that's stored, even
+// after a restart or when another process saved a newer one.
+// It returns the size of the full snapshot and the number of
+// deltas stored relative to it. If there is no full snapshot,
+// it sets s.base to nil.
+func (s *Store) loadSnapshotBase(ctx context.Context) (size, deltas int, err error) {
+	const q = `
+		SELECT height, octet_length(data),
+			(SELECT COUNT(*) FROM snapshots d WHERE d.base_height = f.height)
+		FROM snapshots f
+		WHERE base_height = 0 ORDER BY height DESC LIMIT 1
+	`
+	var height uint64
+	err = s.db.QueryRow(ctx, q).Scan(&height, &size, &deltas)
+	if err == sql.ErrNoRows {
+		s.base = nil
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, errors.Wrap(err, "finding latest full snapshot")
+	}
+	if s.base != nil && s.baseHeight == height {
+		return size, deltas, nil
+	}
+
+	data, err := getRawSnapshot(ctx, s.db, height)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "reading full snapshot at height %d", height)
+	}
+	base, err := snapshotenc.Decode(data, nil)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "decoding full snapshot")
+	}
+	s.base = base
+	s.baseHeight = height
+	return size, deltas, nil
 }
 
 func (s *Store) FinalizeBlock(ctx context.Context, height uint64) error {
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+
+	"chain/core/leader"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/lightclient"
+	"chain/protocol/validation"
+)
+
+// POST /get-transaction-proof
+//
+// getTxProof returns a merkle proof that the transaction with the
+// given ID is in the block at the given height. If no height is
+// given, it looks up the block in the transaction index, which
+// requires INDEX_TRANSACTIONS (the default) to be enabled.
+// The proof can be checked against the block header, and the
+// header against the signatures in its witness, without trusting
+// this core.
+func (a *API) getTxProof(ctx context.Context, x struct {
+	ID          bc.Hash `json:"id"`
+	BlockHeight uint64  `json:"block_height"`
+}) (*lightclient.TxProof, error) {
+	height := x.BlockHeight
+	if height == 0 {
+		const q = `SELECT block_height FROM annotated_txs WHERE tx_hash = $1`
+		err := a.DB.QueryRow(ctx, q, x.ID[:]).Scan(&height)
+		if err == sql.ErrNoRows {
+			return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "transaction %s is not indexed; give its block_height, or enable INDEX_TRANSACTIONS", x.ID)
+		} else if err != nil {
+			return nil, errors.Wrap(err, "looking up transaction")
+		}
+	}
+	if height > a.Chain.Height() {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "no block at height %d", height)
+	}
+
+	b, err := a.Chain.GetBlock(ctx, height)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting block %d", height)
+	}
+	for i, tx := range b.Transactions {
+		if tx.ID != x.ID {
+			continue
+		}
+		proof, err := validation.CalcMerkleProof(b.Transactions, i)
+		if err != nil {
+			return nil, errors.Wrap(err, "computing merkle proof")
+		}
+		return &lightclient.TxProof{
+			ID:          x.ID,
+			BlockID:     b.Hash(),
+			BlockHeader: &b.BlockHeader,
+			Proof:       proof,
+		}, nil
+	}
+	return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "transaction %s is not in block %d", x.ID, height)
+}
+
+// getOutputProofRPC returns a proof of whether the output with
+// the given ID is unspent as of the latest block. The proof can be
+// checked against the block header's AssetsMerkleRoot.
+func (a *API) getOutputProofRPC(ctx context.Context, x struct {
+	OutputID bc.Hash `json:"output_id"`
+}) (*lightclient.OutputProof, error) {
+	// Only the leader has a current state snapshot.
+	if !leader.IsLeading() {
+		var resp lightclient.OutputProof
+		err := a.forwardToLeader(ctx, networkRPCPrefix+"get-output-proof", x, &resp)
+		return &resp, err
+	}
+
+	b, snapshot := a.Chain.State()
+	if b == nil {
+		return nil, errors.Wrap(protocol.ErrStaleState)
+	}
+	proof := snapshot.Tree.Prove(x.OutputID.Bytes())
+	return &lightclient.OutputProof{
+		OutputID:    x.OutputID,
+		Unspent:     proof.Path != nil,
+		BlockHeader: &b.BlockHeader,
+		Proof:       proof,
+	}, nil
+}
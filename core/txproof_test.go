@@ -0,0 +1,41 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+	"chain/protocol/validation"
+)
+
+func TestGetTxProof(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	tx1 := prottest.NewIssuanceTx(t, c)
+	tx2 := prottest.NewIssuanceTx(t, c)
+	b := prottest.MakeBlock(t, c, []*bc.Tx{tx1, tx2})
+
+	a := &API{Chain: c}
+	type req struct {
+		ID          bc.Hash `json:"id"`
+		BlockHeight uint64  `json:"block_height"`
+	}
+	res, err := a.getTxProof(ctx, req{ID: tx2.ID, BlockHeight: b.Height})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BlockID != b.Hash() || res.Proof.Position != 1 {
+		t.Errorf("got block %s position %d, want block %s position 1", res.BlockID, res.Proof.Position, b.Hash())
+	}
+	if !validation.VerifyMerkleProof(res.BlockHeader.TransactionsMerkleRoot, tx2.ID, res.Proof) {
+		t.Error("proof didn't verify")
+	}
+
+	_, err = a.getTxProof(ctx, req{ID: tx2.ID, BlockHeight: 1})
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("got error %v, want %v", err, pg.ErrUserInputNotFound)
+	}
+}
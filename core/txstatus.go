@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+
+	"chain/core/generator"
+	"chain/core/leader"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// txConfirmed is the status of a transaction that is in a block.
+// Other statuses are the generator's TxUnknown, TxPending and
+// TxRejected.
+const txConfirmed = "confirmed"
+
+// txStatus describes where a transaction is in its
+// submission lifecycle.
+type txStatus struct {
+	ID     bc.Hash `json:"id"`
+	Status string  `json:"status"`
+	Reason string  `json:"reason,omitempty"`
+
+	// Set only when Status is "confirmed".
+	BlockHeight uint64   `json:"block_height,omitempty"`
+	BlockID     *bc.Hash `json:"block_id,omitempty"`
+	Position    *uint32  `json:"position,omitempty"`
+}
+
+// POST /get-transaction-status
+//
+// getTxStatus reports whether a transaction is confirmed,
+// pending in the generator's pool, rejected by the generator,
+// or unknown.
+//
+// Confirmation is detected only for transactions submitted
+// through this core within the last day, using the height
+// recorded in submitted_txs as the starting point of the search.
+// Pending and rejected statuses come from the generator, so they
+// apply no matter which core submitted the transaction.
+func (a *API) getTxStatus(ctx context.Context, x struct {
+	ID bc.Hash `json:"id"`
+}) (*txStatus, error) {
+	if !leader.IsLeading() {
+		var resp txStatus
+		err := a.forwardToLeader(ctx, "/get-transaction-status", x, &resp)
+		return &resp, err
+	}
+
+	height, err := submittedHeight(ctx, a.DB, x.ID)
+	if err == nil {
+		res, err := a.findConfirmedTx(ctx, x.ID, height)
+		if err != nil || res != nil {
+			return res, err
+		}
+	} else if err != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "looking up submitted tx")
+	}
+
+	res := &txStatus{ID: x.ID, Status: generator.TxUnknown}
+	if s, ok := a.Submitter.(txStatuser); ok {
+		gs, err := s.TxStatus(ctx, x.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting status from generator")
+		}
+		res.Status, res.Reason = gs.Status, gs.Reason
+	}
+	return res, nil
+}
+
+// findConfirmedTx searches the blocks after height for the tx
+// with the given ID. If it isn't found, it advances the tx's
+// submitted height so later searches resume where this one
+// left off.
+func (a *API) findConfirmedTx(ctx context.Context, id bc.Hash, height uint64) (*txStatus, error) {
+	last := a.Chain.Height()
+	for h := height + 1; h <= last; h++ {
+		b, err := a.Chain.GetBlock(ctx, h)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting block %d", h)
+		}
+		for i, tx := range b.Transactions {
+			if tx.ID == id {
+				blockID := b.Hash()
+				pos := uint32(i)
+				return &txStatus{
+					ID:          id,
+					Status:      txConfirmed,
+					BlockHeight: h,
+					BlockID:     &blockID,
+					Position:    &pos,
+				}, nil
+			}
+		}
+	}
+	if last > height {
+		const q = `UPDATE submitted_txs SET height = $2 WHERE tx_hash = $1 AND height < $2`
+		_, err := a.DB.Exec(ctx, q, id[:], last)
+		if err != nil {
+			return nil, errors.Wrap(err, "advancing submitted height")
+		}
+	}
+	return nil, nil
+}
+
+// submittedHeight returns the height recorded by recordSubmittedTx
+// for the tx with the given ID. If there is none, it returns
+// sql.ErrNoRows.
+func submittedHeight(ctx context.Context, db pg.DB, txHash bc.Hash) (uint64, error) {
+	const q = `SELECT height FROM submitted_txs WHERE tx_hash = $1`
+	var height uint64
+	err := db.QueryRow(ctx, q, txHash[:]).Scan(&height)
+	return height, err
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"chain/core/generator"
+	"chain/database/pg/pgtest"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestFindConfirmedTx(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	c := prottest.NewChain(t)
+	g := generator.New(c, nil, db)
+	a := &API{Chain: c, DB: db, Submitter: g}
+
+	tx := prottest.NewIssuanceTx(t, c)
+	height, err := recordSubmittedTx(ctx, db, tx.ID, c.Height())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	lost := bc.Hash{1}
+	_, err = recordSubmittedTx(ctx, db, lost, c.Height())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	err = g.Submit(ctx, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	prottest.MakeBlock(t, c, []*bc.Tx{prottest.NewIssuanceTx(t, c)})
+	prottest.MakeBlock(t, c, g.PendingTxs())
+
+	got, err := a.findConfirmedTx(ctx, tx.ID, height)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got == nil || got.Status != txConfirmed || got.BlockHeight != c.Height() || *got.Position != 0 {
+		t.Errorf("findConfirmedTx(%s) = %+v, want confirmed at height %d position 0", tx.ID, got, c.Height())
+	}
+
+	got, err = a.findConfirmedTx(ctx, lost, height)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got != nil {
+		t.Errorf("findConfirmedTx(%s) = %+v, want nil", lost, got)
+	}
+	h, err := submittedHeight(ctx, db, lost)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if h != c.Height() {
+		t.Errorf("submitted height = %d, want %d", h, c.Height())
+	}
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"chain/core/leader"
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/state"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+)
+
+// Values of inputReport.SpentOutputStatus.
+const (
+	outputUnspent = "unspent"
+	outputMissing = "spent_or_nonexistent"
+)
+
+// txReport describes the result of checking a single transaction
+// against the current blockchain state without submitting it.
+type txReport struct {
+	ID     bc.Hash        `json:"id"`
+	Valid  bool           `json:"valid"`
+	Error  *detailedError `json:"error,omitempty"`
+	Inputs []*inputReport `json:"inputs"`
+}
+
+type inputReport struct {
+	Position          int            `json:"position"`
+	Type              string         `json:"type"`
+	SpentOutputID     *bc.Hash       `json:"spent_output_id,omitempty"`
+	SpentOutputStatus string         `json:"spent_output_status,omitempty"`
+	VMResult          bool           `json:"vm_result"`
+	Error             *detailedError `json:"error,omitempty"`
+}
+
+type validateArg struct {
+	Transactions []txbuilder.Template `json:"transactions"`
+}
+
+// POST /validate-transaction
+//
+// validateTx performs the checks a generator would perform on
+// each transaction before including it in a block, but doesn't
+// submit anything.
+func (a *API) validateTx(ctx context.Context, x validateArg) (interface{}, error) {
+	// Only the leader has a current state snapshot.
+	if !leader.IsLeading() {
+		var resp json.RawMessage
+		err := a.forwardToLeader(ctx, "/validate-transaction", x, &resp)
+		return resp, err
+	}
+
+	prev, snapshot := a.Chain.State()
+	if prev == nil {
+		return nil, errors.Wrap(protocol.ErrStaleState)
+	}
+
+	now := time.Now()
+	if now.Before(prev.Time()) {
+		now = prev.Time()
+	}
+
+	responses := make([]interface{}, len(x.Transactions))
+	for i, tpl := range x.Transactions {
+		func() {
+			defer batchRecover(ctx, &responses[i])
+			if tpl.Transaction == nil {
+				responses[i] = errors.Wrap(txbuilder.ErrMissingRawTx)
+				return
+			}
+			responses[i] = checkTx(a.Chain, snapshot, bc.Millis(now), tpl.Transaction)
+		}()
+	}
+	return responses, nil
+}
+
+// checkTx validates tx as the generator would when adding it to a
+// block with the given timestamp on top of snapshot. In addition to
+// the first error encountered, it reports the VM result and spent
+// output status of every input.
+func checkTx(c *protocol.Chain, snapshot *state.Snapshot, timestampMS uint64, tx *bc.Tx) *txReport {
+	r := &txReport{ID: tx.ID}
+	for i, in := range tx.Inputs {
+		ir := &inputReport{Position: i}
+		switch in.TypedInput.(type) {
+		case *bc.IssuanceInput:
+			ir.Type = "issue"
+		case *bc.SpendInput:
+			ir.Type = "spend"
+			outputID := in.SpentOutputID()
+			ir.SpentOutputID = &outputID
+			ir.SpentOutputStatus = outputMissing
+			if snapshot.Tree.Contains(outputID.Bytes()) {
+				ir.SpentOutputStatus = outputUnspent
+			}
+		}
+		err := vm.VerifyTxInput(tx, uint32(i))
+		if err != nil {
+			ir.Error = rejection(errors.WithDetail(err, err.Error()))
+		} else {
+			ir.VMResult = true
+		}
+		r.Inputs = append(r.Inputs, ir)
+	}
+
+	err := validation.CheckTxWellFormed(tx)
+	if err == nil {
+		err = c.CheckIssuanceWindow(tx)
+	}
+	if err == nil {
+		err = validation.ConfirmTx(snapshot, c.InitialBlockHash, bc.NewBlockVersion, timestampMS, tx)
+	}
+	if err != nil {
+		r.Error = rejection(err)
+	}
+	r.Valid = r.Error == nil
+	return r
+}
+
+// rejection converts a validation error into the response body
+// that would be returned had the transaction been submitted.
+func rejection(err error) *detailedError {
+	body, _ := errInfo(errors.Sub(txbuilder.ErrRejected, err))
+	return &body
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+)
+
+func TestCheckTx(t *testing.T) {
+	c := prottest.NewChain(t)
+	tx := prottest.NewIssuanceTx(t, c)
+
+	_, snapshot := c.State()
+	r := checkTx(c, snapshot, bc.Millis(time.Now()), tx)
+	if !r.Valid || r.Error != nil {
+		t.Fatalf("checkTx(issuance) = %+v, want valid", r.Error)
+	}
+	if len(r.Inputs) != 1 || r.Inputs[0].Type != "issue" || !r.Inputs[0].VMResult {
+		t.Errorf("got inputs %+v, want one successful issuance", r.Inputs)
+	}
+
+	// Once the issuance is in a block, it can't be repeated.
+	prottest.MakeBlock(t, c, []*bc.Tx{tx})
+	_, snapshot = c.State()
+	r = checkTx(c, snapshot, bc.Millis(time.Now()), tx)
+	if r.Valid || r.Error == nil || r.Error.ChainCode != "CH735" {
+		t.Errorf("checkTx(duplicate issuance) = %+v, want CH735 rejection", r.Error)
+	}
+
+	// Spending an output that isn't in the state tree.
+	spend := bc.NewTx(bc.TxData{
+		Version: 1,
+		Inputs: []*bc.TxInput{
+			bc.NewSpendInput(bc.Hash{1}, nil, bc.AssetID{}, 1, []byte{0x51}, nil),
+		},
+		Outputs: []*bc.TxOutput{
+			bc.NewTxOutput(bc.AssetID{}, 1, []byte{0x51}, nil),
+		},
+	})
+	r = checkTx(c, snapshot, bc.Millis(time.Now()), spend)
+	if r.Valid {
+		t.Error("checkTx(spend of missing output) valid, want invalid")
+	}
+	if len(r.Inputs) != 1 || r.Inputs[0].SpentOutputStatus != outputMissing {
+		t.Errorf("got inputs %+v, want spent output status %q", r.Inputs, outputMissing)
+	}
+}
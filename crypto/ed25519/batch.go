@@ -0,0 +1,177 @@
+package ed25519
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"io"
+	"strconv"
+
+	"chain/crypto/ed25519/internal/edwards25519"
+)
+
+// scMinusOne is l-1, where l is the order of the base point.
+var scMinusOne = [32]byte{
+	0xec, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+	0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+}
+
+// scL is l, the order of the base point.
+var scL = [32]byte{
+	0xed, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+	0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+}
+
+// identity is the encoding of the neutral element.
+var identity = [32]byte{1}
+
+// A BatchVerifier checks many signatures at once, which is faster
+// than calling Verify on each. The zero value is an empty batch.
+// A BatchVerifier is not safe for concurrent use.
+type BatchVerifier struct {
+	entries []batchEntry
+}
+
+type batchEntry struct {
+	publicKey PublicKey
+	message   []byte
+	sig       []byte
+}
+
+// Add adds a signature to be checked. It will panic
+// if len(publicKey) is not PublicKeySize.
+func (v *BatchVerifier) Add(publicKey PublicKey, message, sig []byte) {
+	if l := len(publicKey); l != PublicKeySize {
+		panic("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+	v.entries = append(v.entries, batchEntry{publicKey, message, sig})
+}
+
+// Len returns the number of signatures added.
+func (v *BatchVerifier) Len() int {
+	return len(v.entries)
+}
+
+// Verify reports whether every signature added is valid.
+// It accepts exactly the batches in which Verify accepts
+// every signature, except with probability 2^-128. When it
+// returns false, at least one is invalid, and the caller can
+// find which with Verify.
+//
+// It checks a random linear combination of the verification
+// equations of the signatures in a single multi-scalar
+// multiplication. That can't reliably detect an invalid
+// signature whose only defect is a small-order component
+// added to R or the public key, so signatures whose R or
+// public key isn't in the prime-order subgroup are checked
+// individually with Verify instead.
+func (v *BatchVerifier) Verify() bool {
+	n := len(v.entries)
+	if n == 0 {
+		return true
+	}
+
+	// Random 128-bit coefficients for the linear combination.
+	coeffs := make([]byte, 16*n)
+	_, err := io.ReadFull(cryptorand.Reader, coeffs)
+	if err != nil {
+		return false
+	}
+
+	// The combined equation is
+	//   sum(z*R) + sum((z*h)*A) - sum(z*s)*B = 0
+	// where, for each signature, z is its coefficient,
+	// (R, s) is the signature, A is the public key and
+	// h is the hash of R, A and the message.
+	var (
+		scalars = make([][32]byte, 0, 2*n)
+		points  = make([]edwards25519.ExtendedGroupElement, 0, 2*n)
+		zs      [32]byte // sum(z*s)
+		zero    [32]byte
+	)
+	for i, e := range v.entries {
+		if len(e.sig) != SignatureSize || e.sig[63]&224 != 0 {
+			return false
+		}
+
+		var A, R edwards25519.ExtendedGroupElement
+		var publicKeyBytes, encodedR [32]byte
+		copy(publicKeyBytes[:], e.publicKey)
+		if !A.FromBytes(&publicKeyBytes) {
+			return false
+		}
+		copy(encodedR[:], e.sig[:32])
+		if !R.FromBytes(&encodedR) || !isCanonical(&R, &encodedR) {
+			// Verify compares the encoding of the point it
+			// computes with R, so it never accepts these.
+			return false
+		}
+		if !isTorsionFree(&A) || !isTorsionFree(&R) {
+			if !Verify(e.publicKey, e.message, e.sig) {
+				return false
+			}
+			continue
+		}
+
+		h := sha512.New()
+		h.Write(e.sig[:32])
+		h.Write(e.publicKey)
+		h.Write(e.message)
+		var digest [64]byte
+		h.Sum(digest[:0])
+		var hReduced [32]byte
+		edwards25519.ScReduce(&hReduced, &digest)
+
+		var z, zh, s [32]byte
+		copy(z[:], coeffs[16*i:16*(i+1)])
+		copy(s[:], e.sig[32:])
+		edwards25519.ScMulAdd(&zh, &z, &hReduced, &zero)
+		edwards25519.ScMulAdd(&zs, &z, &s, &zs)
+
+		scalars = append(scalars, z, zh)
+		points = append(points, R, A)
+	}
+
+	var negZs [32]byte
+	edwards25519.ScMulAdd(&negZs, &zs, &scMinusOne, &zero)
+
+	var sum edwards25519.ProjectiveGroupElement
+	edwards25519.GeMultiScalarMultVartime(&sum, scalars, points, &negZs)
+	var encodedSum [32]byte
+	sum.ToBytes(&encodedSum)
+	return encodedSum == identity
+}
+
+// isCanonical reports whether s, from which p was decoded,
+// is the encoding ToBytes would produce for p.
+func isCanonical(p *edwards25519.ExtendedGroupElement, s *[32]byte) bool {
+	// The y-coordinate must be less than 2^255-19.
+	if s[31]&0x7f == 0x7f && s[0] >= 0xed {
+		high := true
+		for _, b := range s[1:31] {
+			if b != 0xff {
+				high = false
+				break
+			}
+		}
+		if high {
+			return false
+		}
+	}
+	// The sign bit can't be set if x is 0.
+	return s[31]&0x80 == 0 || edwards25519.FeIsNonZero(&p.X) != 0
+}
+
+// isTorsionFree reports whether p is in the
+// prime-order subgroup, that is, whether l*p
+// is the identity.
+func isTorsionFree(p *edwards25519.ExtendedGroupElement) bool {
+	var lp edwards25519.ProjectiveGroupElement
+	var zero, encoded [32]byte
+	edwards25519.GeDoubleScalarMultVartime(&lp, &scL, p, &zero)
+	lp.ToBytes(&encoded)
+	return encoded == identity
+}
@@ -0,0 +1,168 @@
+package ed25519
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"testing"
+
+	"chain/crypto/ed25519/internal/edwards25519"
+)
+
+func newBatch(tb testing.TB, n int) *BatchVerifier {
+	v := new(BatchVerifier)
+	for i := 0; i < n; i++ {
+		pub, priv, err := GenerateKey(rand.Reader)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		msg := []byte(fmt.Sprintf("message %d", i))
+		v.Add(pub, msg, Sign(priv, msg))
+	}
+	return v
+}
+
+func TestBatchVerify(t *testing.T) {
+	if !new(BatchVerifier).Verify() {
+		t.Error("empty batch rejected")
+	}
+	for _, n := range []int{1, 2, 17} {
+		v := newBatch(t, n)
+		if !v.Verify() {
+			t.Errorf("valid batch of %d rejected", n)
+		}
+
+		for i := range v.entries {
+			e := &v.entries[i]
+
+			msg := e.message
+			e.message = []byte("wrong message")
+			if v.Verify() {
+				t.Errorf("batch of %d with wrong message at %d accepted", n, i)
+			}
+			e.message = msg
+
+			sig := e.sig
+			e.sig = append([]byte(nil), sig...)
+			e.sig[40] ^= 1
+			if v.Verify() {
+				t.Errorf("batch of %d with bad s at %d accepted", n, i)
+			}
+			e.sig[40] ^= 1
+			e.sig[63] |= 224
+			if v.Verify() {
+				t.Errorf("batch of %d with high s bits at %d accepted", n, i)
+			}
+			e.sig = sig
+		}
+	}
+}
+
+func TestBatchVerifyNonCanonicalR(t *testing.T) {
+	pub, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("message")
+
+	// R is the identity, (0, 1), encoded with its sign bit
+	// set. It decodes, but Verify never accepts it.
+	sig := Sign(priv, msg)
+	bad := make([]byte, SignatureSize)
+	bad[0] = 1
+	bad[31] = 0x80
+	copy(bad[32:], sig[32:])
+	if Verify(pub, msg, bad) {
+		t.Fatal("Verify accepted non-canonical R")
+	}
+
+	v := newBatch(t, 3)
+	v.Add(pub, msg, bad)
+	if v.Verify() {
+		t.Error("batch with non-canonical R accepted")
+	}
+}
+
+func TestBatchVerifyMixedOrderR(t *testing.T) {
+	pub, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("message")
+
+	// Sign with R' = r*B + T, where T is (0, -1), the point
+	// of order 2, but compute s for r*B alone. Then
+	// s*B - h*A is r*B, not R', and Verify rejects it, but
+	// the batch equation is off by only z*T, which is the
+	// identity whenever the random coefficient z is even.
+	digest := sha512.Sum512(priv[:32])
+	var a [32]byte
+	copy(a[:], digest[:32])
+	a[0] &= 248
+	a[31] &= 63
+	a[31] |= 64
+
+	var rDigest [64]byte
+	_, err = rand.Read(rDigest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r [32]byte
+	edwards25519.ScReduce(&r, &rDigest)
+	var R edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&R, &r)
+
+	encodedT := [32]byte{0xec}
+	for i := 1; i < 31; i++ {
+		encodedT[i] = 0xff
+	}
+	encodedT[31] = 0x7f
+	var T edwards25519.ExtendedGroupElement
+	if !T.FromBytes(&encodedT) {
+		t.Fatal("can't decode point of order 2")
+	}
+	var cachedT edwards25519.CachedGroupElement
+	T.ToCached(&cachedT)
+	var sum edwards25519.CompletedGroupElement
+	edwards25519.GeAdd(&sum, &R, &cachedT)
+	var mixedR edwards25519.ExtendedGroupElement
+	sum.ToExtended(&mixedR)
+	var encodedR [32]byte
+	mixedR.ToBytes(&encodedR)
+
+	h := sha512.New()
+	h.Write(encodedR[:])
+	h.Write(pub)
+	h.Write(msg)
+	var hDigest [64]byte
+	h.Sum(hDigest[:0])
+	var hReduced, s [32]byte
+	edwards25519.ScReduce(&hReduced, &hDigest)
+	edwards25519.ScMulAdd(&s, &hReduced, &a, &r)
+
+	sig := append(encodedR[:], s[:]...)
+	if Verify(pub, msg, sig) {
+		t.Fatal("Verify accepted mixed-order R")
+	}
+	for i := 0; i < 64; i++ {
+		v := newBatch(t, 2)
+		v.Add(pub, msg, sig)
+		if v.Verify() {
+			t.Fatalf("batch with mixed-order R accepted on try %d", i)
+		}
+	}
+}
+
+func BenchmarkBatchVerification(b *testing.B) {
+	for _, n := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			v := newBatch(b, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !v.Verify() {
+					b.Fatal("valid batch rejected")
+				}
+			}
+		})
+	}
+}
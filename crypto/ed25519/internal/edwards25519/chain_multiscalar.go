@@ -0,0 +1,75 @@
+package edwards25519
+
+// GeMultiScalarMultVartime sets r = a[0]*A[0] + ... + a[n-1]*A[n-1] + b*B
+// where B is the Ed25519 base point. It generalizes
+// GeDoubleScalarMultVartime to many points, sharing the doublings
+// among all of them (Straus's method). It panics if a and A have
+// different lengths.
+func GeMultiScalarMultVartime(r *ProjectiveGroupElement, a [][32]byte, A []ExtendedGroupElement, b *[32]byte) {
+	if len(a) != len(A) {
+		panic("edwards25519: mismatched scalars and points")
+	}
+
+	aSlide := make([][256]int8, len(a))
+	Ai := make([][8]CachedGroupElement, len(A)) // A,3A,5A,7A,9A,11A,13A,15A
+	var bSlide [256]int8
+	var t CompletedGroupElement
+	var u, A2 ExtendedGroupElement
+	var i int
+
+	for k := range A {
+		slide(&aSlide[k], &a[k])
+
+		A[k].ToCached(&Ai[k][0])
+		A[k].Double(&t)
+		t.ToExtended(&A2)
+
+		for j := 0; j < 7; j++ {
+			geAdd(&t, &A2, &Ai[k][j])
+			t.ToExtended(&u)
+			u.ToCached(&Ai[k][j+1])
+		}
+	}
+	slide(&bSlide, b)
+
+	r.Zero()
+
+	for i = 255; i >= 0; i-- {
+		if bSlide[i] != 0 || anyNonzero(aSlide, i) {
+			break
+		}
+	}
+
+	for ; i >= 0; i-- {
+		r.Double(&t)
+
+		for k := range aSlide {
+			if aSlide[k][i] > 0 {
+				t.ToExtended(&u)
+				geAdd(&t, &u, &Ai[k][aSlide[k][i]/2])
+			} else if aSlide[k][i] < 0 {
+				t.ToExtended(&u)
+				geSub(&t, &u, &Ai[k][(-aSlide[k][i])/2])
+			}
+		}
+
+		if bSlide[i] > 0 {
+			t.ToExtended(&u)
+			geMixedAdd(&t, &u, &bi[bSlide[i]/2])
+		} else if bSlide[i] < 0 {
+			t.ToExtended(&u)
+			geMixedSub(&t, &u, &bi[(-bSlide[i])/2])
+		}
+
+		t.ToProjective(r)
+	}
+}
+
+func anyNonzero(slides [][256]int8, i int) bool {
+	for k := range slides {
+		if slides[k][i] != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -14,9 +14,9 @@ import (
 	"chain/protocol/vmutil"
 )
 
-// maxBlockTxs limits the number of transactions
-// included in each block.
-const maxBlockTxs = 10000
+// DefaultMaxBlockTxs is the number of transactions
+// included in each block if Chain.MaxBlockTxs is zero.
+const DefaultMaxBlockTxs = 10000
 
 // saveSnapshotFrequency stores how often to save a state
 // snapshot to the Store.
@@ -42,10 +42,25 @@ func (c *Chain) GetBlock(ctx context.Context, height uint64) (*bc.Block, error)
 // After generating the block, the pending transaction pool will be
 // empty.
 func (c *Chain) GenerateBlock(ctx context.Context, prev *bc.Block, snapshot *state.Snapshot, now time.Time, txs []*bc.Tx) (b *bc.Block, result *state.Snapshot, err error) {
+	return c.GenerateBlockWithRejects(ctx, prev, snapshot, now, txs, nil)
+}
+
+// GenerateBlockWithRejects is like GenerateBlock, but it calls
+// reject (if non-nil) for each transaction in txs that cannot be
+// included in the block, along with the reason.
+//
+// Not every transaction left out of the block is rejected.
+// Transactions that don't fit in the block, or whose min time
+// is later than the block timestamp, might be valid in a later
+// block, and are silently skipped.
+func (c *Chain) GenerateBlockWithRejects(ctx context.Context, prev *bc.Block, snapshot *state.Snapshot, now time.Time, txs []*bc.Tx, reject func(*bc.Tx, error)) (b *bc.Block, result *state.Snapshot, err error) {
 	timestampMS := bc.Millis(now)
 	if timestampMS < prev.TimestampMS {
 		return nil, nil, fmt.Errorf("timestamp %d is earlier than prevblock timestamp %d", timestampMS, prev.TimestampMS)
 	}
+	if reject == nil {
+		reject = func(*bc.Tx, error) {}
+	}
 
 	// Make a copy of the state that we can apply our changes to.
 	result = state.Copy(snapshot)
@@ -63,24 +78,36 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *bc.Block, snapshot *sta
 		},
 	}
 
+	maxBlockTxs := c.MaxBlockTxs
+	if maxBlockTxs <= 0 {
+		maxBlockTxs = DefaultMaxBlockTxs
+	}
+
 	for _, tx := range txs {
 		if len(b.Transactions) >= maxBlockTxs {
 			break
 		}
+		if timestampMS < tx.MinTime {
+			continue // not yet
+		}
 
 		// TODO(jackson): Should this go in ConfirmTx too?
-		err = c.checkIssuanceWindow(tx)
+		err = c.CheckIssuanceWindow(tx)
 		if err != nil {
+			reject(tx, err)
 			continue
 		}
 
-		if validation.ConfirmTx(result, c.InitialBlockHash, bc.NewBlockVersion, timestampMS, tx) == nil {
-			err = validation.ApplyTx(result, tx)
-			if err != nil {
-				return nil, nil, err
-			}
-			b.Transactions = append(b.Transactions, tx)
+		err = validation.ConfirmTx(result, c.InitialBlockHash, bc.NewBlockVersion, timestampMS, tx)
+		if err != nil {
+			reject(tx, err)
+			continue
 		}
+		err = validation.ApplyTx(result, tx)
+		if err != nil {
+			return nil, nil, err
+		}
+		b.Transactions = append(b.Transactions, tx)
 	}
 	b.TransactionsMerkleRoot, err = validation.CalcMerkleRoot(b.Transactions)
 	if err != nil {
@@ -95,7 +122,7 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *bc.Block, snapshot *sta
 // the block has been applied.
 func (c *Chain) ValidateBlock(ctx context.Context, prevState *state.Snapshot, prev, block *bc.Block) (*state.Snapshot, error) {
 	newState := state.Copy(prevState)
-	err := validation.ValidateBlockForAccept(ctx, newState, c.InitialBlockHash, prev, block, c.ValidateTxCached)
+	err := validation.ValidateBlockForAccept(ctx, newState, c.InitialBlockHash, prev, block, c.validateTxsBatch(block.Transactions))
 	if err != nil {
 		return nil, errors.Sub(ErrBadBlock, err)
 	}
@@ -200,7 +227,7 @@ func (c *Chain) ValidateBlockForSig(ctx context.Context, block *bc.Block) error
 	// TODO(kr): cache the applied snapshot, and maybe
 	// we can skip re-applying it later
 	snapshot = state.Copy(snapshot)
-	err := validation.ValidateBlock(ctx, snapshot, c.InitialBlockHash, prev, block, validation.CheckTxWellFormed)
+	err := validation.ValidateBlock(ctx, snapshot, c.InitialBlockHash, prev, block, c.validateTxsBatch(block.Transactions))
 	return errors.Wrap(err, "validation")
 }
 
@@ -209,7 +236,13 @@ func NewInitialBlock(pubkeys []ed25519.PublicKey, nSigs int, timestamp time.Time
 	if err != nil {
 		return nil, err
 	}
+	return NewInitialBlockWithProgram(script, timestamp)
+}
 
+// NewInitialBlockWithProgram is like NewInitialBlock, but the
+// second block must satisfy the consensus program prog, which
+// need not be a multisig program.
+func NewInitialBlockWithProgram(prog []byte, timestamp time.Time) (*bc.Block, error) {
 	root, err := validation.CalcMerkleRoot([]*bc.Tx{}) // calculate the zero value of the tx merkle root
 	if err != nil {
 		return nil, errors.Wrap(err, "calculating zero value of tx merkle root")
@@ -222,7 +255,7 @@ func NewInitialBlock(pubkeys []ed25519.PublicKey, nSigs int, timestamp time.Time
 			TimestampMS: bc.Millis(timestamp),
 			BlockCommitment: bc.BlockCommitment{
 				TransactionsMerkleRoot: root,
-				ConsensusProgram:       script,
+				ConsensusProgram:       prog,
 			},
 		},
 	}
@@ -6,9 +6,11 @@ import (
 	"testing"
 	"time"
 
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/memstore"
 	"chain/protocol/state"
+	"chain/protocol/validation"
 	"chain/testutil"
 )
 
@@ -193,6 +195,75 @@ func TestGenerateBlock(t *testing.T) {
 	}
 }
 
+func TestGenerateBlockWithRejects(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c, b1 := newTestChain(t, now)
+
+	spend := bc.NewTx(bc.TxData{
+		Version: 1,
+		Inputs: []*bc.TxInput{
+			bc.NewSpendInput(bc.Hash{1}, nil, bc.AssetID{}, 1, nil, nil),
+		},
+		Outputs: []*bc.TxOutput{
+			bc.NewTxOutput(bc.AssetID{}, 1, nil, nil),
+		},
+	})
+	future := bc.NewTx(bc.TxData{
+		Version: 1,
+		MinTime: bc.Millis(now.Add(time.Hour)),
+		Inputs: []*bc.TxInput{
+			bc.NewSpendInput(bc.Hash{2}, nil, bc.AssetID{}, 1, nil, nil),
+		},
+		Outputs: []*bc.TxOutput{
+			bc.NewTxOutput(bc.AssetID{}, 1, nil, nil),
+		},
+	})
+
+	rejected := make(map[bc.Hash]error)
+	reject := func(tx *bc.Tx, err error) { rejected[tx.ID] = err }
+	got, _, err := c.GenerateBlockWithRejects(ctx, b1, state.Empty(), now, []*bc.Tx{spend, future}, reject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Transactions) != 0 {
+		t.Errorf("got %d transactions, want 0", len(got.Transactions))
+	}
+	if len(rejected) != 1 {
+		t.Fatalf("got %d rejections, want 1", len(rejected))
+	}
+	if errors.Root(rejected[spend.ID]) != validation.ErrBadTx {
+		t.Errorf("got rejection %v for spend, want %v", rejected[spend.ID], validation.ErrBadTx)
+	}
+}
+
+func TestGenerateBlockMaxTxs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c, b1 := newTestChain(t, now)
+	c.MaxBlockTxs = 1
+
+	var txs []*bc.Tx
+	for i := byte(0); i < 2; i++ {
+		in := bc.NewIssuanceInput([]byte{i}, 50, nil, b1.Hash(), nil, nil, nil)
+		txs = append(txs, bc.NewTx(bc.TxData{
+			Version: 1,
+			MinTime: bc.Millis(now),
+			MaxTime: bc.Millis(now.Add(time.Hour)),
+			Inputs:  []*bc.TxInput{in},
+			Outputs: []*bc.TxOutput{bc.NewTxOutput(in.AssetID(), 50, nil, nil)},
+		}))
+	}
+
+	got, _, err := c.GenerateBlock(ctx, b1, state.Empty(), now, txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Transactions) != 1 || got.Transactions[0].ID != txs[0].ID {
+		t.Errorf("got %d transactions, want only the first", len(got.Transactions))
+	}
+}
+
 func TestValidateBlockForSig(t *testing.T) {
 	initialBlock, err := NewInitialBlock(testutil.TestPubs, 1, time.Now())
 	if err != nil {
@@ -0,0 +1,3 @@
+package lightclient
+
+import _ "chain/protocol/tx" // for BlockHeaderHashFunc init
@@ -0,0 +1,299 @@
+// Package lightclient follows a blockchain without storing
+// blocks or state. It keeps only block headers, each checked
+// against the consensus program of the one before it, and uses
+// them to check proofs that a transaction is in a block or that
+// an output is unspent.
+package lightclient
+
+import (
+	"context"
+	"sync"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/patricia"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+)
+
+var (
+	// ErrUnknownBlock is returned when a proof refers
+	// to a block the client hasn't synced.
+	ErrUnknownBlock = errors.New("unknown block")
+
+	// ErrBadProof is returned when a proof doesn't
+	// match the header it refers to.
+	ErrBadProof = errors.New("invalid proof")
+
+	// ErrStaleProof is returned when an output proof is
+	// for a block older than the caller requires.
+	ErrStaleProof = errors.New("stale proof")
+)
+
+// A Peer is a Core serving the network RPCs.
+// *rpc.Client satisfies this interface.
+type Peer interface {
+	Call(ctx context.Context, path string, request, response interface{}) error
+}
+
+// TxProof shows that a transaction is in a block.
+// Cores serve them from /rpc/get-transaction-proof.
+type TxProof struct {
+	ID          bc.Hash                 `json:"id"`
+	BlockID     bc.Hash                 `json:"block_id"`
+	BlockHeader *bc.BlockHeader         `json:"block_header"`
+	Proof       *validation.MerkleProof `json:"proof"`
+}
+
+// OutputProof shows whether an output is unspent as of a block.
+// Cores serve them from /rpc/get-output-proof.
+type OutputProof struct {
+	OutputID    bc.Hash         `json:"output_id"`
+	Unspent     bool            `json:"unspent"`
+	BlockHeader *bc.BlockHeader `json:"block_header"`
+	Proof       *patricia.Proof `json:"proof"`
+}
+
+// Client follows the blockchain served by a peer.
+type Client struct {
+	peer           Peer
+	checkpoint     uint64 // height of the first trusted block
+	checkpointHash bc.Hash
+
+	mu      sync.Mutex
+	headers []*bc.BlockHeader // headers[i] is at height checkpoint+i
+}
+
+// New returns a Client for the blockchain whose initial
+// block hash is blockchainID. It has no headers until
+// Sync is called.
+func New(peer Peer, blockchainID bc.Hash) *Client {
+	return NewFromCheckpoint(peer, 1, blockchainID)
+}
+
+// NewFromCheckpoint returns a Client that trusts the block
+// with the given hash at height, and follows the blockchain
+// from there. It has no headers until Sync is called.
+func NewFromCheckpoint(peer Peer, height uint64, hash bc.Hash) *Client {
+	return &Client{peer: peer, checkpoint: height, checkpointHash: hash}
+}
+
+// Height returns the height of the latest header,
+// or 0 if there are none.
+func (c *Client) Height() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.height()
+}
+
+func (c *Client) height() uint64 {
+	if len(c.headers) == 0 {
+		return 0
+	}
+	return c.checkpoint + uint64(len(c.headers)) - 1
+}
+
+// Header returns the header at height, if the
+// client has synced it.
+func (c *Client) Header(height uint64) (*bc.BlockHeader, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.headers) == 0 || height < c.checkpoint || height > c.height() {
+		return nil, false
+	}
+	return c.headers[height-c.checkpoint], true
+}
+
+// Sync fetches and adds headers until the client
+// reaches the peer's height.
+func (c *Client) Sync(ctx context.Context) error {
+	var resp map[string]uint64
+	err := c.peer.Call(ctx, "/rpc/block-height", nil, &resp)
+	if err != nil {
+		return errors.Wrap(err, "getting peer height")
+	}
+	height, ok := resp["block_height"]
+	if !ok {
+		return errors.New("unexpected response from peer")
+	}
+	return c.SyncTo(ctx, height)
+}
+
+// SyncTo fetches and adds headers until the
+// client reaches height.
+func (c *Client) SyncTo(ctx context.Context, height uint64) error {
+	h := c.Height() + 1
+	if h == 1 {
+		h = c.checkpoint
+	}
+	for ; h <= height; h++ {
+		var header bc.BlockHeader
+		err := c.peer.Call(ctx, "/rpc/get-block-header", h, &header)
+		if err != nil {
+			return errors.Wrapf(err, "getting header %d", h)
+		}
+		err = c.AddHeader(&header)
+		if err != nil {
+			return errors.Wrapf(err, "adding header %d", h)
+		}
+	}
+	return nil
+}
+
+// AddHeader checks that h follows the latest header and
+// satisfies its consensus program, then adds it. The first
+// header must be the checkpoint's.
+func (c *Client) AddHeader(h *bc.BlockHeader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.headers) == 0 {
+		if h.Height != c.checkpoint {
+			return errors.Wrap(validation.ErrBadHeight)
+		}
+		if h.Hash() != c.checkpointHash {
+			return errors.WithDetailf(validation.ErrBadPrevHash, "block %s at height %d is not checkpoint %s", h.Hash(), h.Height, c.checkpointHash)
+		}
+		c.headers = append(c.headers, h)
+		return nil
+	}
+
+	prev := c.headers[len(c.headers)-1]
+	if h.Height != prev.Height+1 {
+		return errors.Wrap(validation.ErrBadHeight)
+	}
+	if h.PreviousBlockHash != prev.Hash() {
+		return errors.Wrap(validation.ErrBadPrevHash)
+	}
+	if h.TimestampMS < prev.TimestampMS {
+		return errors.Wrap(validation.ErrBadTimestamp)
+	}
+	err := vm.VerifyBlockHeader(prev, &bc.Block{BlockHeader: *h})
+	if err != nil {
+		return errors.Sub(validation.ErrBadSig, err)
+	}
+	c.headers = append(c.headers, h)
+	return nil
+}
+
+// VerifyTxProof checks that p's header is the one the client
+// has at its height and that its merkle proof shows the
+// transaction is in the block.
+func (c *Client) VerifyTxProof(p *TxProof) error {
+	if p.BlockHeader == nil || p.Proof == nil {
+		return errors.WithDetail(ErrBadProof, "missing block header or merkle proof")
+	}
+	err := c.checkHeader(p.BlockHeader)
+	if err != nil {
+		return err
+	}
+	if p.BlockID != p.BlockHeader.Hash() {
+		return errors.WithDetailf(ErrBadProof, "block ID %s doesn't match header", p.BlockID)
+	}
+	if !validation.VerifyMerkleProof(p.BlockHeader.TransactionsMerkleRoot, p.ID, p.Proof) {
+		return errors.WithDetailf(ErrBadProof, "transaction %s is not in block %s", p.ID, p.BlockID)
+	}
+	return nil
+}
+
+// VerifyOutputProof checks that p's header is the one the client
+// has at its height, that the height is at least minHeight, and that
+// its patricia proof shows whether the output is unspent. It returns
+// the result of the proof and the height as of which it holds.
+// An output unspent at one height may be spent at a later one, so
+// callers should pass the latest height they know of.
+func (c *Client) VerifyOutputProof(p *OutputProof, minHeight uint64) (unspent bool, height uint64, err error) {
+	if p.BlockHeader == nil {
+		return false, 0, errors.WithDetail(ErrBadProof, "missing block header")
+	}
+	height = p.BlockHeader.Height
+	if height < minHeight {
+		return false, 0, errors.WithDetailf(ErrStaleProof, "proof is at height %d, want at least %d", height, minHeight)
+	}
+	err = c.checkHeader(p.BlockHeader)
+	if err != nil {
+		return false, 0, err
+	}
+	unspent, err = patricia.VerifyProof(p.BlockHeader.AssetsMerkleRoot, p.OutputID.Bytes(), p.Proof)
+	if err != nil {
+		return false, 0, errors.Sub(ErrBadProof, err)
+	}
+	if unspent != p.Unspent {
+		return false, 0, errors.WithDetailf(ErrBadProof, "proof contradicts claim that output %s is unspent: %t", p.OutputID, p.Unspent)
+	}
+	return unspent, height, nil
+}
+
+// checkHeader returns an error unless h is the
+// header the client has at its height.
+func (c *Client) checkHeader(h *bc.BlockHeader) error {
+	ours, ok := c.Header(h.Height)
+	if !ok {
+		return errors.WithDetailf(ErrUnknownBlock, "no header at height %d", h.Height)
+	}
+	if ours.Hash() != h.Hash() {
+		return errors.WithDetailf(ErrBadProof, "block %s at height %d is not in the blockchain", h.Hash(), h.Height)
+	}
+	return nil
+}
+
+// GetTxProof fetches a proof that the transaction with the given
+// ID is in the block at height, syncing up to that height if
+// necessary, and verifies it. If height is 0, the peer looks up
+// the block in its transaction index.
+func (c *Client) GetTxProof(ctx context.Context, id bc.Hash, height uint64) (*TxProof, error) {
+	req := struct {
+		ID          bc.Hash `json:"id"`
+		BlockHeight uint64  `json:"block_height"`
+	}{id, height}
+	var p TxProof
+	err := c.peer.Call(ctx, "/rpc/get-transaction-proof", req, &p)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting transaction proof")
+	}
+	if p.ID != id || p.BlockHeader == nil || (height != 0 && p.BlockHeader.Height != height) {
+		return nil, errors.WithDetail(ErrBadProof, "proof is for a different transaction or block")
+	}
+	err = c.SyncTo(ctx, p.BlockHeader.Height)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyTxProof(&p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetOutputProof syncs to the peer's latest block, then fetches a
+// proof of whether the output with the given ID is unspent as of
+// that block or a later one, and verifies it. It returns the
+// verified proof and the height as of which it holds.
+func (c *Client) GetOutputProof(ctx context.Context, outputID bc.Hash) (*OutputProof, uint64, error) {
+	err := c.Sync(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	tip := c.Height()
+
+	req := struct {
+		OutputID bc.Hash `json:"output_id"`
+	}{outputID}
+	var p OutputProof
+	err = c.peer.Call(ctx, "/rpc/get-output-proof", req, &p)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "getting output proof")
+	}
+	if p.OutputID != outputID || p.BlockHeader == nil {
+		return nil, 0, errors.WithDetail(ErrBadProof, "proof is for a different output")
+	}
+	err = c.SyncTo(ctx, p.BlockHeader.Height)
+	if err != nil {
+		return nil, 0, err
+	}
+	_, height, err := c.VerifyOutputProof(&p, tip)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &p, height, nil
+}
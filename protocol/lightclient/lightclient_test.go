@@ -0,0 +1,286 @@
+package lightclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+	"chain/protocol/validation"
+	"chain/protocol/vmutil"
+	"chain/testutil"
+)
+
+func TestSyncAndProofs(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	tx := prottest.NewIssuanceTx(t, c)
+	prottest.MakeBlock(t, c, []*bc.Tx{prottest.NewIssuanceTx(t, c), tx})
+	prottest.MakeBlock(t, c, nil)
+
+	b1, err := c.GetBlock(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc := New(&chainPeer{c: c}, b1.Hash())
+	err = lc.Sync(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if lc.Height() != 3 {
+		t.Fatalf("Height() = %d, want 3", lc.Height())
+	}
+
+	txProof, err := lc.GetTxProof(ctx, tx.ID, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if txProof.Proof.Position != 1 {
+		t.Errorf("got position %d, want 1", txProof.Proof.Position)
+	}
+
+	outProof, height, err := lc.GetOutputProof(ctx, tx.OutputID(0))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !outProof.Unspent {
+		t.Error("issued output is not unspent")
+	}
+	if height != 3 {
+		t.Errorf("got proof at height %d, want 3", height)
+	}
+	outProof, _, err = lc.GetOutputProof(ctx, bc.Hash{1})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if outProof.Unspent {
+		t.Error("unknown output is unspent")
+	}
+
+	// A lie about the proof's result must be caught.
+	outProof.Unspent = true
+	_, _, err = lc.VerifyOutputProof(outProof, 0)
+	if errors.Root(err) != ErrBadProof {
+		t.Errorf("got error %v, want %v", err, ErrBadProof)
+	}
+
+	// So must a proof against a header that isn't in the blockchain.
+	forged := *txProof.BlockHeader
+	forged.TimestampMS++
+	txProof.BlockHeader = &forged
+	txProof.BlockID = forged.Hash()
+	err = lc.VerifyTxProof(txProof)
+	if errors.Root(err) != ErrBadProof {
+		t.Errorf("got error %v, want %v", err, ErrBadProof)
+	}
+
+	txProof.BlockHeader.Height = 4
+	err = lc.VerifyTxProof(txProof)
+	if errors.Root(err) != ErrUnknownBlock {
+		t.Errorf("got error %v, want %v", err, ErrUnknownBlock)
+	}
+}
+
+func TestStaleOutputProof(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	tx := prottest.NewIssuanceTx(t, c)
+	prottest.MakeBlock(t, c, []*bc.Tx{tx})
+
+	// Get a proof that the output is unspent at height 2;
+	// by the next block, it might have been spent.
+	peer := &chainPeer{c: c}
+	var stale OutputProof
+	err := peer.Call(ctx, "/rpc/get-output-proof", struct {
+		OutputID bc.Hash `json:"output_id"`
+	}{tx.OutputID(0)}, &stale)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !stale.Unspent || stale.BlockHeader.Height != 2 {
+		t.Fatalf("got proof unspent=%t at height %d, want unspent at height 2", stale.Unspent, stale.BlockHeader.Height)
+	}
+	prottest.MakeBlock(t, c, nil)
+
+	b1, err := c.GetBlock(ctx, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	lc := New(peer, b1.Hash())
+	err = lc.Sync(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// The old proof is valid for its block, but not for the tip.
+	unspent, height, err := lc.VerifyOutputProof(&stale, 2)
+	if err != nil || !unspent || height != 2 {
+		t.Errorf("VerifyOutputProof(stale, 2) = %t, %d, %v, want true, 2, nil", unspent, height, err)
+	}
+	_, _, err = lc.VerifyOutputProof(&stale, lc.Height())
+	if errors.Root(err) != ErrStaleProof {
+		t.Errorf("VerifyOutputProof(stale, tip) error = %v, want %v", err, ErrStaleProof)
+	}
+
+	// A peer serving the old proof is caught.
+	peer.outputProof = &stale
+	_, _, err = lc.GetOutputProof(ctx, tx.OutputID(0))
+	if errors.Root(err) != ErrStaleProof {
+		t.Errorf("GetOutputProof from stale peer error = %v, want %v", err, ErrStaleProof)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	b2 := prottest.MakeBlock(t, c, nil)
+	prottest.MakeBlock(t, c, nil)
+
+	lc := NewFromCheckpoint(&chainPeer{c: c}, 2, b2.Hash())
+	err := lc.Sync(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if lc.Height() != 3 {
+		t.Errorf("Height() = %d, want 3", lc.Height())
+	}
+	if _, ok := lc.Header(1); ok {
+		t.Error("got header before checkpoint")
+	}
+
+	lc = NewFromCheckpoint(&chainPeer{c: c}, 2, bc.Hash{1})
+	err = lc.Sync(ctx)
+	if errors.Root(err) != validation.ErrBadPrevHash {
+		t.Errorf("got error %v, want %v", err, validation.ErrBadPrevHash)
+	}
+}
+
+func TestAddHeader(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{pub}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1, err := protocol.NewInitialBlockWithProgram(prog, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lc := New(nil, bc.Hash{})
+	err = lc.AddHeader(&b1.BlockHeader)
+	if errors.Root(err) != validation.ErrBadPrevHash {
+		t.Errorf("initial block of wrong blockchain: got error %v, want %v", err, validation.ErrBadPrevHash)
+	}
+
+	lc = New(nil, b1.Hash())
+	err = lc.AddHeader(&b1.BlockHeader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	h := &bc.BlockHeader{
+		Version:           bc.NewBlockVersion,
+		Height:            2,
+		PreviousBlockHash: b1.Hash(),
+		TimestampMS:       b1.TimestampMS,
+		BlockCommitment: bc.BlockCommitment{
+			TransactionsMerkleRoot: b1.TransactionsMerkleRoot,
+			ConsensusProgram:       prog,
+		},
+	}
+	err = lc.AddHeader(h)
+	if errors.Root(err) != validation.ErrBadSig {
+		t.Errorf("unsigned header: got error %v, want %v", err, validation.ErrBadSig)
+	}
+
+	hash := h.Hash()
+	h.Witness = [][]byte{ed25519.Sign(priv, hash[:])}
+	h.Height = 3
+	err = lc.AddHeader(h)
+	if errors.Root(err) != validation.ErrBadHeight {
+		t.Errorf("header at wrong height: got error %v, want %v", err, validation.ErrBadHeight)
+	}
+
+	h.Height = 2
+	err = lc.AddHeader(h)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if lc.Height() != 2 {
+		t.Errorf("Height() = %d, want 2", lc.Height())
+	}
+}
+
+// chainPeer serves the network RPCs used by Client from a Chain,
+// sending everything through JSON as an rpc.Client would.
+type chainPeer struct {
+	c *protocol.Chain
+
+	// outputProof, if set, is served for every output
+	outputProof *OutputProof
+}
+
+func (p *chainPeer) Call(ctx context.Context, path string, request, response interface{}) error {
+	var resp interface{}
+	switch path {
+	case "/rpc/block-height":
+		resp = map[string]uint64{"block_height": p.c.Height()}
+	case "/rpc/get-block-header":
+		b, err := p.c.GetBlock(ctx, request.(uint64))
+		if err != nil {
+			return err
+		}
+		resp = &b.BlockHeader
+	case "/rpc/get-transaction-proof":
+		var req struct {
+			ID          bc.Hash `json:"id"`
+			BlockHeight uint64  `json:"block_height"`
+		}
+		roundTrip(request, &req)
+		b, err := p.c.GetBlock(ctx, req.BlockHeight)
+		if err != nil {
+			return err
+		}
+		for i, tx := range b.Transactions {
+			if tx.ID == req.ID {
+				proof, err := validation.CalcMerkleProof(b.Transactions, i)
+				if err != nil {
+					return err
+				}
+				resp = &TxProof{ID: tx.ID, BlockID: b.Hash(), BlockHeader: &b.BlockHeader, Proof: proof}
+			}
+		}
+	case "/rpc/get-output-proof":
+		var req struct {
+			OutputID bc.Hash `json:"output_id"`
+		}
+		roundTrip(request, &req)
+		if p.outputProof != nil {
+			resp = p.outputProof
+			break
+		}
+		b, snapshot := p.c.State()
+		proof := snapshot.Tree.Prove(req.OutputID.Bytes())
+		resp = &OutputProof{OutputID: req.OutputID, Unspent: proof.Path != nil, BlockHeader: &b.BlockHeader, Proof: proof}
+	default:
+		return fmt.Errorf("unknown rpc %s", path)
+	}
+	return roundTrip(resp, response)
+}
+
+func roundTrip(v, dst interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
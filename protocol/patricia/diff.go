@@ -0,0 +1,61 @@
+package patricia
+
+import "bytes"
+
+// Diff calls inserted for each item in b but not in a, and
+// deleted for each item in a but not in b. Subtrees the two
+// trees share are skipped, so when b was made from a by a few
+// inserts and deletes, Diff takes time proportional to the
+// number of changes rather than the size of the trees.
+// If an error is returned by either function, processing is
+// stopped and the error is returned.
+func Diff(a, b *Tree, inserted, deleted WalkFunc) error {
+	return diff(a.root, b.root, inserted, deleted)
+}
+
+func diff(a, b *node, inserted, deleted WalkFunc) error {
+	switch {
+	case a == b:
+		return nil
+	case a == nil:
+		return walk(b, inserted)
+	case b == nil:
+		return walk(a, deleted)
+	case a.Hash() == b.Hash():
+		return nil
+	}
+
+	// Descend in whichever tree has the shorter key until
+	// the two subtrees start at the same place.
+	switch {
+	case !a.isLeaf && len(a.key) < len(b.key) && bytes.HasPrefix(b.key, a.key):
+		bit := b.key[len(a.key)]
+		err := walk(a.children[1-bit], deleted)
+		if err != nil {
+			return err
+		}
+		return diff(a.children[bit], b, inserted, deleted)
+	case !b.isLeaf && len(b.key) < len(a.key) && bytes.HasPrefix(a.key, b.key):
+		bit := a.key[len(b.key)]
+		err := walk(b.children[1-bit], inserted)
+		if err != nil {
+			return err
+		}
+		return diff(a, b.children[bit], inserted, deleted)
+	case !a.isLeaf && !b.isLeaf && bytes.Equal(a.key, b.key):
+		for i := range a.children {
+			err := diff(a.children[i], b.children[i], inserted, deleted)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The subtrees have no items in common.
+	err := walk(a, deleted)
+	if err != nil {
+		return err
+	}
+	return walk(b, inserted)
+}
@@ -0,0 +1,77 @@
+package patricia
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randItem := func() []byte {
+		var b [32]byte
+		r.Read(b[:])
+		return b[:]
+	}
+
+	a := new(Tree)
+	var items [][]byte
+	for i := 0; i < 200; i++ {
+		item := randItem()
+		items = append(items, item)
+		a.Insert(item)
+	}
+
+	b := new(Tree)
+	*b = *a
+	var wantIns, wantDel []string
+	for i := 0; i < 20; i++ {
+		item := randItem()
+		b.Insert(item)
+		wantIns = append(wantIns, string(item))
+	}
+	for _, item := range items[:20] {
+		b.Delete(item)
+		wantDel = append(wantDel, string(item))
+	}
+
+	for _, c := range []struct {
+		a, b             *Tree
+		wantIns, wantDel []string
+	}{
+		{a, b, wantIns, wantDel},
+		{b, a, wantDel, wantIns},
+		{a, a, nil, nil},
+		{new(Tree), a, sortedItems(a), nil},
+	} {
+		var gotIns, gotDel []string
+		err := Diff(c.a, c.b, func(item []byte) error {
+			gotIns = append(gotIns, string(item))
+			return nil
+		}, func(item []byte) error {
+			gotDel = append(gotDel, string(item))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(gotIns)
+		sort.Strings(gotDel)
+		sort.Strings(c.wantIns)
+		sort.Strings(c.wantDel)
+		if !reflect.DeepEqual(gotIns, c.wantIns) || !reflect.DeepEqual(gotDel, c.wantDel) {
+			t.Errorf("got %d inserted, %d deleted; want %d, %d", len(gotIns), len(gotDel), len(c.wantIns), len(c.wantDel))
+		}
+	}
+}
+
+func sortedItems(t *Tree) []string {
+	var res []string
+	Walk(t, func(item []byte) error {
+		res = append(res, string(item))
+		return nil
+	})
+	sort.Strings(res)
+	return res
+}
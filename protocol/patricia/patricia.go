@@ -73,14 +73,7 @@ func (t *Tree) Contains(item []byte) bool {
 
 	key := bitKey(item)
 	n := lookup(t.root, key)
-
-	var hash bc.Hash
-	h := sha3pool.Get256()
-	h.Write(leafPrefix)
-	h.Write(item)
-	h.Read(hash[:])
-	sha3pool.Put256(h)
-	return n != nil && n.Hash() == hash
+	return n != nil && n.Hash() == leafHash(item)
 }
 
 func lookup(n *node, key []uint8) *node {
@@ -112,13 +105,7 @@ func (t *Tree) Insert(item []byte) error {
 // and remove the extra func
 func (t *Tree) insert(bkey, val []byte) error {
 	key := bitKey(bkey)
-
-	var hash bc.Hash
-	h := sha3pool.Get256()
-	h.Write(leafPrefix)
-	h.Write(val)
-	h.Read(hash[:])
-	sha3pool.Put256(h)
+	hash := leafHash(val)
 
 	if t.root == nil {
 		t.root = &node{key: key, hash: &hash, isLeaf: true}
@@ -0,0 +1,231 @@
+package patricia
+
+import (
+	"bytes"
+
+	"chain/crypto/sha3pool"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// ErrBadProof is returned by VerifyProof when a proof
+// doesn't match the root hash or the item.
+var ErrBadProof = errors.New("invalid patricia tree proof")
+
+// A Path is the audit path from an item's leaf to the
+// root of a tree.
+type Path struct {
+	Item chainjson.HexBytes `json:"item"`
+
+	// Siblings are the nodes next to those on the path,
+	// bottom first.
+	Siblings []Sibling `json:"siblings"`
+}
+
+// A Sibling is a node next to one on a Path.
+type Sibling struct {
+	Hash bc.Hash `json:"hash"`
+
+	// Left is whether the sibling is the left child
+	// of its parent, so the path goes right.
+	Left bool `json:"left"`
+}
+
+// A Proof shows whether an item is in a tree.
+//
+// If the item is in the tree, Path is its path. Otherwise, Prev
+// and Next are the paths of the items just before and after it
+// in the tree's order, which must be adjacent leaves. Prev is nil
+// if the item would be first, Next is nil if it would be last,
+// and both are nil if the tree is empty.
+type Proof struct {
+	Path *Path `json:"path,omitempty"`
+	Prev *Path `json:"prev,omitempty"`
+	Next *Path `json:"next,omitempty"`
+}
+
+// Prove returns a proof of whether t contains item.
+func (t *Tree) Prove(item []byte) *Proof {
+	if t.root == nil {
+		return new(Proof)
+	}
+	key := bitKey(item)
+	if t.Contains(item) {
+		return &Proof{Path: t.path(key)}
+	}
+	prev, next := neighbors(t.root, key)
+	p := new(Proof)
+	if prev != nil {
+		p.Prev = t.path(prev.key)
+	}
+	if next != nil {
+		p.Next = t.path(next.key)
+	}
+	return p
+}
+
+// path returns the path to the leaf with the given key,
+// which must be in t.
+func (t *Tree) path(key []uint8) *Path {
+	var siblings []Sibling
+	n := t.root
+	for !n.isLeaf {
+		bit := key[len(n.key)]
+		siblings = append(siblings, Sibling{
+			Hash: n.children[1-bit].Hash(),
+			Left: bit == 1,
+		})
+		n = n.children[bit]
+	}
+	// Reverse to put the bottom first.
+	for i, j := 0, len(siblings)-1; i < j; i, j = i+1, j-1 {
+		siblings[i], siblings[j] = siblings[j], siblings[i]
+	}
+	return &Path{Item: n.Key(), Siblings: siblings}
+}
+
+// neighbors returns the leaves under n just before
+// and after key, which must not be in the tree.
+func neighbors(n *node, key []uint8) (prev, next *node) {
+	if n.isLeaf || len(key) <= len(n.key) || !bytes.HasPrefix(key, n.key) {
+		// All the leaves under n are on
+		// the same side of key.
+		first := leftmost(n)
+		if less(key, first.key) {
+			return nil, first
+		}
+		return rightmost(n), nil
+	}
+	bit := key[len(n.key)]
+	prev, next = neighbors(n.children[bit], key)
+	if bit == 1 && prev == nil {
+		prev = rightmost(n.children[0])
+	}
+	if bit == 0 && next == nil {
+		next = leftmost(n.children[1])
+	}
+	return prev, next
+}
+
+func less(a, b []uint8) bool {
+	c := commonPrefixLen(a, b)
+	if c == len(a) || c == len(b) {
+		return len(a) < len(b)
+	}
+	return a[c] < b[c]
+}
+
+func leftmost(n *node) *node {
+	for !n.isLeaf {
+		n = n.children[0]
+	}
+	return n
+}
+
+func rightmost(n *node) *node {
+	for !n.isLeaf {
+		n = n.children[1]
+	}
+	return n
+}
+
+// VerifyProof checks p against the root hash of a tree and
+// reports whether it shows that item is in the tree. It
+// returns ErrBadProof if p doesn't prove either way.
+func VerifyProof(root bc.Hash, item []byte, p *Proof) (bool, error) {
+	if p == nil {
+		return false, errors.Wrap(ErrBadProof, "no proof")
+	}
+	if p.Path != nil {
+		if !bytes.Equal(p.Path.Item, item) || p.Path.root() != root {
+			return false, errors.Wrap(ErrBadProof, "path doesn't lead from item to root")
+		}
+		return true, nil
+	}
+
+	if p.Prev == nil && p.Next == nil {
+		if root != (bc.Hash{}) {
+			return false, errors.Wrap(ErrBadProof, "tree is not empty")
+		}
+		return false, nil
+	}
+	for _, q := range []*Path{p.Prev, p.Next} {
+		if q != nil && q.root() != root {
+			return false, errors.Wrap(ErrBadProof, "neighbor's path doesn't lead to root")
+		}
+	}
+	if p.Prev != nil && !less(bitKey(p.Prev.Item), bitKey(item)) {
+		return false, errors.Wrap(ErrBadProof, "previous item isn't before item")
+	}
+	if p.Next != nil && !less(bitKey(item), bitKey(p.Next.Item)) {
+		return false, errors.Wrap(ErrBadProof, "next item isn't after item")
+	}
+
+	// Check that nothing is between Prev and Next.
+	var prevSibs, nextSibs []Sibling
+	if p.Prev != nil {
+		prevSibs = p.Prev.Siblings
+	}
+	if p.Next != nil {
+		nextSibs = p.Next.Siblings
+	}
+	if p.Prev != nil && p.Next != nil {
+		// Above the node where they diverge, the paths
+		// are the same. There, Prev goes left and Next
+		// goes right.
+		i, j := len(prevSibs)-1, len(nextSibs)-1
+		for i >= 0 && j >= 0 && prevSibs[i] == nextSibs[j] {
+			i, j = i-1, j-1
+		}
+		if i < 0 || j < 0 || prevSibs[i].Left || !nextSibs[j].Left {
+			return false, errors.Wrap(ErrBadProof, "paths don't diverge")
+		}
+		prevSibs, nextSibs = prevSibs[:i], nextSibs[:j]
+	}
+	// Below that, Prev is the rightmost leaf and
+	// Next is the leftmost.
+	for _, s := range prevSibs {
+		if !s.Left {
+			return false, errors.Wrap(ErrBadProof, "previous item isn't adjacent")
+		}
+	}
+	for _, s := range nextSibs {
+		if s.Left {
+			return false, errors.Wrap(ErrBadProof, "next item isn't adjacent")
+		}
+	}
+	return false, nil
+}
+
+// root computes the root hash of the tree from the path.
+func (p *Path) root() bc.Hash {
+	hash := leafHash(p.Item)
+	for _, s := range p.Siblings {
+		if s.Left {
+			hash = interiorHash(s.Hash, hash)
+		} else {
+			hash = interiorHash(hash, s.Hash)
+		}
+	}
+	return hash
+}
+
+func leafHash(item []byte) (hash bc.Hash) {
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+	h.Write(leafPrefix)
+	h.Write(item)
+	h.Read(hash[:])
+	return hash
+}
+
+func interiorHash(left, right bc.Hash) (hash bc.Hash) {
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+	h.Write(interiorPrefix)
+	h.Write(left[:])
+	h.Write(right[:])
+	h.Read(hash[:])
+	return hash
+}
@@ -0,0 +1,85 @@
+package patricia
+
+import (
+	"math/rand"
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+func TestProof(t *testing.T) {
+	r := rand.New(rand.NewSource(12345))
+	randItem := func() []byte {
+		item := make([]byte, 32)
+		r.Read(item)
+		return item
+	}
+
+	for _, n := range []int{0, 1, 2, 3, 10, 100} {
+		tr := new(Tree)
+		var items [][]byte
+		for i := 0; i < n; i++ {
+			item := randItem()
+			items = append(items, item)
+			err := tr.Insert(item)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		root := tr.RootHash()
+
+		for _, item := range items {
+			got, err := VerifyProof(root, item, tr.Prove(item))
+			if err != nil || !got {
+				t.Errorf("%d items: VerifyProof(present item) = %t, %v; want true", n, got, err)
+			}
+		}
+		for i := 0; i < 20; i++ {
+			item := randItem()
+			got, err := VerifyProof(root, item, tr.Prove(item))
+			if err != nil || got {
+				t.Errorf("%d items: VerifyProof(absent item) = %t, %v; want false", n, got, err)
+			}
+		}
+
+		// A present item can't be proven absent with the proof
+		// for an absent item next to it.
+		for _, item := range items {
+			tr2 := *tr
+			tr2.Delete(item)
+			p := tr2.Prove(item)
+			p.Prev, p.Next = reroot(tr, p.Prev), reroot(tr, p.Next)
+			_, err := VerifyProof(root, item, p)
+			if errors.Root(err) != ErrBadProof {
+				t.Errorf("%d items: VerifyProof(present item, absence proof) error = %v, want %v", n, err, ErrBadProof)
+			}
+		}
+	}
+}
+
+func TestProofWrongRoot(t *testing.T) {
+	tr := new(Tree)
+	for _, item := range [][]byte{{0x01}, {0x02}, {0x04}} {
+		err := tr.Insert(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := VerifyProof(bc.Hash{1}, []byte{0x02}, tr.Prove([]byte{0x02}))
+	if errors.Root(err) != ErrBadProof {
+		t.Errorf("got error %v, want %v", err, ErrBadProof)
+	}
+	_, err = VerifyProof(bc.Hash{1}, []byte{0x03}, tr.Prove([]byte{0x03}))
+	if errors.Root(err) != ErrBadProof {
+		t.Errorf("got error %v, want %v", err, ErrBadProof)
+	}
+}
+
+// reroot returns the path in t to the item on p.
+func reroot(t *Tree, p *Path) *Path {
+	if p == nil {
+		return nil
+	}
+	return t.path(bitKey(p.Item))
+}
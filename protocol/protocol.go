@@ -86,6 +86,7 @@ type Store interface {
 type Chain struct {
 	InitialBlockHash  bc.Hash
 	MaxIssuanceWindow time.Duration // only used by generators
+	MaxBlockTxs       int           // only used by generators
 
 	state struct {
 		cond     sync.Cond // protects height, block, snapshot
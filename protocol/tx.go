@@ -24,6 +24,33 @@ func (c *Chain) ValidateTxCached(tx *bc.Tx) error {
 	return err
 }
 
+// validateTxsBatch checks the transactions in txs that aren't in
+// the cache of prevalidated transactions, verifying their signatures
+// in batches, and caches the results. It returns a function, for
+// validation.ValidateBlock, that reports them.
+func (c *Chain) validateTxsBatch(txs []*bc.Tx) func(*bc.Tx) error {
+	var unchecked []*bc.Tx
+	for _, tx := range txs {
+		if _, ok := c.prevalidated.lookup(tx.ID); !ok {
+			unchecked = append(unchecked, tx)
+		}
+	}
+	results := make(map[bc.Hash]error, len(unchecked))
+	for i, err := range validation.CheckTxsWellFormed(unchecked) {
+		results[unchecked[i].ID] = err
+		c.prevalidated.cache(unchecked[i].ID, err)
+	}
+
+	return func(tx *bc.Tx) error {
+		// The cache may have evicted some of the results
+		// already, so check them here first.
+		if err, ok := results[tx.ID]; ok {
+			return err
+		}
+		return c.ValidateTxCached(tx)
+	}
+}
+
 type prevalidatedTxsCache struct {
 	mu  sync.Mutex
 	lru *lru.Cache
@@ -48,7 +75,11 @@ func (c *prevalidatedTxsCache) cache(txID bc.Hash, err error) {
 	c.mu.Unlock()
 }
 
-func (c *Chain) checkIssuanceWindow(tx *bc.Tx) error {
+// CheckIssuanceWindow returns an error if tx contains an issuance
+// input and its time window is larger than c.MaxIssuanceWindow.
+// Generators use it to refuse issuances whose duplicate-protection
+// memory would need to be kept for too long.
+func (c *Chain) CheckIssuanceWindow(tx *bc.Tx) error {
 	for _, txi := range tx.Inputs {
 		if _, ok := txi.TypedInput.(*bc.IssuanceInput); ok {
 			// TODO(tessr): consider removing 0 check once we can configure this
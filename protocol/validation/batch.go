@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"runtime"
+	"sync"
+
+	"chain/crypto/ed25519"
+	"chain/protocol/bc"
+)
+
+// sigBatchTxs is the number of transactions whose signatures
+// CheckTxsWellFormed verifies in each batch.
+const sigBatchTxs = 64
+
+// CheckTxsWellFormed is like calling CheckTxWellFormed on each
+// transaction in txs, and returns the results in the same order.
+// It verifies the signatures in the transactions' programs in
+// batches, spread across GOMAXPROCS goroutines. When a batch
+// fails, it checks each transaction in it on its own.
+func CheckTxsWellFormed(txs []*bc.Tx) []error {
+	errs := make([]error, len(txs))
+	starts := make(chan int, (len(txs)+sigBatchTxs-1)/sigBatchTxs)
+	for i := 0; i < len(txs); i += sigBatchTxs {
+		starts <- i
+	}
+	close(starts)
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(starts) {
+		workers = len(starts)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for start := range starts {
+				end := start + sigBatchTxs
+				if end > len(txs) {
+					end = len(txs)
+				}
+				checkTxsWellFormed(txs[start:end], errs[start:end])
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func checkTxsWellFormed(txs []*bc.Tx, errs []error) {
+	var sigs ed25519.BatchVerifier
+	for i, tx := range txs {
+		errs[i] = checkTxWellFormed(tx, &sigs)
+	}
+	if sigs.Verify() {
+		return
+	}
+
+	// At least one signature is invalid, so any of the
+	// results above might be wrong.
+	for i, tx := range txs {
+		errs[i] = CheckTxWellFormed(tx)
+	}
+}
@@ -0,0 +1,42 @@
+package validation_test
+
+import (
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+	"chain/protocol/validation"
+)
+
+func TestCheckTxsWellFormed(t *testing.T) {
+	c := prottest.NewChain(t)
+	var txs []*bc.Tx
+	for i := 0; i < 150; i++ {
+		txs = append(txs, prottest.NewIssuanceTx(t, c))
+	}
+
+	errs := validation.CheckTxsWellFormed(txs)
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("tx %d: unexpected error %v", i, err)
+		}
+	}
+
+	// Corrupt the signature of one transaction.
+	args := txs[70].Inputs[0].Arguments()
+	sig := append([]byte(nil), args[1]...)
+	sig[0] ^= 1
+	txs[70].Inputs[0].SetArguments([][]byte{args[0], sig, args[2]})
+
+	errs = validation.CheckTxsWellFormed(txs)
+	for i, err := range errs {
+		if i == 70 {
+			if errors.Root(err) != validation.ErrBadTx {
+				t.Errorf("tx %d: got error %v want %v", i, err, validation.ErrBadTx)
+			}
+		} else if err != nil {
+			t.Errorf("tx %d: unexpected error %v", i, err)
+		}
+	}
+}
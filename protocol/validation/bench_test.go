@@ -2,6 +2,8 @@ package validation_test
 
 import (
 	"context"
+	"fmt"
+	"runtime"
 	"testing"
 	"time"
 
@@ -22,6 +24,34 @@ func BenchmarkValidateTx(b *testing.B) {
 	}
 }
 
+func BenchmarkCheckTxsWellFormed(b *testing.B) {
+	c := prottest.NewChain(b)
+	var txs []*bc.Tx
+	for i := 0; i < 1000; i++ {
+		txs = append(txs, prottest.NewIssuanceTx(b, c))
+	}
+
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, tx := range txs {
+				err := validation.CheckTxWellFormed(tx)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, err := range validation.CheckTxsWellFormed(txs) {
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
 func BenchmarkValidateBlock(b *testing.B) {
 	b.StopTimer()
 	ctx := context.Background()
@@ -50,6 +80,41 @@ func BenchmarkValidateBlock(b *testing.B) {
 	}
 }
 
+// BenchmarkValidateBlockProcs compares validating a block's
+// transactions on one processor, which is serial, to validating
+// them across all of them.
+func BenchmarkValidateBlockProcs(b *testing.B) {
+	ctx := context.Background()
+	c := prottest.NewChain(b)
+	b1, s := c.State()
+
+	var txs []*bc.Tx
+	for i := 0; i < 1000; i++ {
+		txs = append(txs, prottest.NewIssuanceTx(b, c))
+	}
+	nextBlock, _, err := c.GenerateBlock(ctx, b1, s, time.Now(), txs)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	procs := []int{1}
+	if n := runtime.NumCPU(); n > 1 {
+		procs = append(procs, n)
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, procs := range procs {
+		b.Run(fmt.Sprintf("procs=%d", procs), func(b *testing.B) {
+			runtime.GOMAXPROCS(procs)
+			for i := 0; i < b.N; i++ {
+				err := validation.ValidateBlock(ctx, state.Copy(s), b1.Hash(), b1, nextBlock, validation.CheckTxWellFormed)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkCalcMerkleRoot(b *testing.B) {
 	b.StopTimer()
 	c := prottest.NewChain(b)
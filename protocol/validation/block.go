@@ -54,11 +54,48 @@ func ValidateBlockForAccept(ctx context.Context, snapshot *state.Snapshot, initi
 // See $CHAIN/protocol/doc/spec/validation.md#validate-block.
 // Note that it does not execute prevBlock's consensus program.
 // (See ValidateBlockForAccept for that.)
+//
+// ValidateBlock calls validateTx, for the checks that don't depend
+// on the state, on several transactions at once, so it must be safe
+// for concurrent use. The state-dependent checks are done one
+// transaction at a time, in block order.
 func ValidateBlock(ctx context.Context, snapshot *state.Snapshot, initialBlockHash bc.Hash, prevBlock, block *bc.Block, validateTx func(*bc.Tx) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	// Run validateTx, which doesn't depend on the state, for each
+	// transaction across a pool of GOMAXPROCS goroutines. Each
+	// transaction's result goes in its own channel, so the
+	// sequential work below can consume them in block order.
+	txs := block.Transactions
+	results := make([]chan error, len(txs))
+	indexes := make(chan int, len(txs))
+	for i := range txs {
+		results[i] = make(chan error, 1)
+		indexes <- i
+	}
+	close(indexes)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for i := range indexes {
+				if ctx.Err() != nil {
+					// The block is already invalid, or
+					// the caller gave up.
+					return nil
+				}
+				results[i] <- validateTx(txs[i])
+			}
+			return nil
+		})
+	}
 
-	var g errgroup.Group
 	// Do all of the unparallelizable work, plus validating the block
-	// header in one goroutine.
+	// header in one goroutine. Transactions are confirmed and applied
+	// in order as the pool finishes them, so the error returned is
+	// always the one for the first invalid transaction.
 	g.Go(func() error {
 		var prev *bc.BlockHeader
 		if prevBlock != nil {
@@ -73,7 +110,15 @@ func ValidateBlock(ctx context.Context, snapshot *state.Snapshot, initialBlockHa
 		// TODO: Check that other block headers are valid.
 		// TODO(erykwalder): consider writing to a copy of the state tree
 		// of the one provided and make the caller call ApplyBlock as well
-		for _, tx := range block.Transactions {
+		for i, tx := range txs {
+			select {
+			case err = <-results[i]:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if err != nil {
+				return err
+			}
 			err = ConfirmTx(snapshot, initialBlockHash, block.Version, block.TimestampMS, tx)
 			if err != nil {
 				return err
@@ -88,24 +133,6 @@ func ValidateBlock(ctx context.Context, snapshot *state.Snapshot, initialBlockHa
 		}
 		return nil
 	})
-
-	// Distribute checking well-formedness of the transactions across
-	// GOMAXPROCS goroutines.
-	ch := make(chan *bc.Tx, len(block.Transactions))
-	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
-		g.Go(func() error {
-			for tx := range ch {
-				if err := validateTx(tx); err != nil {
-					return err
-				}
-			}
-			return nil
-		})
-	}
-	for _, tx := range block.Transactions {
-		ch <- tx
-	}
-	close(ch)
 	return g.Wait()
 }
 
@@ -3,6 +3,7 @@ package validation
 import (
 	"context"
 	"testing"
+	"time"
 
 	"chain/errors"
 	"chain/protocol/bc"
@@ -138,3 +139,57 @@ func TestValidateBlockHeader(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateBlockFirstBadTx(t *testing.T) {
+	ctx := context.Background()
+	prev := &bc.Block{BlockHeader: bc.BlockHeader{Height: 1}}
+
+	var txs []*bc.Tx
+	for i := 0; i < 20; i++ {
+		txs = append(txs, bc.NewTx(bc.TxData{Version: 1, ReferenceData: []byte{byte(i)}}))
+	}
+	root, err := CalcMerkleRoot(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &bc.Block{
+		BlockHeader: bc.BlockHeader{
+			Version:           1,
+			PreviousBlockHash: prev.Hash(),
+			Height:            2,
+			BlockCommitment: bc.BlockCommitment{
+				TransactionsMerkleRoot: root,
+				AssetsMerkleRoot:       state.Empty().Tree.RootHash(),
+				ConsensusProgram:       []byte{byte(vm.OP_TRUE)},
+			},
+		},
+		Transactions: txs,
+	}
+
+	var (
+		errFirst = errors.New("first")
+		errLater = errors.New("later")
+	)
+	validateTx := func(tx *bc.Tx) error {
+		switch tx.ID {
+		case txs[5].ID:
+			// Give the later bad tx a head start.
+			time.Sleep(10 * time.Millisecond)
+			return errFirst
+		case txs[12].ID:
+			return errLater
+		}
+		return nil
+	}
+	for i := 0; i < 10; i++ {
+		err := ValidateBlock(ctx, state.Empty(), bc.Hash{}, prev, block, validateTx)
+		if err != errFirst {
+			t.Fatalf("ValidateBlock = %v want %v", err, errFirst)
+		}
+	}
+
+	err = ValidateBlock(ctx, state.Empty(), bc.Hash{}, prev, block, func(*bc.Tx) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+}
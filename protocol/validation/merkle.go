@@ -4,6 +4,7 @@ import (
 	"math"
 
 	"chain/crypto/sha3pool"
+	"chain/errors"
 	"chain/protocol/bc"
 )
 
@@ -21,13 +22,7 @@ func CalcMerkleRoot(transactions []*bc.Tx) (root bc.Hash, err error) {
 		return root, nil
 
 	case len(transactions) == 1:
-		h := sha3pool.Get256()
-		defer sha3pool.Put256(h)
-
-		h.Write(leafPrefix)
-		h.Write(transactions[0].ID[:])
-		h.Read(root[:])
-		return root, nil
+		return leafHash(transactions[0].ID), nil
 
 	default:
 		k := prevPowerOfTwo(len(transactions))
@@ -41,14 +36,116 @@ func CalcMerkleRoot(transactions []*bc.Tx) (root bc.Hash, err error) {
 			return root, err
 		}
 
-		h := sha3pool.Get256()
-		defer sha3pool.Put256(h)
-		h.Write(interiorPrefix)
-		h.Write(left[:])
-		h.Write(right[:])
-		h.Read(root[:])
-		return root, nil
+		return interiorHash(left, right), nil
+	}
+}
+
+func leafHash(txID bc.Hash) (hash bc.Hash) {
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+	h.Write(leafPrefix)
+	h.Write(txID[:])
+	h.Read(hash[:])
+	return hash
+}
+
+func interiorHash(left, right bc.Hash) (hash bc.Hash) {
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+	h.Write(interiorPrefix)
+	h.Write(left[:])
+	h.Write(right[:])
+	h.Read(hash[:])
+	return hash
+}
+
+// MerkleProof is the audit path of a transaction in the
+// merkle tree of a block's transactions.
+type MerkleProof struct {
+	// Position is the index of the transaction in the block.
+	Position uint32 `json:"position"`
+
+	// Count is the number of transactions in the block.
+	Count uint32 `json:"count"`
+
+	// Hashes are the roots of the sibling subtrees on the
+	// path from the transaction to the root, bottom first.
+	Hashes []bc.Hash `json:"hashes"`
+}
+
+// CalcMerkleProof returns the audit path of the transaction
+// at position pos in transactions.
+func CalcMerkleProof(transactions []*bc.Tx, pos int) (*MerkleProof, error) {
+	if pos < 0 || pos >= len(transactions) {
+		return nil, errors.New("transaction position out of range")
+	}
+	hashes, err := merklePath(transactions, pos)
+	if err != nil {
+		return nil, err
+	}
+	return &MerkleProof{
+		Position: uint32(pos),
+		Count:    uint32(len(transactions)),
+		Hashes:   hashes,
+	}, nil
+}
+
+func merklePath(transactions []*bc.Tx, pos int) ([]bc.Hash, error) {
+	if len(transactions) == 1 {
+		return nil, nil
+	}
+	k := prevPowerOfTwo(len(transactions))
+	var (
+		path    []bc.Hash
+		sibling bc.Hash
+		err     error
+	)
+	if pos < k {
+		path, err = merklePath(transactions[:k], pos)
+		if err == nil {
+			sibling, err = CalcMerkleRoot(transactions[k:])
+		}
+	} else {
+		path, err = merklePath(transactions[k:], pos-k)
+		if err == nil {
+			sibling, err = CalcMerkleRoot(transactions[:k])
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(path, sibling), nil
+}
+
+// VerifyMerkleProof reports whether p proves that the
+// transaction with the given ID is in the merkle tree
+// with the given root, such as a block header's
+// TransactionsMerkleRoot. It needs no other data.
+func VerifyMerkleProof(root, txID bc.Hash, p *MerkleProof) bool {
+	if p == nil || p.Position >= p.Count {
+		return false
+	}
+	got, ok := merkleRootFromPath(leafHash(txID), int(p.Position), int(p.Count), p.Hashes)
+	return ok && got == root
+}
+
+// merkleRootFromPath computes the root of a tree of n leaves
+// from the leaf at pos and the audit path for it.
+func merkleRootFromPath(leaf bc.Hash, pos, n int, path []bc.Hash) (bc.Hash, bool) {
+	if n == 1 {
+		return leaf, len(path) == 0
+	}
+	if len(path) == 0 {
+		return bc.Hash{}, false
+	}
+	k := prevPowerOfTwo(n)
+	sibling, path := path[len(path)-1], path[:len(path)-1]
+	if pos < k {
+		left, ok := merkleRootFromPath(leaf, pos, k, path)
+		return interiorHash(left, sibling), ok
 	}
+	right, ok := merkleRootFromPath(leaf, pos-k, n-k, path)
+	return interiorHash(sibling, right), ok
 }
 
 // prevPowerOfTwo returns the largest power of two that is smaller than a given number.
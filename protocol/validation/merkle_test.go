@@ -139,6 +139,47 @@ func TestAllDuplicateLeaves(t *testing.T) {
 	}
 }
 
+func TestMerkleProof(t *testing.T) {
+	var txs []*bc.Tx
+	for i := 0; i < 9; i++ {
+		txs = append(txs, bc.NewTx(bc.TxData{Version: 1, ReferenceData: []byte{byte(i)}}))
+	}
+
+	for n := 1; n <= len(txs); n++ {
+		root, err := CalcMerkleRoot(txs[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for pos := 0; pos < n; pos++ {
+			p, err := CalcMerkleProof(txs[:n], pos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !VerifyMerkleProof(root, txs[pos].ID, p) {
+				t.Errorf("%d txs: proof for tx %d didn't verify", n, pos)
+			}
+
+			// The proof doesn't work for other txs or positions.
+			other := txs[(pos+1)%len(txs)].ID
+			if VerifyMerkleProof(root, other, p) {
+				t.Errorf("%d txs: proof for tx %d verified for another tx", n, pos)
+			}
+			if n > 1 {
+				moved := *p
+				moved.Position = uint32((pos + 1) % n)
+				if VerifyMerkleProof(root, txs[pos].ID, &moved) {
+					t.Errorf("%d txs: proof for tx %d verified at position %d", n, pos, moved.Position)
+				}
+			}
+		}
+	}
+
+	_, err := CalcMerkleProof(txs, len(txs))
+	if err == nil {
+		t.Error("expected error for out-of-range position")
+	}
+}
+
 func mustParseHash(s string) bc.Hash {
 	h, err := bc.ParseHash(s)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"math"
 
+	"chain/crypto/ed25519"
 	"chain/errors"
 	"chain/math/checked"
 	"chain/protocol/bc"
@@ -120,6 +121,13 @@ func ConfirmTx(snapshot *state.Snapshot, initialBlockHash bc.Hash, blockVersion,
 // Result is nil for well-formed transactions, ErrBadTx with
 // supporting detail otherwise.
 func CheckTxWellFormed(tx *bc.Tx) error {
+	return checkTxWellFormed(tx, nil)
+}
+
+// checkTxWellFormed is CheckTxWellFormed, but if sigs is non-nil,
+// it defers the signature checks in the input programs to sigs.
+// See vm.VerifyTxInputDeferred.
+func checkTxWellFormed(tx *bc.Tx, sigs *ed25519.BatchVerifier) error {
 	if len(tx.Inputs) == 0 {
 		return badTxErr(errNoInputs)
 	}
@@ -245,7 +253,12 @@ func CheckTxWellFormed(tx *bc.Tx) error {
 	}
 
 	for i := range tx.Inputs {
-		err := vm.VerifyTxInput(tx, uint32(i))
+		var err error
+		if sigs == nil {
+			err = vm.VerifyTxInput(tx, uint32(i))
+		} else {
+			err = vm.VerifyTxInputDeferred(tx, uint32(i), sigs)
+		}
 		if err != nil {
 			return badTxErrf(err, "validation failed in script execution, input %d", i)
 		}
@@ -69,10 +69,16 @@ func opCheckPredicate(vm *virtualMachine) error {
 		tx:         vm.tx,
 		txContext:  vm.txContext,
 		inputIndex: vm.inputIndex,
+		tracer:     vm.tracer,
+		sigs:       vm.sigs,
 	}
 	vm.dataStack = vm.dataStack[:l-n]
 
 	childErr := childVM.run()
+	if childVM.halted {
+		vm.halted = true
+		return childErr
+	}
 
 	vm.deferCost(-childVM.runLimit)
 	vm.deferCost(-stackCost(childVM.dataStack))
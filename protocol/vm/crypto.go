@@ -62,7 +62,7 @@ func opCheckSig(vm *virtualMachine) error {
 	if err != nil {
 		return err
 	}
-	return vm.pushBool(ed25519.Verify(ed25519.PublicKey(pubkeyBytes), msg, sig), true)
+	return vm.pushBool(vm.verify(ed25519.PublicKey(pubkeyBytes), msg, sig), true)
 }
 
 func opCheckMultiSig(vm *virtualMachine) error {
@@ -117,6 +117,17 @@ func opCheckMultiSig(vm *virtualMachine) error {
 		pubkeys = append(pubkeys, ed25519.PublicKey(p))
 	}
 
+	if vm.sigs != nil && len(sigs) == len(pubkeys) {
+		// With as many signatures as keys, each signature must
+		// be by the key in the same position, so the checks
+		// can be deferred.
+		ok := true
+		for i := range sigs {
+			ok = ok && vm.verify(pubkeys[i], msg, sigs[i])
+		}
+		return vm.pushBool(ok, true)
+	}
+
 	for len(sigs) > 0 && len(pubkeys) > 0 {
 		if ed25519.Verify(pubkeys[0], msg, sigs[0]) {
 			sigs = sigs[1:]
@@ -126,6 +137,17 @@ func opCheckMultiSig(vm *virtualMachine) error {
 	return vm.pushBool(len(sigs) == 0, true)
 }
 
+// verify checks sig. In deferred mode, it instead adds sig to
+// vm.sigs and assumes it's valid, unless its length shows
+// it isn't.
+func (vm *virtualMachine) verify(pubkey ed25519.PublicKey, msg, sig []byte) bool {
+	if vm.sigs == nil || len(sig) != ed25519.SignatureSize {
+		return ed25519.Verify(pubkey, msg, sig)
+	}
+	vm.sigs.Add(pubkey, msg, sig)
+	return true
+}
+
 func opTxSigHash(vm *virtualMachine) error {
 	if vm.tx == nil {
 		return ErrContext
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"chain/crypto/ed25519"
 	"chain/protocol/bc"
 	"chain/testutil"
 )
@@ -83,6 +84,23 @@ func TestCheckSig(t *testing.T) {
 		} else if !vm.falseResult() {
 			t.Errorf("case %d: expected false VM result, got error %s", i, err)
 		}
+
+		// In deferred mode, the result must be the same
+		// once the batch of signatures is checked.
+		sigs := new(ed25519.BatchVerifier)
+		vm = &virtualMachine{
+			program:  prog,
+			runLimit: 50000,
+			sigs:     sigs,
+		}
+		err = vm.run()
+		if c.err {
+			if err == nil {
+				t.Errorf("case %d deferred: expected error, got ok result", i)
+			}
+		} else if ok := err == nil && !vm.falseResult() && sigs.Verify(); ok != c.ok {
+			t.Errorf("case %d deferred: got ok=%t (err %v) want %t", i, ok, err, c.ok)
+		}
 	}
 }
 
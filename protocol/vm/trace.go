@@ -0,0 +1,196 @@
+package vm
+
+import (
+	"errors"
+
+	chainjson "chain/encoding/json"
+	"chain/protocol/bc"
+)
+
+// ErrAborted is returned when a Debugger halts execution.
+var ErrAborted = errors.New("execution aborted by debugger")
+
+// Step is a snapshot of the VM taken around the execution of a
+// single instruction.
+//
+// In the snapshot passed to Tracer.BeforeStep, the stacks and
+// run limit reflect the state before the instruction runs and
+// Err is empty. In the snapshot passed to Tracer.AfterStep, they
+// reflect the state after it has run (including any deferred
+// cost), and Err holds the error, if any, it produced.
+type Step struct {
+	Depth        int                  `json:"depth"`
+	PC           uint32               `json:"pc"`
+	Op           Op                   `json:"-"`
+	OpName       string               `json:"op"`
+	Data         chainjson.HexBytes   `json:"data,omitempty"`
+	DataStack    []chainjson.HexBytes `json:"data_stack"`
+	AltStack     []chainjson.HexBytes `json:"alt_stack"`
+	RunLimit     int64                `json:"run_limit"`
+	DeferredCost int64                `json:"deferred_cost"`
+	Err          string               `json:"error,omitempty"`
+}
+
+// Tracer receives structured records of VM execution.
+// It is passed to VerifyTxInputTrace or VerifyBlockHeaderTrace
+// and applies only to that invocation (including any child VMs
+// spawned by CHECKPREDICATE).
+type Tracer interface {
+	// BeforeStep is called before each instruction executes.
+	// If it returns a non-nil error, execution halts and the
+	// error is returned from the verification function.
+	BeforeStep(Step) error
+
+	// AfterStep is called after each instruction executes,
+	// whether or not it succeeded.
+	AfterStep(Step)
+}
+
+// VerifyTxInputTrace is like VerifyTxInput, but reports each
+// step of execution to t.
+func VerifyTxInputTrace(tx *bc.Tx, inputIndex uint32, t Tracer) (err error) {
+	defer func() {
+		if panErr := recover(); panErr != nil {
+			err = ErrUnexpected
+		}
+	}()
+	return verifyTxInput(tx, inputIndex, t, nil)
+}
+
+// VerifyBlockHeaderTrace is like VerifyBlockHeader, but reports
+// each step of execution to t.
+func VerifyBlockHeaderTrace(prev *bc.BlockHeader, block *bc.Block, t Tracer) (err error) {
+	defer func() {
+		if panErr := recover(); panErr != nil {
+			err = ErrUnexpected
+		}
+	}()
+	return verifyBlockHeader(prev, block, t)
+}
+
+func (vm *virtualMachine) snapshot(inst Instruction) Step {
+	return Step{
+		Depth:        vm.depth,
+		PC:           vm.pc,
+		Op:           inst.Op,
+		OpName:       inst.Op.String(),
+		Data:         copyBytes(inst.Data),
+		DataStack:    copyStack(vm.dataStack),
+		AltStack:     copyStack(vm.altStack),
+		RunLimit:     vm.runLimit,
+		DeferredCost: vm.deferredCost,
+	}
+}
+
+func copyStack(stack [][]byte) []chainjson.HexBytes {
+	res := make([]chainjson.HexBytes, 0, len(stack))
+	for _, item := range stack {
+		res = append(res, copyBytes(item))
+	}
+	return res
+}
+
+func copyBytes(b []byte) chainjson.HexBytes {
+	if b == nil {
+		return nil
+	}
+	return append(chainjson.HexBytes{}, b...)
+}
+
+// Recorder is a Tracer that accumulates the post-execution
+// snapshot of every instruction. The resulting slice can be
+// serialized as JSON for rendering in external tools.
+type Recorder struct {
+	Steps []Step
+}
+
+// BeforeStep implements Tracer.
+func (r *Recorder) BeforeStep(Step) error { return nil }
+
+// AfterStep implements Tracer.
+func (r *Recorder) AfterStep(s Step) {
+	r.Steps = append(r.Steps, s)
+}
+
+// Command tells a Debugger how to proceed after pausing.
+type Command int
+
+const (
+	// Continue runs until the next breakpoint.
+	Continue Command = iota
+
+	// StepNext pauses again before the next instruction,
+	// including instructions in child VMs.
+	StepNext
+
+	// Abort halts execution with ErrAborted.
+	Abort
+)
+
+// Breakpoint identifies an instruction at which a Debugger
+// pauses. A Depth of -1 matches any CHECKPREDICATE depth.
+type Breakpoint struct {
+	Depth int
+	PC    uint32
+}
+
+// Debugger is a Tracer that pauses execution at breakpoints
+// and, when single-stepping, before every instruction.
+// On each pause it calls Pause with the pre-execution
+// snapshot and proceeds according to the returned Command.
+type Debugger struct {
+	Pause func(Step) Command
+
+	// Stepping, if true, pauses before the first instruction.
+	Stepping bool
+
+	breakpoints []Breakpoint
+	breakOps    map[Op]bool
+}
+
+// BreakAt adds a breakpoint at pc in VMs of the given depth.
+func (d *Debugger) BreakAt(depth int, pc uint32) {
+	d.breakpoints = append(d.breakpoints, Breakpoint{Depth: depth, PC: pc})
+}
+
+// BreakOn adds a breakpoint before every execution of op.
+func (d *Debugger) BreakOn(op Op) {
+	if d.breakOps == nil {
+		d.breakOps = make(map[Op]bool)
+	}
+	d.breakOps[op] = true
+}
+
+// BeforeStep implements Tracer.
+func (d *Debugger) BeforeStep(s Step) error {
+	if !d.Stepping && !d.shouldBreak(s) {
+		return nil
+	}
+	if d.Pause == nil {
+		return nil
+	}
+	switch d.Pause(s) {
+	case StepNext:
+		d.Stepping = true
+	case Abort:
+		return ErrAborted
+	default:
+		d.Stepping = false
+	}
+	return nil
+}
+
+// AfterStep implements Tracer.
+func (d *Debugger) AfterStep(Step) {}
+
+func (d *Debugger) shouldBreak(s Step) bool {
+	if d.breakOps[s.Op] {
+		return true
+	}
+	for _, b := range d.breakpoints {
+		if b.PC == s.PC && (b.Depth < 0 || b.Depth == s.Depth) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package vm
+
+import (
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+func TestRecorder(t *testing.T) {
+	tx := bc.NewTx(bc.TxData{
+		Inputs: []*bc.TxInput{bc.NewSpendInput(
+			bc.Hash{},
+			[][]byte{{2}, {3}},
+			bc.AssetID{},
+			1,
+			[]byte{byte(OP_ADD), byte(OP_6), byte(OP_NUMEQUAL)},
+			nil,
+		)},
+	})
+
+	r := new(Recorder)
+	err := VerifyTxInputTrace(tx, 0, r)
+	if vmErr(err) != ErrFalseVMResult {
+		t.Fatalf("got error %v, want %v", err, ErrFalseVMResult)
+	}
+
+	wantOps := []Op{OP_ADD, OP_6, OP_NUMEQUAL}
+	if len(r.Steps) != len(wantOps) {
+		t.Fatalf("got %d steps, want %d", len(r.Steps), len(wantOps))
+	}
+	for i, s := range r.Steps {
+		if s.Op != wantOps[i] {
+			t.Errorf("step %d: got op %s, want %s", i, s.Op, wantOps[i])
+		}
+	}
+
+	add := r.Steps[0]
+	if add.PC != 0 || len(add.DataStack) != 1 || add.DataStack[0][0] != 5 {
+		t.Errorf("after ADD got pc %d stack %x, want pc 0 stack [05]", add.PC, add.DataStack)
+	}
+	last := r.Steps[len(r.Steps)-1]
+	if last.PC != 2 || len(last.DataStack) != 1 || len(last.DataStack[0]) != 0 {
+		t.Errorf("after NUMEQUAL got pc %d stack %x, want pc 2 stack [false]", last.PC, last.DataStack)
+	}
+	if last.RunLimit >= initialRunLimit {
+		t.Errorf("got run limit %d, want less than %d", last.RunLimit, initialRunLimit)
+	}
+}
+
+func TestRecorderCheckPredicate(t *testing.T) {
+	prog, err := Assemble("0 0x51 0 CHECKPREDICATE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := &bc.BlockHeader{
+		BlockCommitment: bc.BlockCommitment{ConsensusProgram: prog},
+	}
+
+	r := new(Recorder)
+	err = VerifyBlockHeaderTrace(prev, &bc.Block{}, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sawChild bool
+	for _, s := range r.Steps {
+		if s.Depth == 1 {
+			sawChild = true
+			if s.Op != OP_TRUE {
+				t.Errorf("child VM executed %s, want %s", s.Op, OP_TRUE)
+			}
+		}
+	}
+	if !sawChild {
+		t.Error("expected steps from child VM")
+	}
+}
+
+func TestRecorderError(t *testing.T) {
+	prev := &bc.BlockHeader{
+		BlockCommitment: bc.BlockCommitment{ConsensusProgram: []byte{byte(OP_ADD)}},
+	}
+	r := new(Recorder)
+	err := VerifyBlockHeaderTrace(prev, &bc.Block{}, r)
+	if vmErr(err) != ErrDataStackUnderflow {
+		t.Fatalf("got error %v, want %v", err, ErrDataStackUnderflow)
+	}
+	if len(r.Steps) != 1 || r.Steps[0].Err != ErrDataStackUnderflow.Error() {
+		t.Errorf("got steps %+v, want one step with error %q", r.Steps, ErrDataStackUnderflow)
+	}
+}
+
+func TestDebugger(t *testing.T) {
+	prev := &bc.BlockHeader{
+		BlockCommitment: bc.BlockCommitment{
+			ConsensusProgram: []byte{byte(OP_1), byte(OP_2), byte(OP_ADD), byte(OP_3), byte(OP_NUMEQUAL)},
+		},
+	}
+
+	var paused []uint32
+	d := &Debugger{
+		Pause: func(s Step) Command {
+			paused = append(paused, s.PC)
+			if s.Op == OP_ADD {
+				return StepNext
+			}
+			return Continue
+		},
+	}
+	d.BreakOn(OP_ADD)
+	d.BreakAt(0, 4)
+	err := VerifyBlockHeaderTrace(prev, &bc.Block{}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint32{2, 3, 4}
+	if len(paused) != len(want) {
+		t.Fatalf("paused at %v, want %v", paused, want)
+	}
+	for i := range want {
+		if paused[i] != want[i] {
+			t.Fatalf("paused at %v, want %v", paused, want)
+		}
+	}
+
+	d = &Debugger{
+		Stepping: true,
+		Pause:    func(Step) Command { return Abort },
+	}
+	err = VerifyBlockHeaderTrace(prev, &bc.Block{}, d)
+	if vmErr(err) != ErrAborted {
+		t.Errorf("got error %v, want %v", err, ErrAborted)
+	}
+}
+
+func TestDebuggerAbortInChild(t *testing.T) {
+	// The child predicate would fail on its own, which
+	// CHECKPREDICATE would report as false rather than an error.
+	prog, err := Assemble("0 0x93 0 CHECKPREDICATE NOT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := &bc.BlockHeader{
+		BlockCommitment: bc.BlockCommitment{ConsensusProgram: prog},
+	}
+
+	d := &Debugger{
+		Pause: func(s Step) Command {
+			if s.Depth == 1 {
+				return Abort
+			}
+			return Continue
+		},
+	}
+	d.BreakAt(1, 0)
+	err = VerifyBlockHeaderTrace(prev, &bc.Block{}, d)
+	if vmErr(err) != ErrAborted {
+		t.Errorf("got error %v, want %v", err, ErrAborted)
+	}
+
+	abortErr := errors.New("stop")
+	err = VerifyBlockHeaderTrace(prev, &bc.Block{}, haltTracer{depth: 1, err: abortErr})
+	if vmErr(err) != abortErr {
+		t.Errorf("got error %v, want %v", err, abortErr)
+	}
+}
+
+// haltTracer fails BeforeStep for the first
+// instruction it sees at depth.
+type haltTracer struct {
+	depth int
+	err   error
+}
+
+func (h haltTracer) BeforeStep(s Step) error {
+	if s.Depth == h.depth {
+		return h.err
+	}
+	return nil
+}
+
+func (h haltTracer) AfterStep(Step) {}
+
+func vmErr(err error) error {
+	if e, ok := errors.Root(err).(Error); ok {
+		return e.Err
+	}
+	return errors.Root(err)
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"strings"
 
+	"chain/crypto/ed25519"
 	// TODO(bobg): very little of this package depends on bc, consider trying to remove the dependency
 	"chain/errors"
 	"chain/protocol/bc"
@@ -38,13 +39,26 @@ type virtualMachine struct {
 	inputIndex uint32
 
 	block *bc.Block
+
+	// tracer, if non-nil, receives a snapshot around each step
+	tracer Tracer
+
+	// halted is set when the tracer stops execution, in this vm
+	// or in a child; CHECKPREDICATE then propagates the error
+	// instead of treating it as a false result
+	halted bool
+
+	// sigs, if non-nil, collects the signatures checked by
+	// CHECKSIG and CHECKMULTISIG, which assume they're valid
+	sigs *ed25519.BatchVerifier
 }
 
 // ErrFalseVMResult is one of the ways for a transaction to fail validation
 var ErrFalseVMResult = errors.New("false VM result")
 
 // TraceOut - if non-nil - will receive trace output during
-// execution.
+// execution. It applies to every VM in the process; for
+// structured tracing of a single invocation, see Tracer.
 var TraceOut io.Writer
 
 func VerifyTxInput(tx *bc.Tx, inputIndex uint32) (err error) {
@@ -53,10 +67,25 @@ func VerifyTxInput(tx *bc.Tx, inputIndex uint32) (err error) {
 			err = ErrUnexpected
 		}
 	}()
-	return verifyTxInput(tx, inputIndex)
+	return verifyTxInput(tx, inputIndex, nil, nil)
 }
 
-func verifyTxInput(tx *bc.Tx, inputIndex uint32) error {
+// VerifyTxInputDeferred is like VerifyTxInput, but rather than
+// checking each signature CHECKSIG and CHECKMULTISIG need, it
+// assumes the signature is valid and adds it to sigs, so that
+// the signatures of many inputs can be checked in one batch.
+// A nil error stands only if sigs.Verify later returns true.
+// Otherwise, VerifyTxInput gives the definitive result.
+func VerifyTxInputDeferred(tx *bc.Tx, inputIndex uint32, sigs *ed25519.BatchVerifier) (err error) {
+	defer func() {
+		if panErr := recover(); panErr != nil {
+			err = ErrUnexpected
+		}
+	}()
+	return verifyTxInput(tx, inputIndex, nil, sigs)
+}
+
+func verifyTxInput(tx *bc.Tx, inputIndex uint32, tracer Tracer, sigs *ed25519.BatchVerifier) error {
 	if inputIndex < 0 || inputIndex >= uint32(len(tx.Inputs)) {
 		return ErrBadValue
 	}
@@ -80,6 +109,8 @@ func verifyTxInput(tx *bc.Tx, inputIndex uint32) error {
 			mainprog: prog,
 			program:  prog,
 			runLimit: initialRunLimit,
+			tracer:   tracer,
+			sigs:     sigs,
 		}
 		for _, arg := range args {
 			err := vm.push(arg, false)
@@ -109,10 +140,10 @@ func VerifyBlockHeader(prev *bc.BlockHeader, block *bc.Block) (err error) {
 			err = ErrUnexpected
 		}
 	}()
-	return verifyBlockHeader(prev, block)
+	return verifyBlockHeader(prev, block, nil)
 }
 
-func verifyBlockHeader(prev *bc.BlockHeader, block *bc.Block) error {
+func verifyBlockHeader(prev *bc.BlockHeader, block *bc.Block, tracer Tracer) error {
 	vm := virtualMachine{
 		block: block,
 
@@ -121,6 +152,7 @@ func verifyBlockHeader(prev *bc.BlockHeader, block *bc.Block) error {
 		mainprog: prev.ConsensusProgram,
 		program:  prev.ConsensusProgram,
 		runLimit: initialRunLimit,
+		tracer:   tracer,
 	}
 
 	for _, arg := range block.Witness {
@@ -153,7 +185,7 @@ func (vm *virtualMachine) run() error {
 	return nil
 }
 
-func (vm *virtualMachine) step() error {
+func (vm *virtualMachine) step() (err error) {
 	inst, err := ParseOp(vm.program, vm.pc)
 	if err != nil {
 		return err
@@ -170,6 +202,23 @@ func (vm *virtualMachine) step() error {
 		fmt.Fprint(TraceOut, "\n")
 	}
 
+	if vm.tracer != nil {
+		err = vm.tracer.BeforeStep(vm.snapshot(inst))
+		if err != nil {
+			vm.halted = true
+			return err
+		}
+		pc := vm.pc
+		defer func() {
+			s := vm.snapshot(inst)
+			s.PC = pc
+			if err != nil {
+				s.Err = err.Error()
+			}
+			vm.tracer.AfterStep(s)
+		}()
+	}
+
 	if isExpansion[inst.Op] {
 		if vm.expansionReserved {
 			return ErrDisallowedOpcode
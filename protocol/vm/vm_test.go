@@ -468,7 +468,7 @@ func TestVerifyTxInputQuickCheck(t *testing.T) {
 		tx := bc.NewTx(bc.TxData{
 			Inputs: []*bc.TxInput{bc.NewSpendInput(bc.Hash{}, witnesses, bc.AssetID{}, 10, program, nil)},
 		})
-		verifyTxInput(tx, 0)
+		verifyTxInput(tx, 0, nil, nil)
 		return true
 	}
 	if err := quick.Check(f, nil); err != nil {
@@ -498,7 +498,7 @@ func TestVerifyBlockHeaderQuickCheck(t *testing.T) {
 				Witness: witnesses,
 			},
 		}}
-		verifyBlockHeader(prev, block)
+		verifyBlockHeader(prev, block, nil)
 		return true
 	}
 	if err := quick.Check(f, nil); err != nil {